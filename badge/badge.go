@@ -5,25 +5,42 @@
 package badge
 
 import (
+	"encoding/xml"
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
 )
 
-// SVG generates an OJS conformance badge as SVG.
+// Badge colors by status. Statuses without a color are drawn grey.
+var statusColors = map[string]string{
+	"pass":    "#4c1",
+	"partial": "#dfb317",
+	"fail":    "#e05d44",
+	"revoked": "#e05d44",
+}
+
+const defaultColor = "#9f9f9f"
+
+// SVG generates an OJS conformance badge as SVG. status selects the color
+// of the level side: "pass", "partial", "fail" or "revoked"; anything else,
+// such as "expired", "pending" or "error", is grey.
 func SVG(label, level, status string) string {
-	color := "#4c1"
-	if status == "fail" {
-		color = "#e05d44"
-	} else if status == "partial" {
-		color = "#dfb317"
+	color, ok := statusColors[status]
+	if !ok {
+		color = defaultColor
 	}
 
-	labelWidth := len(label)*7 + 10
-	valueWidth := len(level)*7 + 10
+	labelText, levelText := textWidth(label), textWidth(level)
+	labelWidth := int(math.Ceil(labelText)) + 10
+	valueWidth := int(math.Ceil(levelText)) + 10
 	totalWidth := labelWidth + valueWidth
+	labelX := float64(labelWidth) / 2
+	levelX := float64(labelWidth) + float64(valueWidth)/2
+	label, level = escapeXML(label), escapeXML(level)
 
-	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img">
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+  <title>%s: %s</title>
   <linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
   <clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>
   <g clip-path="url(#r)">
@@ -32,14 +49,22 @@ func SVG(label, level, status string) string {
     <rect width="%d" height="20" fill="url(#s)"/>
   </g>
   <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="11">
-    <text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text>
-    <text x="%d" y="14">%s</text>
-    <text x="%d" y="15" fill="#010101" fill-opacity=".3">%s</text>
-    <text x="%d" y="14">%s</text>
+    <text x="%.1f" y="15" fill="#010101" fill-opacity=".3" textLength="%.1f">%s</text>
+    <text x="%.1f" y="14" textLength="%.1f">%s</text>
+    <text x="%.1f" y="15" fill="#010101" fill-opacity=".3" textLength="%.1f">%s</text>
+    <text x="%.1f" y="14" textLength="%.1f">%s</text>
   </g>
-</svg>`, totalWidth, totalWidth, labelWidth, labelWidth, valueWidth, color,
-		totalWidth, labelWidth/2, label, labelWidth/2, label,
-		labelWidth+valueWidth/2, level, labelWidth+valueWidth/2, level)
+</svg>`, totalWidth, label, level, label, level,
+		totalWidth, labelWidth, labelWidth, valueWidth, color, totalWidth,
+		labelX, labelText, label, labelX, labelText, label,
+		levelX, levelText, level, levelX, levelText, level)
+}
+
+// escapeXML escapes text for use in SVG text and attribute values.
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s)) // a strings.Builder never fails
+	return b.String()
 }
 
 // Handler serves conformance badge HTTP endpoints.
@@ -50,24 +75,23 @@ func NewHandler() *Handler {
 	return &Handler{}
 }
 
-// ServeBadge handles GET /badge/{level}.svg — returns a conformance badge.
+// ServeBadge handles GET /badge/{level}.svg — returns a badge for the
+// level in the path. Nothing backs the level, so the badge is grey and
+// marked unverified whatever the query says; badges backed by an issued
+// certificate are served by Portal.HandleCertificateBadge. The portal does
+// not register this endpoint.
 func (h *Handler) ServeBadge(w http.ResponseWriter, r *http.Request) {
 	level := strings.TrimSuffix(r.URL.Path[len("/badge/"):], ".svg")
 	if level == "" {
 		level = "L0-L4"
 	}
 
-	status := r.URL.Query().Get("status")
-	if status == "" {
-		status = "pass"
-	}
-
 	label := "OJS conformance"
 	if name := r.URL.Query().Get("name"); name != "" {
 		label = "OJS " + name
 	}
 
-	svg := SVG(label, level, status)
+	svg := SVG(label, level+" unverified", "unverified")
 	w.Header().Set("Content-Type", "image/svg+xml")
 	w.Header().Set("Cache-Control", "max-age=300")
 	w.Write([]byte(svg))
@@ -80,12 +104,7 @@ func (h *Handler) ServeStatus(w http.ResponseWriter, r *http.Request) {
   "service": "OJS Conformance Badge Service",
   "version": "1.0.0",
   "usage": {
-    "badge_url": "/badge/{level}.svg?name={backend_name}&status={pass|fail|partial}",
-    "examples": [
-      "/badge/L0-L4.svg?name=MyBackend&status=pass",
-      "/badge/L0-L2.svg?name=CustomImpl&status=partial",
-      "/badge/L0.svg?status=fail"
-    ],
+    "certificate_badge_url": "/badge/cert/{certificate_id}.svg",
     "levels": ["L0", "L0-L1", "L0-L2", "L0-L3", "L0-L4"]
   }
 }`))
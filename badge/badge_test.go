@@ -49,6 +49,10 @@ func TestServeBadge(t *testing.T) {
 	if !strings.Contains(rec.Body.String(), "OJS Redis") {
 		t.Error("expected backend name in badge")
 	}
+	// The query cannot make the badge look certified.
+	if body := rec.Body.String(); !strings.Contains(body, "L0-L4 unverified") || strings.Contains(body, statusColors["pass"]) || !strings.Contains(body, defaultColor) {
+		t.Errorf("expected a grey unverified badge, got %s", body)
+	}
 }
 
 func TestServeBadgeDefaults(t *testing.T) {
@@ -74,4 +78,39 @@ func TestServeStatus(t *testing.T) {
 	if !strings.Contains(rec.Body.String(), "Conformance Badge Service") {
 		t.Error("expected service name")
 	}
+	if strings.Contains(rec.Body.String(), "status=") {
+		t.Error("status lists badges drawn from query parameters")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	if w := textWidth("OJS conformance"); w < 96 || w > 97 {
+		t.Errorf("OJS conformance: width %.2f, want ~96.2", w)
+	}
+	if narrow, wide := textWidth("iiii"), textWidth("WWWW"); narrow*3 > wide {
+		t.Errorf("expected WWWW (%.1f) much wider than iiii (%.1f)", wide, narrow)
+	}
+	if w := textWidth("日本"); w != 22 {
+		t.Errorf("expected full-width ideographs to be 11px each, got %.2f", w)
+	}
+	if textWidth("e\u0301") != textWidth("e") {
+		t.Error("expected combining marks to take no space")
+	}
+}
+
+func TestSVGEscapesText(t *testing.T) {
+	svg := SVG(`OJS <script>alert("x")</script> & co`, "L0", "pass")
+	if strings.Contains(svg, "<script>") || strings.Contains(svg, `"x"`) {
+		t.Errorf("label not escaped:\n%s", svg)
+	}
+	if !strings.Contains(svg, "&lt;script&gt;") || !strings.Contains(svg, "&amp; co") {
+		t.Errorf("expected escaped label:\n%s", svg)
+	}
+}
+
+func TestSVGUnknownStatus(t *testing.T) {
+	svg := SVG("OJS test", "L0-L4 expired", "expired")
+	if !strings.Contains(svg, "#9f9f9f") || strings.Contains(svg, "#4c1") {
+		t.Error("expected grey for an unknown status")
+	}
 }
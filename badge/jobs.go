@@ -0,0 +1,238 @@
+package badge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+	"github.com/openjobspec/ojs-conformance/runner/httprunner"
+)
+
+// --- Certification Runs ---
+
+// Certification job states.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed" // the suite could not be run; see Job.Error
+)
+
+// RunFunc runs the conformance suite for a certification request.
+type RunFunc func(ctx context.Context, req CertificationRequest) (lib.SuiteReport, error)
+
+// Job tracks the conformance run behind a certificate.
+type Job struct {
+	CertificateID string           `json:"certificate_id"`
+	Status        string           `json:"status"`
+	QueuedAt      time.Time        `json:"queued_at"`
+	StartedAt     *time.Time       `json:"started_at,omitempty"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	Report        *lib.SuiteReport `json:"report,omitempty"`
+
+	// Request is what to run. GET /api/certify/{id} is open to anyone
+	// with the certificate ID, so it never serves the request, which
+	// holds the submitter's contact and callback details.
+	Request CertificationRequest `json:"-"`
+
+	client string // who requested the run; see Portal.client
+}
+
+// jobRetention is how long a finished job, and its report, is kept for
+// GET /api/certify/{id}. The certificate keeps the results after that.
+const jobRetention = time.Hour
+
+// JobQueue runs certification requests in the background. Finished jobs
+// are dropped after jobRetention.
+type JobQueue struct {
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	queue chan string
+}
+
+// NewJobQueue creates a queue holding up to size pending jobs.
+func NewJobQueue(size int) *JobQueue {
+	return &JobQueue{
+		jobs:  make(map[string]*Job),
+		queue: make(chan string, size),
+	}
+}
+
+var (
+	errQueueFull = errors.New("certification queue is full")
+	errInFlight  = errors.New("too many certification runs in flight")
+)
+
+// Enqueue records a job for the certificate and queues it. It fails if the
+// queue is full.
+func (q *JobQueue) Enqueue(certID string, req CertificationRequest) (*Job, error) {
+	return q.enqueue(certID, req, "", 0)
+}
+
+// enqueue is Enqueue for a client, who may have at most maxInFlight jobs
+// queued or running if maxInFlight > 0.
+func (q *JobQueue) enqueue(certID string, req CertificationRequest, client string, maxInFlight int) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(time.Now())
+	if maxInFlight > 0 {
+		inFlight := 0
+		for _, j := range q.jobs {
+			if j.client == client && (j.Status == JobQueued || j.Status == JobRunning) {
+				inFlight++
+			}
+		}
+		if inFlight >= maxInFlight {
+			return nil, fmt.Errorf("%w: %d of %d", errInFlight, inFlight, maxInFlight)
+		}
+	}
+	job := &Job{CertificateID: certID, Status: JobQueued, Request: req, QueuedAt: time.Now(), client: client}
+	select {
+	case q.queue <- certID:
+	default:
+		return nil, errQueueFull
+	}
+	q.jobs[certID] = job
+	return job, nil
+}
+
+// prune drops jobs that finished more than jobRetention before now. The
+// caller holds q.mu.
+func (q *JobQueue) prune(now time.Time) {
+	for id, job := range q.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobRetention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// Get returns a copy of the job for a certificate.
+func (q *JobQueue) Get(certID string) (Job, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	job, ok := q.jobs[certID]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Start runs queued jobs on workers goroutines until ctx is cancelled.
+// done is called with every finished job, before Get reports it finished.
+func (q *JobQueue) Start(ctx context.Context, workers int, run RunFunc, done func(Job)) {
+	for range max(workers, 1) {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-q.queue:
+					q.run(ctx, id, run, done)
+				}
+			}
+		}()
+	}
+}
+
+func (q *JobQueue) run(ctx context.Context, id string, run RunFunc, done func(Job)) {
+	q.mu.Lock()
+	job := q.jobs[id]
+	now := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &now
+	req := job.Request
+	q.mu.Unlock()
+
+	report, err := run(ctx, req)
+
+	q.mu.RLock()
+	result := *job
+	q.mu.RUnlock()
+	finished := time.Now()
+	result.FinishedAt = &finished
+	if err != nil {
+		result.Status = JobFailed
+		result.Error = err.Error()
+	} else {
+		result.Status = JobDone
+		result.Report = &report
+	}
+
+	// Record the results before the job reads as finished, so that whoever
+	// sees it finish finds its certificate updated.
+	if done != nil {
+		done(result)
+	}
+
+	q.mu.Lock()
+	*job = result
+	q.prune(finished)
+	q.mu.Unlock()
+}
+
+// ParseLevel reads CertificationRequest.Level: "all" (or empty) for every
+// level, or "0"-"4" to certify up to and including that level. It returns
+// -1 for all levels.
+func ParseLevel(level string) (int, error) {
+	if level == "" || level == "all" {
+		return -1, nil
+	}
+	n, err := strconv.Atoi(level)
+	if err != nil || n < 0 || n > 4 {
+		return 0, fmt.Errorf("level must be \"all\" or 0-4, got %q", level)
+	}
+	return n, nil
+}
+
+// SuiteRunner returns a RunFunc that loads the suites in suitesDir and runs
+// them in-process against the request's server URL, as the HTTP runner
+// does, sending its requests with client. Use TargetPolicy.Client to keep
+// runs away from internal networks.
+func SuiteRunner(suitesDir string, client *http.Client) RunFunc {
+	return func(ctx context.Context, req CertificationRequest) (lib.SuiteReport, error) {
+		maxLevel, err := ParseLevel(req.Level)
+		if err != nil {
+			return lib.SuiteReport{}, err
+		}
+		all, err := lib.LoadTests(suitesDir, lib.LoadOptions{})
+		if err != nil {
+			return lib.SuiteReport{}, err
+		}
+		var tests []lib.TestCase
+		for _, tc := range all {
+			if maxLevel < 0 || tc.LevelInt <= maxLevel {
+				tests = append(tests, tc)
+			}
+		}
+		if len(tests) == 0 {
+			return lib.SuiteReport{}, fmt.Errorf("no tests up to level %s in %s", req.Level, suitesDir)
+		}
+
+		target := strings.TrimRight(req.ServerURL, "/")
+		timing := httprunner.DefaultTimingConfig()
+		start := time.Now()
+		results := lib.Scheduler{}.Run(tests, func(tc lib.TestCase) lib.TestResult {
+			if ctx.Err() != nil {
+				return lib.TestResult{
+					TestID: tc.TestID, Name: tc.Name, Level: tc.LevelInt, Category: tc.Category,
+					SpecRef: tc.SpecRef, FilePath: tc.FilePath, Status: "skip",
+					Failures: []lib.Failure{{Message: "Portal shutting down"}},
+				}
+			}
+			return httprunner.RunTest(tc, target, client, timing)
+		})
+		if err := ctx.Err(); err != nil {
+			return lib.SuiteReport{}, err
+		}
+		report := httprunner.BuildReport(results, target, maxLevel, time.Since(start))
+		report.Commit = nil // the portal's checkout, not the certified server's
+		return report, nil
+	}
+}
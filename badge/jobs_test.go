@@ -0,0 +1,238 @@
+package badge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+func waitForJob(t *testing.T, p *Portal, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if job, ok := p.jobs.Get(id); ok && (job.Status == JobDone || job.Status == JobFailed) {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func certify(t *testing.T, mux *http.ServeMux, body string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/certify", strings.NewReader(body)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return resp["certificate_id"].(string)
+}
+
+func TestPortalRunsCertification(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+
+	id := certify(t, mux, `{"server_url":"http://my-ojs:8080","name":"MyBackend","level":"2"}`)
+
+	// Without a runner the job stays queued.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/certify/"+id, nil))
+	var job Job
+	json.NewDecoder(rec.Body).Decode(&job)
+	if rec.Code != http.StatusOK || job.Status != JobQueued || job.CertificateID != id {
+		t.Fatalf("unexpected status %d: %+v", rec.Code, job)
+	}
+	if job, _ := p.jobs.Get(id); job.Request.Level != "2" {
+		t.Fatalf("request not queued: %+v", job.Request)
+	}
+
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.StartRunner(ctx, func(ctx context.Context, req CertificationRequest) (lib.SuiteReport, error) {
+		<-release
+		if req.ServerURL == "http://broken:8080" {
+			return lib.SuiteReport{}, errors.New("no suites")
+		}
+		return lib.SuiteReport{Target: req.ServerURL, Results: lib.ResultsSummary{Total: 10, Passed: 7, Failed: 2, Errored: 1}}, nil
+	}, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job, _ := p.jobs.Get(id); job.Status != JobRunning; job, _ = p.jobs.Get(id) {
+		if time.Now().After(deadline) {
+			t.Fatalf("job never started: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+
+	job = waitForJob(t, p, id)
+	if job.Report == nil || job.Report.Target != "http://my-ojs:8080" || job.StartedAt == nil || job.FinishedAt == nil {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	cert, _ := p.store.Get(id)
+	if cert.Passed != 7 || cert.Failed != 3 || cert.Total != 10 || cert.Status != "partial" {
+		t.Errorf("certificate not updated: %+v", cert)
+	}
+
+	id = certify(t, mux, `{"server_url":"http://broken:8080","name":"Broken"}`)
+	if job := waitForJob(t, p, id); job.Status != JobFailed || job.Error != "no suites" || job.Report != nil {
+		t.Errorf("unexpected failed job: %+v", job)
+	}
+	cert, _ = p.store.Get(id)
+	if cert.Status != "error" || cert.RunError != "no suites" || p.store.Verify(id, cert.Fingerprint) {
+		t.Errorf("failed run not recorded: %+v", cert)
+	}
+	if _, value, _ := certificateBadge(cert, time.Now()); value != "run failed" {
+		t.Errorf("unexpected badge value %q", value)
+	}
+}
+
+func TestJobQueuePrunesFinishedJobs(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.StartRunner(ctx, func(ctx context.Context, req CertificationRequest) (lib.SuiteReport, error) {
+		return levelReport([2]int{10, 0}), nil
+	}, 1)
+
+	id := certify(t, mux, `{"server_url":"http://my-ojs:8080","name":"MyBackend"}`)
+	waitForJob(t, p, id)
+	p.jobs.mu.Lock()
+	long := time.Now().Add(-2 * jobRetention)
+	p.jobs.jobs[id].FinishedAt = &long
+	p.jobs.prune(time.Now())
+	p.jobs.mu.Unlock()
+	if _, ok := p.jobs.Get(id); ok {
+		t.Fatal("finished job was not dropped")
+	}
+
+	// The run's outcome is still reported, from the certificate.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/certify/"+id, nil))
+	var job Job
+	json.NewDecoder(rec.Body).Decode(&job)
+	if rec.Code != http.StatusOK || job.Status != JobDone || job.Report != nil {
+		t.Errorf("unexpected status %d: %+v", rec.Code, job)
+	}
+}
+
+func TestPortalResumesPendingCertificates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "certificates.json")
+	storage, err := OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPortalWithConfig(PortalConfig{Storage: storage})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	id := certify(t, mux, `{"server_url":"http://my-ojs:8080","name":"MyBackend","level":"1"}`)
+	if cert, _ := p.store.Get(id); cert.Status != "pending" {
+		t.Fatalf("expected a pending certificate, got %q", cert.Status)
+	}
+
+	// A new process over the same storage runs the request again.
+	storage, err = OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewPortalWithConfig(PortalConfig{Storage: storage})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got CertificationRequest
+	err = restarted.StartRunner(ctx, func(ctx context.Context, req CertificationRequest) (lib.SuiteReport, error) {
+		got = req
+		return levelReport([2]int{10, 0}, [2]int{10, 0}), nil
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, restarted, id)
+	if cert, _ := restarted.store.Get(id); got.Level != "1" || cert.Status != "pass" || cert.Level != "L0-L1" {
+		t.Errorf("pending certificate not resumed: request %+v, certificate %+v", got, cert)
+	}
+}
+
+func TestPortalCertifyStatusNotFound(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/certify/cert_missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestPortalCertifyInvalidLevel(t *testing.T) {
+	p := NewPortal()
+	for _, level := range []string{"5", "-1", "L2"} {
+		body := fmt.Sprintf(`{"server_url":"http://my-ojs:8080","name":"MyBackend","level":%q}`, level)
+		rec := httptest.NewRecorder()
+		p.HandleCertify(rec, httptest.NewRequest("POST", "/api/certify", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("level %q: expected 400, got %d", level, rec.Code)
+		}
+	}
+	if _, total, _ := p.store.List(0, 0); total != 0 {
+		t.Errorf("expected no certificates, got %d", total)
+	}
+}
+
+func TestSuiteRunner(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]string{
+		"health.json":  `{"test_id":"L0-HLT-001","level":0,"category":"health","name":"health","spec_ref":"ojs-http#health","steps":[{"id":"s1","action":"GET","path":"/ojs/v1/health","expect":{"status":200}}]}`,
+		"missing.json": `{"test_id":"L1-MIS-001","level":1,"category":"missing","name":"missing","spec_ref":"ojs-http#missing","steps":[{"id":"s1","action":"GET","path":"/ojs/v1/missing","expect":{"status":200}}]}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(tc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ojs/v1/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	run := SuiteRunner(dir, &http.Client{Timeout: 5 * time.Second})
+	report, err := run(context.Background(), CertificationRequest{ServerURL: srv.URL + "/", Level: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Results.Total != 1 || report.Results.Passed != 1 || report.ConformantLevel != 0 || report.Target != srv.URL {
+		t.Errorf("unexpected level 0 report: %+v", report)
+	}
+
+	report, err = run(context.Background(), CertificationRequest{ServerURL: srv.URL, Level: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Results.Total != 2 || report.Results.Failed != 1 || report.Conformant || len(report.Failures) != 1 {
+		t.Errorf("unexpected full report: %+v", report.Results)
+	}
+
+	if _, err := run(context.Background(), CertificationRequest{ServerURL: srv.URL, Level: "9"}); err == nil {
+		t.Error("expected invalid level to be rejected")
+	}
+}
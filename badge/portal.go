@@ -1,155 +1,507 @@
 package badge
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
-	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+	"github.com/openjobspec/ojs-conformance/runner/httprunner"
 )
 
 // --- Conformance Certification Portal ---
 
 // CertificationRequest represents a request to certify an implementation.
 type CertificationRequest struct {
-	ServerURL      string `json:"server_url"`
-	Name           string `json:"name"`
-	Organization   string `json:"organization,omitempty"`
-	Repository     string `json:"repository,omitempty"`
-	Level          string `json:"level"` // "all" or "0"-"4"
-	ContactEmail   string `json:"contact_email,omitempty"`
+	ServerURL    string `json:"server_url"`
+	Name         string `json:"name"`
+	Organization string `json:"organization,omitempty"`
+	Repository   string `json:"repository,omitempty"`
+	Level        string `json:"level"` // "all" or "0"-"4"
+	ContactEmail string `json:"contact_email,omitempty"`
+	// CallbackURL receives a signed Notification whenever the
+	// certificate's status changes; CallbackSecret keys the signature.
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
+	// AutoRenew re-runs the suite before the certificate expires.
+	AutoRenew bool `json:"auto_renew,omitempty"`
 }
 
 // Certificate represents a conformance certification result.
 type Certificate struct {
-	ID             string    `json:"id"`
-	Name           string    `json:"name"`
-	Organization   string    `json:"organization,omitempty"`
-	Repository     string    `json:"repository,omitempty"`
-	Level          string    `json:"level"` // highest passing level: "L0", "L0-L1", etc.
-	Status         string    `json:"status"` // "pass", "partial", "fail"
-	Passed         int       `json:"passed"`
-	Failed         int       `json:"failed"`
-	Total          int       `json:"total"`
-	BadgeURL       string    `json:"badge_url"`
-	IssuedAt       time.Time `json:"issued_at"`
-	ExpiresAt      time.Time `json:"expires_at"` // re-certification required every 6 months
-	Fingerprint    string    `json:"fingerprint"` // SHA-256 of cert data
+	ID               string                     `json:"id"`
+	Name             string                     `json:"name"`
+	Organization     string                     `json:"organization,omitempty"`
+	Repository       string                     `json:"repository,omitempty"`
+	Level            string                     `json:"level"`            // highest conformant level: "L0", "L0-L1", etc., or "none"
+	ConformantLevel  int                        `json:"conformant_level"` // -1 if not even level 0 passes
+	Status           string                     `json:"status"`           // "pass", "partial", "fail", "pending", "error" or "expired"
+	Passed           int                        `json:"passed"`
+	Failed           int                        `json:"failed"`
+	Total            int                        `json:"total"`
+	Extensions       map[string]ExtensionResult `json:"extensions,omitempty"` // by extension name, e.g. "webhooks"
+	Levels           []LevelResult              `json:"levels,omitempty"`     // core levels that ran, in order
+	Failures         []FailedTest               `json:"failures,omitempty"`   // failing core and extension tests
+	BadgeURL         string                     `json:"badge_url"`
+	IssuedAt         time.Time                  `json:"issued_at"`
+	UpdatedAt        time.Time                  `json:"updated_at,omitzero"`
+	ExpiresAt        time.Time                  `json:"expires_at"`      // re-certification required every 6 months
+	Fingerprint      string                     `json:"fingerprint"`     // SHA-256 of cert data
+	Token            string                     `json:"token,omitempty"` // signed JWS of the results; see CertificateSigner
+	RevokedAt        *time.Time                 `json:"revoked_at,omitempty"`
+	RevocationReason string                     `json:"revocation_reason,omitempty"`
+
+	// RunError is why the last certification run failed. A certificate
+	// whose first run failed has the status "error" and no results.
+	RunError string `json:"run_error,omitempty"`
+
+	// Attestation says who ran the suite: the portal, or the submitter of
+	// a signed or uploaded report.
+	Attestation Attestation `json:"attestation"`
+
+	// Request is the certification request, kept for renewals and
+	// notifications. Storage persists it; the API never serves it.
+	Request *CertificationRequest `json:"-"`
+}
+
+// ExtensionResult counts the results of one extension's tests. Extensions
+// do not affect a certificate's level.
+type ExtensionResult struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	Total  int `json:"total"`
+}
+
+// certificateValidity is how long a certificate is valid after its last
+// run; re-certification is required every 6 months.
+const certificateValidity = 180 * 24 * time.Hour
+
+// Attestation kinds.
+const (
+	// AttestationPortal certificates come from runs by the portal itself.
+	AttestationPortal = "portal-verified"
+	// AttestationSelf certificates come from reports run and signed by
+	// their submitter and sent to POST /api/reports.
+	AttestationSelf = "self-attested"
+	// AttestationSelfReported certificates come from unsigned reports
+	// uploaded through the web form.
+	AttestationSelfReported = "self-reported"
+)
+
+// Attestation records who ran the suite behind a certificate's results.
+type Attestation struct {
+	Kind string `json:"kind"` // AttestationPortal, AttestationSelf or AttestationSelfReported
+	// KeyID and ReportDigest identify the signed report behind a
+	// self-attested certificate; see lib.KeyID and lib.ReportDigest.
+	KeyID        string          `json:"key_id,omitempty"`
+	ReportDigest string          `json:"report_sha256,omitempty"`
+	Commit       *lib.CommitInfo `json:"commit,omitempty"` // backend revision, from the report
+}
+
+// LevelResult counts the results of one core level.
+type LevelResult struct {
+	Level  int `json:"level"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	Total  int `json:"total"`
+}
+
+// FailedTest is a test that failed or errored, as listed on certificates.
+type FailedTest struct {
+	TestID   string `json:"test_id"`
+	Name     string `json:"name"`
+	Level    int    `json:"level"`
+	Category string `json:"category"`
+	SpecRef  string `json:"spec_ref,omitempty"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"` // the first assertion failure
 }
 
 // CertificationStore manages issued certificates.
 type CertificationStore struct {
-	mu    sync.RWMutex
-	certs map[string]*Certificate // id -> cert
+	mu      sync.Mutex // serializes read-modify-write updates
+	storage Storage
+	signer  *CertificateSigner // nil: certificates carry no token
 }
 
-// NewCertificationStore creates a store for issued certificates.
-func NewCertificationStore() *CertificationStore {
-	return &CertificationStore{
-		certs: make(map[string]*Certificate),
-	}
+// NewCertificationStore creates a store for issued certificates, kept in
+// storage.
+func NewCertificationStore(storage Storage) *CertificationStore {
+	return &CertificationStore{storage: storage}
+}
+
+// Issue creates and stores a new certificate from a conformance report.
+func (cs *CertificationStore) Issue(req CertificationRequest, report lib.SuiteReport) (*Certificate, error) {
+	return cs.issue(req, report, Attestation{Kind: AttestationPortal})
 }
 
-// Issue creates and stores a new certificate.
-func (cs *CertificationStore) Issue(req CertificationRequest, passed, failed, total int) *Certificate {
+// issue is Issue for reports the portal did not run itself.
+func (cs *CertificationStore) issue(req CertificationRequest, report lib.SuiteReport, att Attestation) (*Certificate, error) {
 	cs.mu.Lock()
 	defer cs.mu.Unlock()
 
-	level := computeLevel(passed, total)
-	status := "pass"
-	if failed > 0 && passed > 0 {
-		status = "partial"
-	}
-	if passed == 0 {
-		status = "fail"
+	res := summarize(report)
+	level := levelName(res.conformantLevel)
+	status := res.status()
+	if res.total == 0 {
+		status = "pending" // until the queued run finishes
 	}
 
 	now := time.Now()
 	certData := fmt.Sprintf("%s:%s:%s:%d:%d:%s",
-		req.Name, req.ServerURL, level, passed, total, now.Format(time.RFC3339))
+		req.Name, req.ServerURL, level, res.passed, res.total, now.Format(time.RFC3339Nano))
 	hash := sha256.Sum256([]byte(certData))
 	fingerprint := hex.EncodeToString(hash[:])
 	id := "cert_" + fingerprint[:16]
 
 	cert := &Certificate{
-		ID:           id,
-		Name:         req.Name,
-		Organization: req.Organization,
-		Repository:   req.Repository,
-		Level:        level,
-		Status:       status,
-		Passed:       passed,
-		Failed:       failed,
-		Total:        total,
-		BadgeURL:     fmt.Sprintf("/badge/%s.svg?name=%s&status=%s", level, req.Name, status),
-		IssuedAt:     now,
-		ExpiresAt:    now.Add(180 * 24 * time.Hour), // 6 months
-		Fingerprint:  fingerprint,
-	}
-
-	cs.certs[id] = cert
-	return cert
-}
-
-// Get retrieves a certificate by ID.
-func (cs *CertificationStore) Get(id string) (*Certificate, bool) {
-	cs.mu.RLock()
-	defer cs.mu.RUnlock()
-	c, ok := cs.certs[id]
-	return c, ok
-}
-
-// List returns all certificates, sorted by issue date (newest first).
-func (cs *CertificationStore) List() []*Certificate {
-	cs.mu.RLock()
-	defer cs.mu.RUnlock()
-	result := make([]*Certificate, 0, len(cs.certs))
-	for _, c := range cs.certs {
-		result = append(result, c)
-	}
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].IssuedAt.After(result[j].IssuedAt)
+		ID:              id,
+		Name:            req.Name,
+		Organization:    req.Organization,
+		Repository:      req.Repository,
+		Level:           level,
+		ConformantLevel: res.conformantLevel,
+		Status:          status,
+		Passed:          res.passed,
+		Failed:          res.failed,
+		Total:           res.total,
+		Extensions:      res.extensions,
+		Levels:          res.levels,
+		Failures:        res.failures,
+		BadgeURL:        certificateBadgeURL(id),
+		IssuedAt:        now,
+		UpdatedAt:       now,
+		ExpiresAt:       now.Add(certificateValidity),
+		Fingerprint:     fingerprint,
+		Attestation:     att,
+		Request:         &req,
+	}
+
+	if err := cs.sign(cert); err != nil {
+		return nil, err
+	}
+	if err := cs.storage.Put(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Get retrieves a certificate by ID. It returns ErrCertificateNotFound for
+// unknown IDs.
+func (cs *CertificationStore) Get(id string) (*Certificate, error) {
+	return cs.storage.Get(id)
+}
+
+// List returns up to limit certificates, sorted by issue date (newest
+// first) and starting at offset, and the total number of certificates.
+func (cs *CertificationStore) List(offset, limit int) ([]*Certificate, int, error) {
+	return cs.storage.List(offset, limit)
+}
+
+// Update applies fn to a stored certificate, re-signs it and saves the
+// result.
+func (cs *CertificationStore) Update(id string, fn func(*Certificate)) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cert, err := cs.storage.Get(id)
+	if err != nil {
+		return err
+	}
+	fn(cert)
+	cert.UpdatedAt = time.Now()
+	if err := cs.sign(cert); err != nil {
+		return err
+	}
+	return cs.storage.Put(cert)
+}
+
+// sign sets the certificate's token. Certificates still waiting for their
+// run have no results to attest and get none, and neither do self-reported
+// ones, whose results nobody signed.
+func (cs *CertificationStore) sign(cert *Certificate) error {
+	cert.Token = ""
+	if cs.signer == nil || cert.Total == 0 || cert.Attestation.Kind == AttestationSelfReported {
+		return nil
+	}
+	token, err := cs.signer.Sign(cert)
+	if err != nil {
+		return fmt.Errorf("signing certificate %s: %w", cert.ID, err)
+	}
+	cert.Token = token
+	return nil
+}
+
+// Revoke marks a certificate as revoked; it no longer verifies. Revoking
+// it again keeps the original time and reason.
+func (cs *CertificationStore) Revoke(id, reason string) (*Certificate, error) {
+	var revoked *Certificate
+	err := cs.Update(id, func(c *Certificate) {
+		if c.RevokedAt == nil {
+			now := time.Now()
+			c.RevokedAt = &now
+			c.RevocationReason = reason
+		}
+		revoked = c
 	})
-	return result
+	return revoked, err
 }
 
-// Verify checks if a certificate fingerprint is valid.
+// Delete removes a certificate.
+func (cs *CertificationStore) Delete(id string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.storage.Delete(id)
+}
+
+// VerifyToken checks a certificate token's signature and expiry, and that
+// the certificate it names exists, has not been revoked and still carries
+// that token.
+func (cs *CertificationStore) VerifyToken(token string, now time.Time) (*CertificateClaims, error) {
+	if cs.signer == nil {
+		return nil, fmt.Errorf("%w: certificates are not signed", ErrInvalidToken)
+	}
+	claims, err := VerifyCertificateToken(token, cs.signer.JWKS(), now)
+	if err != nil {
+		return nil, err
+	}
+	c, err := cs.storage.Get(claims.Subject)
+	if err != nil {
+		return claims, err
+	}
+	if c.RevokedAt != nil {
+		return claims, fmt.Errorf("certificate %s was revoked at %s", c.ID, c.RevokedAt.UTC().Format(time.RFC3339))
+	}
+	if c.Token != strings.TrimSpace(token) {
+		return claims, fmt.Errorf("certificate %s has been re-issued", c.ID)
+	}
+	return claims, nil
+}
+
+// Verify checks if a certificate fingerprint is valid: the certificate has
+// results, and has neither been revoked nor expired.
 func (cs *CertificationStore) Verify(id, fingerprint string) bool {
-	cs.mu.RLock()
-	defer cs.mu.RUnlock()
-	c, ok := cs.certs[id]
-	if !ok {
+	c, err := cs.storage.Get(id)
+	if err != nil {
 		return false
 	}
-	return c.Fingerprint == fingerprint && time.Now().Before(c.ExpiresAt)
+	return c.Fingerprint == fingerprint && c.Total > 0 && c.RevokedAt == nil && time.Now().Before(c.ExpiresAt)
 }
 
 // --- Portal HTTP Handlers ---
 
 // Portal serves the conformance certification portal endpoints.
 type Portal struct {
-	store *CertificationStore
-	badge *Handler
+	store      *CertificationStore
+	badge      *Handler
+	jobs       *JobQueue
+	adminToken string
+	webhooks   *WebhookNotifier
+
+	apiKeys     []string
+	targets     *TargetPolicy
+	keyLimiter  *rateLimiter
+	ipLimiter   *rateLimiter
+	maxInFlight int
+	trustProxy  bool
+
+	specBaseURL  string
+	reportKeys   map[string]bool // nil trusts every key
+	suiteVersion string
+	suite        []lib.TestCase
+
+	renewMu  sync.Mutex
+	renewals map[string]time.Time // certificate ID -> last renewal queued
+
+	deliveryMu  sync.Mutex
+	deliveryCtx context.Context // the runner's, once started
+	deliveries  sync.WaitGroup  // background webhook deliveries
+}
+
+// PortalConfig configures a Portal.
+type PortalConfig struct {
+	// Storage keeps issued certificates. Defaults to a MemoryStorage.
+	Storage Storage
+	// AdminToken is the bearer token required to revoke certificates.
+	// Revocation is disabled when it is empty.
+	AdminToken string
+	// SigningKey signs certificate tokens and is published at
+	// /.well-known/jwks.json. Certificates carry no token when it is nil.
+	SigningKey ed25519.PrivateKey
+	// Issuer is the "iss" claim of certificate tokens, typically the
+	// portal's public URL.
+	Issuer string
+	// Webhooks delivers status notifications to callback URLs. Defaults
+	// to NewWebhookNotifier() with a client restricted by Targets.
+	Webhooks *WebhookNotifier
+
+	// APIKeys are the bearer tokens (or X-API-Key values) accepted by
+	// POST /api/certify; the admin token is accepted too. Anyone may
+	// request certification when it is empty.
+	APIKeys []string
+	// KeyRateLimit and IPRateLimit limit certification requests per API
+	// key and, for anonymous callers, per client IP. Zero is unlimited.
+	KeyRateLimit RateLimit
+	IPRateLimit  RateLimit
+	// MaxInFlight caps the certification runs a key or IP may have queued
+	// or running at once. Zero is unlimited.
+	MaxInFlight int
+	// Targets restricts the server and callback URLs requests may name.
+	// Defaults to DefaultTargetPolicy(), which denies non-public networks.
+	Targets *TargetPolicy
+	// TrustProxy takes client IPs from the last X-Forwarded-For entry, as
+	// set by a reverse proxy in front of the portal.
+	TrustProxy bool
+
+	// SpecBaseURL is where the web pages link spec_ref anchors such as
+	// "ojs-core#section-5.1": to SpecBaseURL + "ojs-core.md#section-5.1".
+	// Defaults to DefaultSpecBaseURL.
+	SpecBaseURL string
+
+	// ReportKeys restricts POST /api/reports to reports signed by these
+	// keys. Empty accepts any key whose signature verifies.
+	ReportKeys []ed25519.PublicKey
+	// SuiteVersion is the test_suite_version submitted reports must have.
+	// Defaults to httprunner.SuiteVersion, the suite the portal runs.
+	SuiteVersion string
+	// Suite is that suite's tests, as loaded by lib.LoadTests. Submitted
+	// reports must list every test up to the level they reach, and no
+	// others. POST /api/reports is refused when it is empty.
+	Suite []lib.TestCase
 }
 
-// NewPortal creates a certification portal.
+// NewPortal creates a certification portal that keeps certificates in
+// memory. Certification requests are queued until StartRunner is called.
 func NewPortal() *Portal {
+	return NewPortalWithConfig(PortalConfig{})
+}
+
+// NewPortalWithConfig creates a certification portal from cfg.
+func NewPortalWithConfig(cfg PortalConfig) *Portal {
+	if cfg.Storage == nil {
+		cfg.Storage = NewMemoryStorage()
+	}
+	if cfg.Targets == nil {
+		cfg.Targets = DefaultTargetPolicy()
+	}
+	if cfg.SpecBaseURL == "" {
+		cfg.SpecBaseURL = DefaultSpecBaseURL
+	}
+	if cfg.SuiteVersion == "" {
+		cfg.SuiteVersion = httprunner.SuiteVersion
+	}
+	if cfg.Webhooks == nil {
+		cfg.Webhooks = NewWebhookNotifier()
+		cfg.Webhooks.Client = cfg.Targets.Client(10 * time.Second)
+	}
+	store := NewCertificationStore(cfg.Storage)
+	if cfg.SigningKey != nil {
+		store.signer = NewCertificateSigner(cfg.SigningKey, cfg.Issuer)
+	}
 	return &Portal{
-		store: NewCertificationStore(),
-		badge: NewHandler(),
+		store:      store,
+		badge:      NewHandler(),
+		jobs:       NewJobQueue(100),
+		adminToken: cfg.AdminToken,
+		webhooks:   cfg.Webhooks,
+		renewals:   make(map[string]time.Time),
+
+		apiKeys:     cfg.APIKeys,
+		targets:     cfg.Targets,
+		keyLimiter:  newRateLimiter(cfg.KeyRateLimit),
+		ipLimiter:   newRateLimiter(cfg.IPRateLimit),
+		maxInFlight: cfg.MaxInFlight,
+		trustProxy:  cfg.TrustProxy,
+
+		specBaseURL:  cfg.SpecBaseURL,
+		reportKeys:   reportKeySet(cfg.ReportKeys),
+		suiteVersion: cfg.SuiteVersion,
+		suite:        cfg.Suite,
 	}
 }
 
-// HandleCertify processes a certification request.
+// StartRunner starts workers goroutines that run queued certification
+// requests with run and record the results, or why the run failed, on
+// their certificates. They stop when ctx is cancelled, as do webhook
+// deliveries (see WaitDeliveries). Certificates still pending from an
+// earlier process, whose runs were lost when it stopped, are queued again
+// first.
+func (p *Portal) StartRunner(ctx context.Context, run RunFunc, workers int) error {
+	p.deliveryMu.Lock()
+	p.deliveryCtx = ctx
+	p.deliveryMu.Unlock()
+
+	err := p.resumePending()
+	p.jobs.Start(ctx, workers, run, func(job Job) {
+		switch {
+		case job.Report != nil:
+			p.UpdateCertificate(job.CertificateID, *job.Report)
+		case ctx.Err() == nil:
+			p.failRun(job.CertificateID, job.Error)
+		}
+		// Runs cut short by shutdown stay pending and are resumed.
+	})
+	return err
+}
+
+// resumePending queues a run for every pending portal certificate that
+// has none. Certificates that cannot be queued are marked failed.
+func (p *Portal) resumePending() error {
+	certs, _, err := p.store.List(0, 0)
+	if err != nil {
+		return fmt.Errorf("listing certificates: %w", err)
+	}
+	var errs []error
+	for _, cert := range certs {
+		if cert.Total > 0 || cert.Status == "error" || cert.RevokedAt != nil || cert.Attestation.Kind != AttestationPortal {
+			continue
+		}
+		if _, ok := p.jobs.Get(cert.ID); ok {
+			continue
+		}
+		reason := "the portal restarted before the run finished and has no request to run again"
+		if cert.Request != nil {
+			_, err := p.jobs.Enqueue(cert.ID, *cert.Request)
+			if err == nil {
+				continue
+			}
+			reason = "the portal restarted before the run finished and could not queue it again: " + err.Error()
+		}
+		if err := p.failRun(cert.ID, reason); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("resuming pending certificates: %v", errs)
+	}
+	return nil
+}
+
+// HandleCertify processes a certification request. It requires an API key
+// if the portal has any, and applies the rate limits and target policy.
 // POST /api/certify
 func (p *Portal) HandleCertify(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writePortalError(w, http.StatusMethodNotAllowed, "POST required")
 		return
 	}
+	client, status, reason := p.admit(w, r, bearerToken(r))
+	if status != 0 {
+		writePortalError(w, status, reason)
+		return
+	}
 
 	var req CertificationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -166,9 +518,40 @@ func (p *Portal) HandleCertify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// In production, this would run the actual conformance suite.
-	// For now, return a placeholder that the async runner will fill in.
-	cert := p.store.Issue(req, 0, 0, 0)
+	if _, err := ParseLevel(req.Level); err != nil {
+		writePortalError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := p.targets.CheckURL(req.ServerURL); err != nil {
+		writeTargetError(w, "server_url", err)
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := p.targets.CheckURL(req.CallbackURL); err != nil {
+			writeTargetError(w, "callback_url", err)
+			return
+		}
+		if req.CallbackSecret == "" {
+			writePortalError(w, http.StatusBadRequest, "callback_secret is required with callback_url")
+			return
+		}
+	}
+
+	// The certificate is filled in when the queued run finishes.
+	cert, err := p.store.Issue(req, lib.SuiteReport{})
+	if err != nil {
+		writePortalError(w, http.StatusInternalServerError, fmt.Sprintf("storing certificate: %v", err))
+		return
+	}
+	if _, err := p.jobs.enqueue(cert.ID, req, client, p.maxInFlight); err != nil {
+		p.store.Delete(cert.ID)
+		status := http.StatusServiceUnavailable
+		if errors.Is(err, errInFlight) {
+			status = http.StatusTooManyRequests
+		}
+		writePortalError(w, status, err.Error())
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
@@ -176,9 +559,48 @@ func (p *Portal) HandleCertify(w http.ResponseWriter, r *http.Request) {
 		"certificate_id": cert.ID,
 		"status":         "queued",
 		"message":        "conformance test run has been queued",
+		"status_url":     "/api/certify/" + cert.ID,
 	})
 }
 
+// HandleCertifyStatus reports the conformance run behind a certificate:
+// queued, running, done (with the full report) or failed. Once the portal
+// has dropped a finished run, it is reported from the certificate, without
+// the report.
+// GET /api/certify/{id}
+func (p *Portal) HandleCertifyStatus(w http.ResponseWriter, r *http.Request) {
+	job, ok := p.jobs.Get(r.PathValue("id"))
+	if !ok {
+		job, ok = p.finishedJob(r.PathValue("id"))
+	}
+	if !ok {
+		writePortalError(w, http.StatusNotFound, "certification run not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// finishedJob describes the last finished run of a portal certificate
+// whose job is no longer kept.
+func (p *Portal) finishedJob(id string) (Job, bool) {
+	cert, err := p.store.Get(id)
+	if err != nil || cert.Attestation.Kind != AttestationPortal {
+		return Job{}, false
+	}
+	job := Job{CertificateID: cert.ID, QueuedAt: cert.IssuedAt, FinishedAt: &cert.UpdatedAt}
+	switch {
+	case cert.Status == "error":
+		job.Status, job.Error = JobFailed, cert.RunError
+	case cert.Total > 0:
+		job.Status = JobDone
+	default:
+		return Job{}, false
+	}
+	return job, true
+}
+
 // HandleGetCertificate retrieves a certificate by ID.
 // GET /api/certificates/{id}
 func (p *Portal) HandleGetCertificate(w http.ResponseWriter, r *http.Request) {
@@ -188,35 +610,125 @@ func (p *Portal) HandleGetCertificate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cert, ok := p.store.Get(id)
-	if !ok {
+	cert, err := p.store.Get(id)
+	if errors.Is(err, ErrCertificateNotFound) {
 		writePortalError(w, http.StatusNotFound, "certificate not found")
 		return
 	}
+	if err != nil {
+		writePortalError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(cert)
 }
 
-// HandleListCertificates returns all issued certificates.
-// GET /api/certificates
+// Default and maximum page sizes for HandleListCertificates.
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// HandleListCertificates returns a page of issued certificates, newest
+// first.
+// GET /api/certificates?offset={n}&limit={n}
 func (p *Portal) HandleListCertificates(w http.ResponseWriter, r *http.Request) {
-	certs := p.store.List()
+	offset, limit := 0, defaultListLimit
+	for name, dst := range map[string]*int{"offset": &offset, "limit": &limit} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writePortalError(w, http.StatusBadRequest, name+" must be a non-negative integer")
+			return
+		}
+		*dst = n
+	}
+	if limit == 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	certs, total, err := p.store.List(offset, limit)
+	if err != nil {
+		writePortalError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
 		"certificates": certs,
 		"count":        len(certs),
+		"total":        total,
+		"offset":       offset,
+		"limit":        limit,
 	})
 }
 
-// HandleVerify checks if a certificate is valid.
+// HandleRevoke revokes a certificate. It requires the portal's admin token
+// as a bearer token and accepts an optional {"reason": "..."} body.
+// POST /api/certificates/{id}/revoke
+func (p *Portal) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if p.adminToken == "" {
+		writePortalError(w, http.StatusForbidden, "revocation is disabled")
+		return
+	}
+	if !tokenMatches(bearerToken(r), p.adminToken) {
+		writePortalError(w, http.StatusUnauthorized, "admin token required")
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writePortalError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+	}
+
+	cert, err := p.store.Revoke(r.PathValue("id"), body.Reason)
+	if errors.Is(err, ErrCertificateNotFound) {
+		writePortalError(w, http.StatusNotFound, "certificate not found")
+		return
+	}
+	if err != nil {
+		writePortalError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cert)
+}
+
+// HandleVerify checks if a certificate is valid, by its signed token or by
+// its ID and fingerprint.
+// GET /api/verify?token={jws}
 // GET /api/verify?id={id}&fingerprint={fp}
 func (p *Portal) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		resp := map[string]any{"valid": true}
+		claims, err := p.store.VerifyToken(token, time.Now())
+		if claims != nil {
+			resp["id"] = claims.Subject
+			resp["claims"] = claims
+		}
+		if err != nil {
+			resp["valid"] = false
+			resp["error"] = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
 	id := r.URL.Query().Get("id")
 	fp := r.URL.Query().Get("fingerprint")
 
 	if id == "" || fp == "" {
-		writePortalError(w, http.StatusBadRequest, "id and fingerprint are required")
+		writePortalError(w, http.StatusBadRequest, "token, or id and fingerprint, are required")
 		return
 	}
 
@@ -228,61 +740,444 @@ func (p *Portal) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleJWKS serves the public keys that sign certificate tokens.
+// GET /.well-known/jwks.json
+func (p *Portal) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	keys := JWKS{Keys: []JWK{}}
+	if p.store.signer != nil {
+		keys = p.store.signer.JWKS()
+	}
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// HandleCertificateBadge renders a certificate's badge from its stored
+// results. Expired and revoked certificates get a grey or red badge that
+// says so, and unknown IDs a grey "not found" one.
+// GET /badge/cert/{id}.svg
+func (p *Portal) HandleCertificateBadge(w http.ResponseWriter, r *http.Request) {
+	id, ok := strings.CutSuffix(r.PathValue("file"), ".svg")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	cert, err := p.store.Get(id)
+	if errors.Is(err, ErrCertificateNotFound) {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(SVG("OJS conformance", "not found", "")))
+		return
+	}
+	if err != nil {
+		writePortalError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := time.Now()
+	label, value, status := certificateBadge(cert, now)
+	svg := []byte(SVG(label, value, status))
+	sum := sha256.Sum256(svg)
+
+	// ServeContent answers If-None-Match and If-Modified-Since.
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "max-age=300")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", certificateModified(cert, now), bytes.NewReader(svg))
+}
+
+// certificateBadge returns the label, value and status of a certificate's
+// badge at now. The value names the attestation, such as "L0-L2
+// self-attested", when the portal did not run the suite.
+func certificateBadge(cert *Certificate, now time.Time) (label, value, status string) {
+	label = "OJS " + cert.Name
+	switch {
+	case cert.RevokedAt != nil:
+		return label, "revoked", "revoked"
+	case !now.Before(cert.ExpiresAt):
+		return label, cert.Level + " expired", "expired"
+	case cert.Status == "error":
+		return label, "run failed", "error"
+	case cert.Total == 0:
+		return label, "pending", "pending"
+	case cert.Attestation.Kind == AttestationSelf || cert.Attestation.Kind == AttestationSelfReported:
+		return label, cert.Level + " " + cert.Attestation.Kind, cert.Status
+	default:
+		return label, cert.Level, cert.Status
+	}
+}
+
+// certificateModified is when a certificate's badge last changed: its last
+// update, or its expiry once that has passed. Certificates stored before
+// UpdatedAt was recorded fall back to IssuedAt.
+func certificateModified(cert *Certificate, now time.Time) time.Time {
+	modified := cert.UpdatedAt
+	if modified.IsZero() {
+		modified = cert.IssuedAt
+	}
+	if !now.Before(cert.ExpiresAt) && cert.ExpiresAt.After(modified) {
+		modified = cert.ExpiresAt
+	}
+	return modified
+}
+
+// certificateBadgeURL is the path of a certificate's badge.
+func certificateBadgeURL(id string) string {
+	return "/badge/cert/" + id + ".svg"
+}
+
 // RegisterRoutes registers portal endpoints on a standard ServeMux.
 func (p *Portal) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/certify", p.HandleCertify)
+	mux.HandleFunc("GET /api/certify/{id}", p.HandleCertifyStatus)
+	mux.HandleFunc("POST /api/reports", p.HandleSubmitReport)
 	mux.HandleFunc("GET /api/certificates/{id}", p.HandleGetCertificate)
 	mux.HandleFunc("GET /api/certificates", p.HandleListCertificates)
+	mux.HandleFunc("POST /api/certificates/{id}/revoke", p.HandleRevoke)
 	mux.HandleFunc("GET /api/verify", p.HandleVerify)
-	mux.HandleFunc("GET /badge/", p.badge.ServeBadge)
+	mux.HandleFunc("GET /.well-known/jwks.json", p.HandleJWKS)
+	mux.HandleFunc("GET /badge/cert/{file}", p.HandleCertificateBadge)
 	mux.HandleFunc("GET /status", p.badge.ServeStatus)
+
+	// Web pages
+	mux.HandleFunc("GET /{$}", p.HandleLeaderboardPage)
+	mux.HandleFunc("GET /certificates/{id}", p.HandleCertificatePage)
+	mux.HandleFunc("GET /verify", p.HandleVerifyPage)
+	mux.HandleFunc("GET /upload", p.HandleUploadPage)
+	mux.HandleFunc("POST /upload", p.HandleUpload)
+	mux.Handle("GET /static/", staticHandler())
 }
 
-// UpdateCertificate updates a certificate with test results (called after async run).
-func (p *Portal) UpdateCertificate(id string, passed, failed, total int) error {
-	p.store.mu.Lock()
-	defer p.store.mu.Unlock()
+// UpdateCertificate updates a certificate with a report's results (called
+// after async run). The certificate is valid for another 6 months from
+// now, and its callback URL is notified if its status changed.
+func (p *Portal) UpdateCertificate(id string, report lib.SuiteReport) error {
+	res := summarize(report)
+	var previous string
+	var updated *Certificate
+	err := p.store.Update(id, func(cert *Certificate) {
+		previous = cert.Status
+		if cert.Total == 0 {
+			previous = "pending"
+		}
+		cert.Passed = res.passed
+		cert.Failed = res.failed
+		cert.Total = res.total
+		cert.Extensions = res.extensions
+		cert.Levels = res.levels
+		cert.Failures = res.failures
+		cert.ConformantLevel = res.conformantLevel
+		cert.Level = levelName(res.conformantLevel)
+		cert.Status = res.status()
+		cert.RunError = ""
+		cert.BadgeURL = certificateBadgeURL(cert.ID)
+		cert.ExpiresAt = time.Now().Add(certificateValidity)
+		updated = cert
+	})
+	if err != nil {
+		return fmt.Errorf("certificate %s: %w", id, err)
+	}
+	p.notify(updated, previous)
+	return nil
+}
 
-	cert, ok := p.store.certs[id]
-	if !ok {
-		return fmt.Errorf("certificate %s not found", id)
+// failRun records on a certificate that its run failed. A certificate
+// waiting for its first run gets the status "error", and its callback URL
+// is notified; one with results keeps them, and its status, until it
+// expires.
+func (p *Portal) failRun(id, reason string) error {
+	var previous string
+	var updated *Certificate
+	err := p.store.Update(id, func(cert *Certificate) {
+		previous = cert.Status
+		if cert.Total == 0 {
+			previous = "pending"
+			cert.Status = "error"
+		}
+		cert.RunError = reason
+		updated = cert
+	})
+	if err != nil {
+		return fmt.Errorf("certificate %s: %w", id, err)
 	}
+	p.notify(updated, previous)
+	return nil
+}
 
-	cert.Passed = passed
-	cert.Failed = failed
-	cert.Total = total
-	cert.Level = computeLevel(passed, total)
+// notify sends a status-change notification in the background if the
+// certificate's status is no longer previous and its request has a
+// callback URL.
+func (p *Portal) notify(cert *Certificate, previous string) {
+	if cert.Status == previous || cert.Request == nil || cert.Request.CallbackURL == "" {
+		return
+	}
+	n := Notification{
+		Event:          NotificationStatusChanged,
+		CertificateID:  cert.ID,
+		Name:           cert.Name,
+		PreviousStatus: previous,
+		Status:         cert.Status,
+		Level:          cert.Level,
+		ExpiresAt:      cert.ExpiresAt,
+		BadgeURL:       cert.BadgeURL,
+		Token:          cert.Token,
+		Timestamp:      time.Now(),
+	}
+	callback, secret := cert.Request.CallbackURL, cert.Request.CallbackSecret
 
-	if failed == 0 && passed > 0 {
-		cert.Status = "pass"
-	} else if passed > 0 {
-		cert.Status = "partial"
-	} else {
-		cert.Status = "fail"
+	p.deliveryMu.Lock()
+	ctx := p.deliveryCtx
+	p.deliveryMu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	p.deliveries.Add(1)
+	go func() {
+		defer p.deliveries.Done()
+		if err := p.webhooks.Deliver(ctx, callback, secret, n); err != nil && p.webhooks.OnError != nil {
+			p.webhooks.OnError(callback, n, err)
+		}
+	}()
+}
 
-	cert.BadgeURL = fmt.Sprintf("/badge/%s.svg?name=%s&status=%s", cert.Level, cert.Name, cert.Status)
-	return nil
+// WaitDeliveries waits until the background webhook deliveries have
+// ended, or ctx is done. Deliveries end early, reporting the error to the
+// notifier's OnError, once the context passed to StartRunner is
+// cancelled, so a shutdown cancels that context and then waits here.
+func (p *Portal) WaitDeliveries(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.deliveries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// certResults are the parts of a report a certificate records.
+type certResults struct {
+	conformantLevel       int
+	passed, failed, total int // core levels 0-4 only
+	extensions            map[string]ExtensionResult
+	levels                []LevelResult
+	failures              []FailedTest
 }
 
-func computeLevel(passed, total int) string {
-	if total == 0 {
+// summarize splits a report's results into the core levels, which
+// determine the certificate level, and extensions, which are recorded
+// separately. Level N is conformant only if every level from 0 to N has
+// results and every test in it passed, so a skipped test fails its level
+// as a failed one does; a level without results ends the conformant
+// levels, however later levels fare. The report counts extension tests (level
+// "ext" or an ext-* category) under their numeric level, so when it lists
+// its tests the level is recomputed without them; reports without a test
+// list use ConformantLevel as is.
+func summarize(report lib.SuiteReport) certResults {
+	failures := failedTests(report)
+	if len(report.Tests) == 0 {
+		res := certResults{conformantLevel: report.ConformantLevel, failures: failures}
+		r := report.Results
+		res.passed, res.failed, res.total = r.Passed, r.Failed+r.Errored+r.XFailed, r.Total
+		if res.total == 0 {
+			res.conformantLevel = -1
+		}
+		return res
+	}
+
+	res := certResults{conformantLevel: -1, failures: failures}
+	var levels [5]LevelResult
+	for _, t := range report.Tests {
+		passed := t.Status == "pass" || t.Status == lib.StatusXPass
+		failed := t.Status == "fail" || t.Status == "error" || t.Status == lib.StatusXFail
+
+		if t.Level == 99 || strings.HasPrefix(t.Category, "ext-") {
+			if res.extensions == nil {
+				res.extensions = make(map[string]ExtensionResult)
+			}
+			name := strings.TrimPrefix(t.Category, "ext-")
+			ext := res.extensions[name]
+			ext.Total++
+			if passed {
+				ext.Passed++
+			} else if failed {
+				ext.Failed++
+			}
+			res.extensions[name] = ext
+			continue
+		}
+
+		res.total++
+		if passed {
+			res.passed++
+		} else if failed {
+			res.failed++
+		}
+		if t.Level >= 0 && t.Level < len(levels) {
+			levels[t.Level].Total++
+			if passed {
+				levels[t.Level].Passed++
+			} else if failed {
+				levels[t.Level].Failed++
+			}
+		}
+	}
+	conformant := true
+	for lvl, l := range levels {
+		if l.Total == 0 {
+			conformant = false
+			continue
+		}
+		l.Level = lvl
+		res.levels = append(res.levels, l)
+		if l.Passed < l.Total {
+			conformant = false
+		}
+		if conformant {
+			res.conformantLevel = lvl
+		}
+	}
+	return res
+}
+
+// failedTests lists the report's failing and erroring tests.
+func failedTests(report lib.SuiteReport) []FailedTest {
+	var failures []FailedTest
+	for _, t := range report.Failures {
+		f := FailedTest{
+			TestID: t.TestID, Name: t.Name, Level: t.Level, Category: t.Category,
+			SpecRef: t.SpecRef, Status: t.Status,
+		}
+		if len(t.Failures) > 0 {
+			f.Message = t.Failures[0].Message
+		}
+		failures = append(failures, f)
+	}
+	return failures
+}
+
+// status is "pass" if every core test passed (a skipped test did not),
+// "partial" if some level is conformant, and "fail" otherwise.
+func (r certResults) status() string {
+	switch {
+	case r.conformantLevel < 0:
+		return "fail"
+	case r.passed == r.total:
+		return "pass"
+	default:
+		return "partial"
+	}
+}
+
+// levelName formats a conformant level for certificates and badges.
+func levelName(level int) string {
+	switch {
+	case level < 0:
+		return "none"
+	case level == 0:
 		return "L0"
+	default:
+		return fmt.Sprintf("L0-L%d", level)
+	}
+}
+
+// admit authenticates a certification request by the token it presented
+// and applies the caller's rate limit. If the request is refused it sets
+// WWW-Authenticate or Retry-After and returns the status and reason to
+// report; otherwise status is 0.
+func (p *Portal) admit(w http.ResponseWriter, r *http.Request, token string) (client string, status int, reason string) {
+	client, ok := p.client(r, token)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return "", http.StatusUnauthorized, "API key required"
+	}
+	limiter := p.ipLimiter
+	if strings.HasPrefix(client, "key:") {
+		limiter = p.keyLimiter
+	}
+	if ok, wait := limiter.allow(client, time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		return "", http.StatusTooManyRequests, "rate limit exceeded"
+	}
+	return client, 0, ""
+}
+
+// Admit wraps next in the API key and rate limit checks of POST
+// /api/certify, for endpoints served beside the portal such as the
+// transparency log's POST /api/log/entries.
+func (p *Portal) Admit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, status, reason := p.admit(w, r, bearerToken(r)); status != 0 {
+			writePortalError(w, status, reason)
+			return
+		}
+		next(w, r)
 	}
-	ratio := float64(passed) / float64(total)
-	if ratio >= 1.0 {
-		return "L0-L4"
+}
+
+// client identifies the caller of a certification request for rate
+// limits: "admin" for the admin token, "key:" and a hash of the key for
+// API keys, and "ip:" and the client IP otherwise. It reports false if the
+// portal requires a key and the caller presented no valid one.
+func (p *Portal) client(r *http.Request, token string) (string, bool) {
+	if p.adminToken != "" && tokenMatches(token, p.adminToken) {
+		return "admin", true
 	}
-	if ratio >= 0.8 {
-		return "L0-L3"
+	for _, key := range p.apiKeys {
+		if tokenMatches(token, key) {
+			sum := sha256.Sum256([]byte(key))
+			return "key:" + hex.EncodeToString(sum[:8]), true
+		}
 	}
-	if ratio >= 0.6 {
-		return "L0-L2"
+	if len(p.apiKeys) > 0 {
+		return "", false
 	}
-	if ratio >= 0.4 {
-		return "L0-L1"
+	return "ip:" + p.clientIP(r), true
+}
+
+// clientIP is the request's remote address, or the last X-Forwarded-For
+// entry when the portal trusts its proxy.
+func (p *Portal) clientIP(r *http.Request) string {
+	if p.trustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			entries := strings.Split(xff, ",")
+			return strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// bearerToken returns the request's bearer token or X-API-Key header.
+func bearerToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// tokenMatches compares a presented token with a configured one in
+// constant time. An empty configured token matches nothing.
+func tokenMatches(token, want string) bool {
+	return want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// writeTargetError reports a URL the target policy refused: 403 for denied
+// addresses, 400 for malformed URLs.
+func writeTargetError(w http.ResponseWriter, field string, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, ErrTargetDenied) {
+		status = http.StatusForbidden
 	}
-	return "L0"
+	writePortalError(w, status, field+": "+err.Error())
 }
 
 func writePortalError(w http.ResponseWriter, status int, message string) {
@@ -6,8 +6,46 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
 )
 
+// levelReport builds a report with levels[i] = {passed, failed} tests at
+// level i.
+func levelReport(levels ...[2]int) lib.SuiteReport {
+	var results []lib.TestResult
+	for lvl, c := range levels {
+		for i := range c[0] + c[1] {
+			status := "pass"
+			if i >= c[0] {
+				status = "fail"
+			}
+			results = append(results, lib.TestResult{Level: lvl, Category: "core", Status: status})
+		}
+	}
+	return lib.SuiteReport{Tests: lib.Outcomes(results)}
+}
+
+// skipLevel marks every test of a level in report as skipped.
+func skipLevel(report lib.SuiteReport, level int) lib.SuiteReport {
+	for i := range report.Tests {
+		if report.Tests[i].Level == level {
+			report.Tests[i].Status = "skip"
+		}
+	}
+	return report
+}
+
+func issue(t *testing.T, p *Portal, req CertificationRequest, report lib.SuiteReport) *Certificate {
+	t.Helper()
+	cert, err := p.store.Issue(req, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
 func TestPortalCertify(t *testing.T) {
 	p := NewPortal()
 	body := `{"server_url":"http://my-ojs:8080","name":"MyBackend","organization":"AcmeCorp"}`
@@ -54,10 +92,10 @@ func TestPortalCertifyMissingFields(t *testing.T) {
 
 func TestPortalGetCertificate(t *testing.T) {
 	p := NewPortal()
-	cert := p.store.Issue(CertificationRequest{
+	cert := issue(t, p, CertificationRequest{
 		ServerURL: "http://test:8080",
 		Name:      "TestBackend",
-	}, 170, 5, 175)
+	}, levelReport([2]int{100, 0}, [2]int{70, 5}))
 
 	req := httptest.NewRequest("GET", "/api/certificates/"+cert.ID, nil)
 	req.SetPathValue("id", cert.ID)
@@ -93,8 +131,8 @@ func TestPortalGetCertificateNotFound(t *testing.T) {
 
 func TestPortalListCertificates(t *testing.T) {
 	p := NewPortal()
-	p.store.Issue(CertificationRequest{ServerURL: "http://a:8080", Name: "A"}, 175, 0, 175)
-	p.store.Issue(CertificationRequest{ServerURL: "http://b:8080", Name: "B"}, 100, 75, 175)
+	issue(t, p, CertificationRequest{ServerURL: "http://a:8080", Name: "A"}, levelReport([2]int{175, 0}))
+	issue(t, p, CertificationRequest{ServerURL: "http://b:8080", Name: "B"}, levelReport([2]int{100, 75}))
 
 	req := httptest.NewRequest("GET", "/api/certificates", nil)
 	rec := httptest.NewRecorder()
@@ -115,10 +153,10 @@ func TestPortalListCertificates(t *testing.T) {
 
 func TestPortalVerify(t *testing.T) {
 	p := NewPortal()
-	cert := p.store.Issue(CertificationRequest{
+	cert := issue(t, p, CertificationRequest{
 		ServerURL: "http://valid:8080",
 		Name:      "ValidImpl",
-	}, 175, 0, 175)
+	}, levelReport([2]int{175, 0}))
 
 	// Valid verification
 	req := httptest.NewRequest("GET", "/api/verify?id="+cert.ID+"&fingerprint="+cert.Fingerprint, nil)
@@ -155,12 +193,15 @@ func TestPortalVerifyMissingParams(t *testing.T) {
 
 func TestUpdateCertificate(t *testing.T) {
 	p := NewPortal()
-	cert := p.store.Issue(CertificationRequest{
+	cert := issue(t, p, CertificationRequest{
 		ServerURL: "http://test:8080",
 		Name:      "TestImpl",
-	}, 0, 0, 0) // initially empty
+	}, lib.SuiteReport{}) // initially empty
+	if cert.Level != "none" || cert.ConformantLevel != -1 {
+		t.Errorf("expected no level before the run, got %s (%d)", cert.Level, cert.ConformantLevel)
+	}
 
-	err := p.UpdateCertificate(cert.ID, 175, 0, 175)
+	err := p.UpdateCertificate(cert.ID, levelReport([2]int{40, 0}, [2]int{35, 0}, [2]int{30, 0}, [2]int{35, 0}, [2]int{35, 0}))
 	if err != nil {
 		t.Fatalf("UpdateCertificate: %v", err)
 	}
@@ -177,24 +218,343 @@ func TestUpdateCertificate(t *testing.T) {
 	}
 }
 
-func TestComputeLevel(t *testing.T) {
+func TestCertificateLevels(t *testing.T) {
 	tests := []struct {
-		passed int
-		total  int
-		want   string
+		name   string
+		report lib.SuiteReport
+		level  string
+		status string
 	}{
-		{175, 175, "L0-L4"},
-		{140, 175, "L0-L3"},
-		{105, 175, "L0-L2"},
-		{70, 175, "L0-L1"},
-		{30, 175, "L0"},
-		{0, 0, "L0"},
+		{"all pass", levelReport([2]int{10, 0}, [2]int{10, 0}, [2]int{10, 0}), "L0-L2", "pass"},
+		{"level 2 fails", levelReport([2]int{10, 0}, [2]int{10, 0}, [2]int{9, 1}, [2]int{10, 0}), "L0-L1", "partial"},
+		// A high pass ratio does not make up for failing level 0.
+		{"level 0 fails", levelReport([2]int{0, 10}, [2]int{50, 0}, [2]int{50, 0}, [2]int{50, 0}), "none", "fail"},
+		{"level 0 only", levelReport([2]int{10, 0}), "L0", "pass"},
+		// Passing levels 3 and 4 says nothing about the levels below them.
+		{"levels 3 and 4 only", levelReport([2]int{}, [2]int{}, [2]int{}, [2]int{10, 0}, [2]int{10, 0}), "none", "fail"},
+		{"level 1 missing", levelReport([2]int{10, 0}, [2]int{}, [2]int{10, 0}), "L0", "pass"},
+		// A level whose tests were all skipped has no failures, but no passes
+		// either.
+		{"level 1 skipped", skipLevel(levelReport([2]int{10, 0}, [2]int{10, 0}, [2]int{10, 0}), 1), "L0", "partial"},
+		{"all skipped", skipLevel(levelReport([2]int{10, 0}), 0), "none", "fail"},
+		// Certification requests are issued before their run.
+		{"no results", lib.SuiteReport{}, "none", "pending"},
+		{"no test list", lib.SuiteReport{ConformantLevel: 3, Results: lib.ResultsSummary{Total: 10, Passed: 9, Failed: 1}}, "L0-L3", "partial"},
 	}
-
 	for _, tt := range tests {
-		got := computeLevel(tt.passed, tt.total)
-		if got != tt.want {
-			t.Errorf("computeLevel(%d, %d) = %s, want %s", tt.passed, tt.total, got, tt.want)
+		p := NewPortal()
+		cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T"}, tt.report)
+		if cert.Level != tt.level || cert.Status != tt.status {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.name, cert.Level, cert.Status, tt.level, tt.status)
 		}
 	}
 }
+
+func TestCertificateExtensions(t *testing.T) {
+	report := levelReport([2]int{10, 0}, [2]int{10, 0})
+	report.Tests = append(report.Tests,
+		lib.TestOutcome{Level: 0, Category: "ext-webhooks", Status: "fail"},
+		lib.TestOutcome{Level: 0, Category: "ext-webhooks", Status: "pass"},
+		lib.TestOutcome{Level: 99, Category: "admin-api", Status: "pass"},
+	)
+
+	p := NewPortal()
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T"}, report)
+	if cert.Level != "L0-L1" || cert.Status != "pass" || cert.Total != 20 {
+		t.Errorf("extension results affected the core level: %+v", cert)
+	}
+	want := map[string]ExtensionResult{
+		"webhooks":  {Passed: 1, Failed: 1, Total: 2},
+		"admin-api": {Passed: 1, Total: 1},
+	}
+	if len(cert.Extensions) != len(want) || cert.Extensions["webhooks"] != want["webhooks"] || cert.Extensions["admin-api"] != want["admin-api"] {
+		t.Errorf("unexpected extensions: %+v", cert.Extensions)
+	}
+}
+
+func TestPortalListCertificatesPagination(t *testing.T) {
+	p := NewPortal()
+	for i := range 5 {
+		if err := p.store.storage.Put(testCertificate(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	p.HandleListCertificates(rec, httptest.NewRequest("GET", "/api/certificates?offset=3&limit=10", nil))
+	var resp struct {
+		Certificates []Certificate `json:"certificates"`
+		Count        int           `json:"count"`
+		Total        int           `json:"total"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Count != 2 || resp.Total != 5 || resp.Certificates[0].ID != "cert_0000000000000001" {
+		t.Errorf("unexpected page: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	p.HandleListCertificates(rec, httptest.NewRequest("GET", "/api/certificates?limit=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative limit, got %d", rec.Code)
+	}
+}
+
+func TestPortalRevoke(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{AdminToken: "s3cret"})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T"}, levelReport([2]int{10, 0}))
+
+	revoke := func(id, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/certificates/"+id+"/revoke", strings.NewReader(`{"reason":"tests were skipped"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := revoke(cert.ID, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := revoke(cert.ID, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", rec.Code)
+	}
+	if rec := revoke("cert_missing", "s3cret"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if !p.store.Verify(cert.ID, cert.Fingerprint) {
+		t.Fatal("certificate should verify before revocation")
+	}
+
+	rec := revoke(cert.ID, "s3cret")
+	var got Certificate
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || got.RevokedAt == nil || got.RevocationReason != "tests were skipped" {
+		t.Fatalf("unexpected revoke response %d: %+v", rec.Code, got)
+	}
+	if p.store.Verify(cert.ID, cert.Fingerprint) {
+		t.Error("revoked certificate still verifies")
+	}
+
+	// Without an admin token, revocation is disabled.
+	p = NewPortal()
+	rec = httptest.NewRecorder()
+	p.HandleRevoke(rec, httptest.NewRequest("POST", "/api/certificates/x/revoke", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", rec.Code)
+	}
+}
+
+func TestPortalCertificateBadge(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "<Redis>"}, levelReport([2]int{10, 0}, [2]int{5, 0}))
+	if cert.BadgeURL != "/badge/cert/"+cert.ID+".svg" {
+		t.Errorf("unexpected badge URL %s", cert.BadgeURL)
+	}
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get(cert.BadgeURL)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("unexpected response %d: %s", rec.Code, body)
+	}
+	if !strings.Contains(body, "OJS &lt;Redis&gt;") || !strings.Contains(body, "L0-L1") || !strings.Contains(body, "#4c1") {
+		t.Errorf("badge does not match the certificate:\n%s", body)
+	}
+	etag, modified := rec.Header().Get("ETag"), rec.Header().Get("Last-Modified")
+	if etag == "" || modified == "" {
+		t.Fatalf("expected ETag and Last-Modified, got %v", rec.Header())
+	}
+	if rec := get(cert.BadgeURL, "If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+	if rec := get(cert.BadgeURL, "If-Modified-Since", modified); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 if not modified, got %d", rec.Code)
+	}
+
+	// Query parameters cannot change a certificate's badge.
+	if rec := get(cert.BadgeURL + "?status=pass&name=Other"); !strings.Contains(rec.Body.String(), "OJS &lt;Redis&gt;") {
+		t.Error("query parameters changed the badge")
+	}
+
+	if _, err := p.store.Revoke(cert.ID, "tests were skipped"); err != nil {
+		t.Fatal(err)
+	}
+	rec = get(cert.BadgeURL, "If-None-Match", etag)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), ">revoked<") || rec.Header().Get("ETag") == etag {
+		t.Errorf("expected a new revoked badge, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	if rec := get("/badge/cert/cert_missing.svg"); rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "not found") {
+		t.Errorf("expected a not found badge, got %d", rec.Code)
+	}
+	if rec := get("/badge/cert/" + cert.ID); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without .svg, got %d", rec.Code)
+	}
+	// The portal only serves badges of issued certificates.
+	if rec := get("/badge/L0-L4.svg?name=Forged&status=pass"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a badge without a certificate, got %d", rec.Code)
+	}
+}
+
+func TestCertificateBadgeStates(t *testing.T) {
+	issued := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	cert := &Certificate{Name: "Redis", Level: "L0-L2", Status: "partial", Total: 100, IssuedAt: issued, ExpiresAt: issued.Add(180 * 24 * time.Hour)}
+
+	for _, tc := range []struct {
+		name          string
+		now           time.Time
+		pending       bool
+		attestation   string
+		value, status string
+	}{
+		{"valid", issued.Add(time.Hour), false, AttestationPortal, "L0-L2", "partial"},
+		{"pending", issued, true, AttestationPortal, "pending", "pending"},
+		{"expired", cert.ExpiresAt, false, AttestationPortal, "L0-L2 expired", "expired"},
+		{"self-attested", issued.Add(time.Hour), false, AttestationSelf, "L0-L2 self-attested", "partial"},
+		{"self-reported", issued.Add(time.Hour), false, AttestationSelfReported, "L0-L2 self-reported", "partial"},
+	} {
+		c := *cert
+		c.Attestation.Kind = tc.attestation
+		if tc.pending {
+			c.Total = 0
+		}
+		label, value, status := certificateBadge(&c, tc.now)
+		if label != "OJS Redis" || value != tc.value || status != tc.status {
+			t.Errorf("%s: got %q %q %q", tc.name, label, value, status)
+		}
+	}
+
+	// A legacy certificate without UpdatedAt was last modified when issued,
+	// until it expires.
+	if m := certificateModified(cert, issued.Add(time.Hour)); !m.Equal(issued) {
+		t.Errorf("expected IssuedAt, got %s", m)
+	}
+	if m := certificateModified(cert, cert.ExpiresAt.Add(time.Hour)); !m.Equal(cert.ExpiresAt) {
+		t.Errorf("expected ExpiresAt after expiry, got %s", m)
+	}
+}
+
+func TestPortalCertifyAccessControl(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{
+		AdminToken:   "admin",
+		APIKeys:      []string{"key-a", "key-b"},
+		KeyRateLimit: RateLimit{Requests: 2, Window: time.Hour},
+		MaxInFlight:  1,
+	})
+	certify := func(body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/certify", strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		p.HandleCertify(rec, req)
+		return rec
+	}
+	body := `{"server_url":"http://my-ojs:8080","name":"MyBackend"}`
+
+	if rec := certify(body); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("expected 401 without a key, got %d", rec.Code)
+	}
+	if rec := certify(body, "Authorization", "Bearer wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong key, got %d", rec.Code)
+	}
+	if rec := certify(body, "Authorization", "Bearer key-a"); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// key-a's first run is still queued.
+	if rec := certify(body, "X-API-Key", "key-a"); rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "in flight") {
+		t.Errorf("expected the in-flight cap, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := certify(body, "X-API-Key", "key-a"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected the rate limit with Retry-After, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := certify(body, "X-API-Key", "key-b"); rec.Code != http.StatusAccepted {
+		t.Errorf("keys share limits: %d", rec.Code)
+	}
+	if rec := certify(body, "Authorization", "Bearer admin"); rec.Code != http.StatusAccepted {
+		t.Errorf("expected the admin token to be accepted, got %d", rec.Code)
+	}
+	if _, total, _ := p.store.List(0, 0); total != 3 {
+		t.Errorf("expected 3 certificates, got %d", total)
+	}
+}
+
+func TestPortalAdmit(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{APIKeys: []string{"key-a"}})
+	called := 0
+	h := p.Admit(func(w http.ResponseWriter, r *http.Request) { called++ })
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("POST", "/api/log/entries", nil))
+	if rec.Code != http.StatusUnauthorized || called != 0 {
+		t.Errorf("expected 401 without a key, got %d", rec.Code)
+	}
+	req := httptest.NewRequest("POST", "/api/log/entries", nil)
+	req.Header.Set("X-API-Key", "key-a")
+	h(httptest.NewRecorder(), req)
+	if called != 1 {
+		t.Errorf("expected the handler to run with a key")
+	}
+}
+
+func TestPortalCertifyIPRateLimit(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{IPRateLimit: RateLimit{Requests: 1, Window: time.Hour}, TrustProxy: true})
+	certify := func(remote, forwarded string) int {
+		req := httptest.NewRequest("POST", "/api/certify", strings.NewReader(`{"server_url":"http://my-ojs:8080","name":"X"}`))
+		req.RemoteAddr = remote
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		rec := httptest.NewRecorder()
+		p.HandleCertify(rec, req)
+		return rec.Code
+	}
+	if code := certify("192.0.2.1:1234", ""); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	if code := certify("192.0.2.1:5678", ""); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for the same IP, got %d", code)
+	}
+	// Behind the proxy, the last forwarded address is the client.
+	if code := certify("10.0.0.1:1234", "198.51.100.7, 203.0.113.9"); code != http.StatusAccepted {
+		t.Errorf("expected 202 for a forwarded client, got %d", code)
+	}
+	if code := certify("10.0.0.1:1234", "192.0.2.99, 203.0.113.9"); code != http.StatusTooManyRequests {
+		t.Errorf("expected 429 for the same forwarded client, got %d", code)
+	}
+}
+
+func TestPortalCertifyDeniedTargets(t *testing.T) {
+	p := NewPortal()
+	for body, want := range map[string]int{
+		`{"server_url":"http://127.0.0.1:8080","name":"X"}`:                                                               http.StatusForbidden,
+		`{"server_url":"http://169.254.169.254/latest","name":"X"}`:                                                       http.StatusForbidden,
+		`{"server_url":"http://localhost:8080","name":"X"}`:                                                               http.StatusForbidden,
+		`{"server_url":"file:///etc/passwd","name":"X"}`:                                                                  http.StatusBadRequest,
+		`{"server_url":"https://ojs.example.com","name":"X","callback_url":"http://10.0.0.5/hook","callback_secret":"s"}`: http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		p.HandleCertify(rec, httptest.NewRequest("POST", "/api/certify", strings.NewReader(body)))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", body, want, rec.Code, rec.Body.String())
+		}
+	}
+	if _, total, _ := p.store.List(0, 0); total != 0 {
+		t.Errorf("expected no certificates, got %d", total)
+	}
+}
@@ -0,0 +1,105 @@
+package badge
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Rate Limits ---
+
+// RateLimit allows Requests per Window, in bursts of up to Requests. The
+// zero RateLimit is unlimited.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseRateLimit parses "N/window", such as "10/h", "100/24h" or "5/30m".
+// A bare unit means one of it. "" and "0" are unlimited.
+func ParseRateLimit(s string) (RateLimit, error) {
+	if s == "" || s == "0" {
+		return RateLimit{}, nil
+	}
+	n, window, ok := strings.Cut(s, "/")
+	requests, err := strconv.Atoi(n)
+	if !ok || err != nil || requests < 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: want requests/window, such as 10/h", s)
+	}
+	if window != "" && (window[0] < '0' || window[0] > '9') {
+		window = "1" + window
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: bad window %q", s, window)
+	}
+	return RateLimit{Requests: requests, Window: d}, nil
+}
+
+func (r RateLimit) String() string {
+	if r.Requests == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d/%s", r.Requests, r.Window)
+}
+
+// rateLimiter is a token bucket per client.
+type rateLimiter struct {
+	mu      sync.Mutex
+	limit   RateLimit
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxBuckets bounds the clients a rateLimiter tracks before it forgets
+// those whose buckets have refilled.
+const maxBuckets = 10000
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	return &rateLimiter{limit: limit, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a token from client's bucket. If there is none, it returns
+// how long until there will be.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	if l == nil || l.limit.Requests == 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	burst := float64(l.limit.Requests)
+	perSecond := burst / l.limit.Window.Seconds()
+	b, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.prune(now, burst, perSecond)
+		}
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration(math.Ceil((1 - b.tokens) / perSecond * float64(time.Second)))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops buckets that are full again. The caller holds l.mu.
+func (l *rateLimiter) prune(now time.Time, burst, perSecond float64) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*perSecond >= burst {
+			delete(l.buckets, client)
+		}
+	}
+}
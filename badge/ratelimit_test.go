@@ -0,0 +1,51 @@
+package badge
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	for s, want := range map[string]RateLimit{
+		"":        {},
+		"0":       {},
+		"10/h":    {Requests: 10, Window: time.Hour},
+		"100/24h": {Requests: 100, Window: 24 * time.Hour},
+		"5/30m":   {Requests: 5, Window: 30 * time.Minute},
+	} {
+		got, err := ParseRateLimit(s)
+		if err != nil || got != want {
+			t.Errorf("%q: got %v, %v; want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"10", "x/h", "10/", "10/-1h", "-1/h"} {
+		if _, err := ParseRateLimit(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(RateLimit{Requests: 2, Window: time.Minute})
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d denied within the burst", i)
+		}
+	}
+	ok, wait := l.allow("a", now)
+	if ok || wait != 30*time.Second {
+		t.Errorf("expected a 30s wait, got %v %s", ok, wait)
+	}
+	if ok, _ := l.allow("b", now); !ok {
+		t.Error("clients share a bucket")
+	}
+	if ok, _ := l.allow("a", now.Add(30*time.Second)); !ok {
+		t.Error("bucket did not refill")
+	}
+
+	var unlimited *rateLimiter
+	if ok, _ := unlimited.allow("a", now); !ok {
+		t.Error("nil limiter should allow everything")
+	}
+}
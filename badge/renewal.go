@@ -0,0 +1,98 @@
+package badge
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// --- Renewals and Expiry ---
+
+// renewalRetry is how long a renewal waits before it is queued again
+// when the previous run failed.
+const renewalRetry = 24 * time.Hour
+
+// StartRenewals checks every certificate each interval until ctx is
+// cancelled. Certificates past their expiry are marked "expired", and
+// those whose request set auto_renew are queued for a new run once they
+// are within renewBefore of expiry. The runs are picked up by the workers
+// started with StartRunner; a run that completes extends the certificate
+// by another 6 months.
+func (p *Portal) StartRenewals(ctx context.Context, interval, renewBefore time.Duration, onError func(error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := p.checkRenewals(time.Now(), renewBefore); err != nil && onError != nil {
+				onError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkRenewals expires and queues renewals for certificates as of now.
+func (p *Portal) checkRenewals(now time.Time, renewBefore time.Duration) error {
+	certs, _, err := p.store.List(0, 0)
+	if err != nil {
+		return fmt.Errorf("listing certificates: %w", err)
+	}
+	var errs []error
+	for _, cert := range certs {
+		if cert.RevokedAt != nil || cert.Total == 0 {
+			continue // revoked, or still waiting for its first run
+		}
+		if !now.Before(cert.ExpiresAt) && cert.Status != "expired" {
+			if err := p.expire(cert.ID); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if cert.Request != nil && cert.Request.AutoRenew && !now.Before(cert.ExpiresAt.Add(-renewBefore)) {
+			if err := p.renew(cert, now); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("renewals: %v", errs)
+	}
+	return nil
+}
+
+// expire marks a certificate expired and notifies its callback URL.
+func (p *Portal) expire(id string) error {
+	var previous string
+	var expired *Certificate
+	err := p.store.Update(id, func(c *Certificate) {
+		previous = c.Status
+		c.Status = "expired"
+		expired = c
+	})
+	if err != nil {
+		return fmt.Errorf("expiring certificate %s: %w", id, err)
+	}
+	p.notify(expired, previous)
+	return nil
+}
+
+// renew queues a new run for a certificate unless one is already queued or
+// running, or the last one was queued less than renewalRetry ago.
+func (p *Portal) renew(cert *Certificate, now time.Time) error {
+	if job, ok := p.jobs.Get(cert.ID); ok && (job.Status == JobQueued || job.Status == JobRunning) {
+		return nil
+	}
+	p.renewMu.Lock()
+	defer p.renewMu.Unlock()
+	if last, ok := p.renewals[cert.ID]; ok && now.Sub(last) < renewalRetry {
+		return nil
+	}
+	if _, err := p.jobs.Enqueue(cert.ID, *cert.Request); err != nil {
+		return fmt.Errorf("renewing certificate %s: %w", cert.ID, err)
+	}
+	p.renewals[cert.ID] = now
+	return nil
+}
@@ -0,0 +1,119 @@
+package badge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+func TestPortalExpiresCertificates(t *testing.T) {
+	srv, notifications := callbackServer(t)
+	p := NewPortalWithConfig(PortalConfig{Targets: &TargetPolicy{}}) // the callback server is on loopback
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T", CallbackURL: srv.URL, CallbackSecret: "s3cret"}, levelReport([2]int{10, 0}))
+	revoked := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "R"}, levelReport([2]int{10, 0}))
+	if _, err := p.store.Revoke(revoked.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.checkRenewals(cert.ExpiresAt.Add(-time.Minute), 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.store.Get(cert.ID); got.Status != "pass" {
+		t.Fatalf("expired early: %s", got.Status)
+	}
+
+	if err := p.checkRenewals(cert.ExpiresAt, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.store.Get(cert.ID); got.Status != "expired" {
+		t.Errorf("expected expired, got %s", got.Status)
+	}
+	if got, _ := p.store.Get(revoked.ID); got.Status != "pass" {
+		t.Errorf("revoked certificate changed: %s", got.Status)
+	}
+	if n := receive(t, notifications); n.PreviousStatus != "pass" || n.Status != "expired" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+}
+
+func TestPortalRenewsCertificates(t *testing.T) {
+	srv, notifications := callbackServer(t)
+	p := NewPortalWithConfig(PortalConfig{Targets: &TargetPolicy{}}) // the callback server is on loopback
+	req := CertificationRequest{ServerURL: "http://test:8080", Name: "T", Level: "1", AutoRenew: true, CallbackURL: srv.URL, CallbackSecret: "s3cret"}
+	cert := issue(t, p, req, levelReport([2]int{10, 0}, [2]int{5, 0}))
+	manual := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "M"}, levelReport([2]int{10, 0}))
+	renewBefore := 14 * 24 * time.Hour
+
+	// Outside the renewal window nothing is queued.
+	if err := p.checkRenewals(cert.ExpiresAt.Add(-renewBefore-time.Hour), renewBefore); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.jobs.Get(cert.ID); ok {
+		t.Fatal("renewal queued too early")
+	}
+
+	now := cert.ExpiresAt.Add(-time.Hour)
+	if err := p.checkRenewals(now, renewBefore); err != nil {
+		t.Fatal(err)
+	}
+	job, ok := p.jobs.Get(cert.ID)
+	if !ok || job.Status != JobQueued || job.Request.Level != "1" {
+		t.Fatalf("expected a queued renewal, got %+v", job)
+	}
+	if _, ok := p.jobs.Get(manual.ID); ok {
+		t.Error("certificate without auto_renew was renewed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p.StartRunner(ctx, func(ctx context.Context, r CertificationRequest) (lib.SuiteReport, error) {
+		return levelReport([2]int{10, 0}, [2]int{4, 1}), nil
+	}, 1)
+	waitForJob(t, p, cert.ID)
+
+	got, _ := p.store.Get(cert.ID)
+	if got.Status != "partial" || !got.ExpiresAt.After(cert.ExpiresAt) {
+		t.Errorf("renewal not recorded: status %s, expires %s", got.Status, got.ExpiresAt)
+	}
+	if n := receive(t, notifications); n.PreviousStatus != "pass" || n.Status != "partial" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+
+	// A renewal is not queued again within a day.
+	p.store.Update(cert.ID, func(c *Certificate) { c.ExpiresAt = cert.ExpiresAt })
+	p.checkRenewals(now.Add(time.Hour), renewBefore)
+	if job, _ := p.jobs.Get(cert.ID); job.Status != JobDone {
+		t.Errorf("renewal queued again: %+v", job)
+	}
+}
+
+func TestPortalHidesCallbackSecret(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	id := certify(t, mux, `{"server_url":"http://private-ojs:8080","name":"X","contact_email":"ops@acme.example","callback_url":"https://hooks.example/ojs","callback_secret":"s3cret"}`)
+
+	for _, path := range []string{"/api/certify/" + id, "/api/certificates/" + id, "/api/certificates"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "s3cret") {
+			t.Errorf("%s: %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	// The run's status is public; the request behind it is not.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/certify/"+id, nil))
+	for _, private := range []string{"ops@acme.example", "hooks.example", "private-ojs", `"request"`} {
+		if strings.Contains(rec.Body.String(), private) {
+			t.Errorf("run status shows %s: %s", private, rec.Body.String())
+		}
+	}
+	if cert, _ := p.store.Get(id); cert.Request == nil || cert.Request.CallbackSecret != "s3cret" {
+		t.Errorf("request not stored: %+v", cert.Request)
+	}
+}
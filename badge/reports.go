@@ -0,0 +1,202 @@
+package badge
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// --- Signed Report Submissions ---
+
+// Implementations that cannot expose a server to the portal run the suite
+// themselves, sign the report with ojs-conformance sign, and submit it to
+// POST /api/reports. The portal checks the signature, the report schema,
+// the suite version and that the report covers the suite, and issues a
+// certificate whose attestation is AttestationSelf rather than
+// AttestationPortal.
+
+var (
+	// ErrInvalidReport is returned for submissions that are malformed, do
+	// not verify, or whose report the portal does not certify.
+	ErrInvalidReport = errors.New("invalid report submission")
+	// ErrUntrustedReportKey is returned when the portal only accepts
+	// reports from configured keys and the report was signed by another.
+	ErrUntrustedReportKey = lib.ErrUntrustedKey
+)
+
+// ReportSubmission is the body of POST /api/reports: a signed report, as
+// submitted to the transparency log, and the implementation's details.
+// Name, Organization and Repository default to the report's backend name,
+// submitter organization and commit repository.
+type ReportSubmission struct {
+	lib.SignedReport
+
+	Name         string `json:"name,omitempty"`
+	Organization string `json:"organization,omitempty"`
+	Repository   string `json:"repository,omitempty"`
+}
+
+// HandleSubmitReport issues a self-attested certificate from a signed
+// report. Like POST /api/certify it requires an API key if the portal has
+// any and applies the rate limits. It returns 201 with the certificate.
+// POST /api/reports
+func (p *Portal) HandleSubmitReport(w http.ResponseWriter, r *http.Request) {
+	if _, status, reason := p.admit(w, r, bearerToken(r)); status != 0 {
+		writePortalError(w, status, reason)
+		return
+	}
+	if len(p.suite) == 0 {
+		writePortalError(w, http.StatusServiceUnavailable, "the portal has no test suite to check reports against")
+		return
+	}
+
+	var sub ReportSubmission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUploadSize)).Decode(&sub); err != nil {
+		writePortalError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	report, att, err := p.verifySubmission(sub)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrUntrustedReportKey) {
+			status = http.StatusForbidden
+		}
+		writePortalError(w, status, err.Error())
+		return
+	}
+
+	req := CertificationRequest{
+		ServerURL:    report.Target,
+		Name:         sub.Name,
+		Organization: sub.Organization,
+		Repository:   sub.Repository,
+	}
+	if req.Name == "" && report.Backend != nil {
+		req.Name = report.Backend.Name
+	}
+	if req.Organization == "" && report.Submitter != nil {
+		req.Organization = report.Submitter.Org
+	}
+	if req.Repository == "" {
+		req.Repository = report.Commit.Repo
+	}
+	if req.Name == "" {
+		writePortalError(w, http.StatusBadRequest, "name is required when the report names no backend")
+		return
+	}
+
+	cert, err := p.store.issue(req, report, att)
+	if err != nil {
+		writePortalError(w, http.StatusInternalServerError, fmt.Sprintf("storing certificate: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/certificates/"+cert.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cert)
+}
+
+// verifySubmission checks a submission's signature, then that its report
+// is a v1.1 report from the portal's suite version, with the commit,
+// environment and submitter that v1.1 adds, and that it covers the suite
+// (see checkCoverage). It returns the report and its attestation.
+func (p *Portal) verifySubmission(sub ReportSubmission) (lib.SuiteReport, Attestation, error) {
+	report, pub, err := lib.VerifySignedReport(sub.SignedReport, p.reportKeys)
+	if errors.Is(err, ErrUntrustedReportKey) {
+		return report, Attestation{}, err
+	}
+	if err != nil {
+		return report, Attestation{}, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+
+	switch {
+	case report.ReportSchemaVersion != lib.SchemaVersionV11:
+		err = fmt.Errorf("report_schema_version is %q, want %q", report.ReportSchemaVersion, lib.SchemaVersionV11)
+	case report.TestSuiteVersion != p.suiteVersion:
+		err = fmt.Errorf("test_suite_version is %q, but the portal certifies suite version %q", report.TestSuiteVersion, p.suiteVersion)
+	case report.Commit == nil || report.Commit.SHA == "":
+		err = errors.New("report has no commit")
+	case report.Environment == nil:
+		err = errors.New("report has no environment")
+	case report.Submitter == nil:
+		err = errors.New("report has no submitter")
+	case len(report.Tests) == 0:
+		err = errors.New("report lists no tests")
+	default:
+		err = p.checkCoverage(report)
+	}
+	if err != nil {
+		return report, Attestation{}, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+
+	digest, err := lib.ReportDigest(report)
+	if err != nil {
+		return report, Attestation{}, err
+	}
+	return report, Attestation{Kind: AttestationSelf, KeyID: lib.KeyID(pub), ReportDigest: digest, Commit: report.Commit}, nil
+}
+
+// checkCoverage checks a report's tests against the portal's suite: each
+// must be a suite test, at its level and category, with a status the
+// runners report, and every suite test up to the level the report reaches
+// must be listed; extension tests are optional, since they do not count
+// towards levels. A report therefore cannot reach a level by leaving out
+// the tests it fails, by moving them to a higher level or an extension, or
+// by giving them a status that is neither a pass nor a failure. A report
+// that reaches no level is refused, since there is nothing to certify.
+func (p *Portal) checkCoverage(report lib.SuiteReport) error {
+	suite := make(map[string]lib.TestCase, len(p.suite))
+	for _, tc := range p.suite {
+		suite[tc.TestID] = tc
+	}
+	listed := make(map[string]bool, len(report.Tests))
+	for _, t := range report.Tests {
+		tc, ok := suite[t.TestID]
+		switch {
+		case !ok:
+			return fmt.Errorf("test %s is not in suite version %s", t.TestID, p.suiteVersion)
+		case t.Level != tc.LevelInt || t.Category != tc.Category:
+			return fmt.Errorf("test %s is level %d, category %q in the suite, not level %d, category %q",
+				t.TestID, tc.LevelInt, tc.Category, t.Level, t.Category)
+		case !reportStatuses[t.Status]:
+			return fmt.Errorf("test %s has status %q, not pass, fail, error, skip, xfail or xpass", t.TestID, t.Status)
+		}
+		listed[t.TestID] = true
+	}
+
+	reached := summarize(report).conformantLevel
+	if reached < 0 {
+		return errors.New("report reaches no conformance level")
+	}
+	for _, tc := range p.suite {
+		extension := tc.LevelInt == 99 || strings.HasPrefix(tc.Category, "ext-")
+		if !extension && tc.LevelInt <= reached && !listed[tc.TestID] {
+			return fmt.Errorf("report reaches %s without test %s", levelName(reached), tc.TestID)
+		}
+	}
+	return nil
+}
+
+// reportStatuses are the test statuses a report can record.
+var reportStatuses = map[string]bool{
+	"pass": true, "fail": true, "error": true, "skip": true,
+	lib.StatusXFail: true, lib.StatusXPass: true,
+}
+
+// reportKeySet indexes trusted report keys by key ID. It returns nil, which
+// trusts every key, when keys is empty.
+func reportKeySet(keys []ed25519.PublicKey) map[string]bool {
+	if len(keys) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[lib.KeyID(k)] = true
+	}
+	return set
+}
@@ -0,0 +1,206 @@
+package badge
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+	"github.com/openjobspec/ojs-conformance/runner/httprunner"
+)
+
+// reportSuite is the suite attestedReport was run against.
+var reportSuite = []lib.TestCase{
+	{TestID: "L0-ENV-001", LevelInt: 0, Category: "envelope"},
+	{TestID: "L0-ENV-002", LevelInt: 0, Category: "envelope"},
+	{TestID: "L0-WEBHOOK-001", LevelInt: 0, Category: "ext-webhooks"},
+	{TestID: "L1-RETRY-001", LevelInt: 1, Category: "retry"},
+	{TestID: "L2-CRON-001", LevelInt: 2, Category: "cron"},
+}
+
+// attestedReport is a v1.1 report of failingReport's results, and one
+// more passing level 0 test.
+func attestedReport() lib.SuiteReport {
+	report := failingReport()
+	report.Tests = append(report.Tests, lib.TestOutcome{TestID: "L0-ENV-002", Level: 0, Category: "envelope", Status: "pass"})
+	report.Results.Total, report.Results.Passed = 3, 2
+	report.ReportSchemaVersion = lib.SchemaVersionV11
+	report.TestSuiteVersion = httprunner.SuiteVersion
+	report.Backend = &lib.BackendInfo{Name: "ojs-backend-test"}
+	report.Commit = &lib.CommitInfo{Repo: "https://github.com/acme/ojs-backend-test", SHA: strings.Repeat("a", 40)}
+	report.Environment = &lib.EnvironmentInfo{OS: "linux", Arch: "amd64"}
+	report.Submitter = &lib.SubmitterInfo{Org: "Acme"}
+	return report
+}
+
+func newReportKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := lib.MarshalVerifyKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, string(pubPEM)
+}
+
+// signedSubmission signs report with a detached signature.
+func signedSubmission(t *testing.T, report lib.SuiteReport, key ed25519.PrivateKey, pubPEM string) ReportSubmission {
+	t.Helper()
+	sig, err := lib.SignReport(report, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ReportSubmission{SignedReport: lib.SignedReport{Report: raw, Signature: &sig, PublicKey: pubPEM}}
+}
+
+func submitReport(t *testing.T, mux *http.ServeMux, apiKey string, sub ReportSubmission) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/reports", bytes.NewReader(body))
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSubmitReport(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{Suite: reportSuite})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	key, pubPEM := newReportKey(t)
+	report := attestedReport()
+
+	envelope, err := lib.SignReportEnvelope(report, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, sub := range map[string]ReportSubmission{
+		"detached": signedSubmission(t, report, key, pubPEM),
+		"envelope": {SignedReport: lib.SignedReport{Envelope: &envelope, PublicKey: pubPEM}},
+	} {
+		rec := submitReport(t, mux, "", sub)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d: %s", name, rec.Code, rec.Body)
+		}
+		var cert Certificate
+		if err := json.NewDecoder(rec.Body).Decode(&cert); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Header().Get("Location") != "/api/certificates/"+cert.ID {
+			t.Errorf("%s: unexpected Location %q", name, rec.Header().Get("Location"))
+		}
+		att := cert.Attestation
+		if att.Kind != AttestationSelf || att.KeyID != lib.KeyID(key.Public().(ed25519.PublicKey)) ||
+			att.ReportDigest == "" || att.Commit == nil || att.Commit.SHA != report.Commit.SHA {
+			t.Errorf("%s: unexpected attestation %+v", name, att)
+		}
+		if cert.Name != "ojs-backend-test" || cert.Organization != "Acme" || cert.Repository != report.Commit.Repo || cert.Level != "L0" {
+			t.Errorf("%s: unexpected certificate %+v", name, cert)
+		}
+		if _, body := getPage(t, mux, "/certificates/"+cert.ID); !strings.Contains(body, "self-attested") || !strings.Contains(body, att.KeyID) {
+			t.Errorf("%s: certificate page does not show the attestation", name)
+		}
+	}
+}
+
+func TestSubmitReportRejected(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{Suite: reportSuite})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	key, pubPEM := newReportKey(t)
+
+	tampered := signedSubmission(t, attestedReport(), key, pubPEM)
+	tampered.Report = bytes.Replace(tampered.Report, []byte(`"passed":2`), []byte(`"passed":3`), 1)
+
+	tests := []struct {
+		name   string
+		modify func(*lib.SuiteReport)
+		sub    *ReportSubmission
+		want   string
+	}{
+		{name: "tampered", sub: &tampered, want: "modified after signing"},
+		{name: "unsigned", sub: &ReportSubmission{SignedReport: lib.SignedReport{Report: tampered.Report, PublicKey: pubPEM}}, want: "need an envelope"},
+		{name: "no key", sub: &ReportSubmission{SignedReport: lib.SignedReport{Report: tampered.Report, Signature: tampered.Signature}}, want: "public_key"},
+		{name: "schema", modify: func(r *lib.SuiteReport) { r.ReportSchemaVersion = "1.0" }, want: "report_schema_version"},
+		{name: "suite", modify: func(r *lib.SuiteReport) { r.TestSuiteVersion = "0.9" }, want: "test_suite_version"},
+		{name: "commit", modify: func(r *lib.SuiteReport) { r.Commit = nil }, want: "no commit"},
+		{name: "environment", modify: func(r *lib.SuiteReport) { r.Environment = nil }, want: "no environment"},
+		{name: "submitter", modify: func(r *lib.SuiteReport) { r.Submitter = nil }, want: "no submitter"},
+		{name: "no tests", modify: func(r *lib.SuiteReport) { r.Tests = nil }, want: "lists no tests"},
+		{name: "unknown test", modify: func(r *lib.SuiteReport) {
+			r.Tests = append(r.Tests, lib.TestOutcome{TestID: "L0-EXTRA-001", Level: 0, Category: "envelope", Status: "pass"})
+		}, want: "L0-EXTRA-001 is not in suite version"},
+		// Moving a failing test up a level, or into an extension, would
+		// leave level 1 passing.
+		{name: "moved level", modify: func(r *lib.SuiteReport) { r.Tests[1].Level = 2 }, want: "L1-RETRY-001 is level 1"},
+		{name: "moved category", modify: func(r *lib.SuiteReport) { r.Tests[1].Category = "ext-retry" }, want: "L1-RETRY-001 is level 1"},
+		{name: "missing test", modify: func(r *lib.SuiteReport) { r.Tests = r.Tests[:1] }, want: "reaches L0 without test L0-ENV-002"},
+		{name: "unknown status", modify: func(r *lib.SuiteReport) { r.Tests[1].Status = "bogus" }, want: "not pass, fail, error, skip, xfail or xpass"},
+		// Skipped tests have no failures, but do not pass their level.
+		{name: "all skipped", modify: func(r *lib.SuiteReport) {
+			for i := range r.Tests {
+				r.Tests[i].Status = "skip"
+			}
+		}, want: "reaches no conformance level"},
+	}
+	for _, tt := range tests {
+		sub := tt.sub
+		if sub == nil {
+			report := attestedReport()
+			tt.modify(&report)
+			s := signedSubmission(t, report, key, pubPEM)
+			sub = &s
+		}
+		rec := submitReport(t, mux, "", *sub)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: expected 400 mentioning %q, got %d: %s", tt.name, tt.want, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestSubmitReportAccess(t *testing.T) {
+	trusted, trustedPEM := newReportKey(t)
+	other, otherPEM := newReportKey(t)
+	p := NewPortalWithConfig(PortalConfig{
+		APIKeys:    []string{"key-1"},
+		ReportKeys: []ed25519.PublicKey{trusted.Public().(ed25519.PublicKey)},
+		Suite:      reportSuite,
+	})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	report := attestedReport()
+
+	if rec := submitReport(t, mux, "", signedSubmission(t, report, trusted, trustedPEM)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without an API key: expected 401, got %d", rec.Code)
+	}
+	if rec := submitReport(t, mux, "key-1", signedSubmission(t, report, other, otherPEM)); rec.Code != http.StatusForbidden {
+		t.Errorf("untrusted key: expected 403, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := submitReport(t, mux, "key-1", signedSubmission(t, report, trusted, trustedPEM)); rec.Code != http.StatusCreated {
+		t.Errorf("trusted key: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Without a suite there is nothing to check reports against.
+	mux = http.NewServeMux()
+	NewPortal().RegisterRoutes(mux)
+	if rec := submitReport(t, mux, "", signedSubmission(t, report, trusted, trustedPEM)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("without a suite: expected 503, got %d: %s", rec.Code, rec.Body)
+	}
+}
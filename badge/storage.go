@@ -0,0 +1,355 @@
+package badge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --- Certificate Storage ---
+
+// ErrCertificateNotFound is returned for unknown certificate IDs.
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// Storage persists certificates for a CertificationStore. Implementations
+// must be safe for concurrent use and must not keep references to the
+// certificates passed in or handed out.
+type Storage interface {
+	// Put inserts or replaces a certificate.
+	Put(cert *Certificate) error
+	// Get returns ErrCertificateNotFound for unknown IDs.
+	Get(id string) (*Certificate, error)
+	// Delete removes a certificate; unknown IDs are not an error.
+	Delete(id string) error
+	// List returns up to limit certificates, newest first, starting at
+	// offset, and the total number stored. A limit <= 0 means no limit.
+	List(offset, limit int) ([]*Certificate, int, error)
+	Close() error
+}
+
+// OpenStorage opens the storage named by spec: "memory" (or empty),
+// "file:<path>" or "sqlite:<path>". SQLite needs a binary built with
+// -tags sqlite.
+func OpenStorage(spec string) (Storage, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "memory":
+		return NewMemoryStorage(), nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("storage %q: missing path", spec)
+		}
+		return OpenFileStorage(path)
+	case "sqlite":
+		if path == "" {
+			return nil, fmt.Errorf("storage %q: missing path", spec)
+		}
+		return openSQLite(path)
+	default:
+		return nil, fmt.Errorf("unknown storage %q (want memory, file:<path> or sqlite:<path>)", spec)
+	}
+}
+
+func cloneCertificate(c *Certificate) *Certificate {
+	cp := *c
+	if c.Extensions != nil {
+		cp.Extensions = make(map[string]ExtensionResult, len(c.Extensions))
+		for k, v := range c.Extensions {
+			cp.Extensions[k] = v
+		}
+	}
+	cp.Levels = slices.Clone(c.Levels)
+	cp.Failures = slices.Clone(c.Failures)
+	if c.RevokedAt != nil {
+		t := *c.RevokedAt
+		cp.RevokedAt = &t
+	}
+	if c.Request != nil {
+		req := *c.Request
+		cp.Request = &req
+	}
+	if c.Attestation.Commit != nil {
+		commit := *c.Attestation.Commit
+		cp.Attestation.Commit = &commit
+	}
+	return &cp
+}
+
+// MemoryStorage keeps certificates in memory. They are lost on restart.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	certs map[string]*Certificate // id -> cert
+}
+
+// NewMemoryStorage creates an empty in-memory storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{certs: make(map[string]*Certificate)}
+}
+
+// Put inserts or replaces a certificate.
+func (s *MemoryStorage) Put(cert *Certificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs[cert.ID] = cloneCertificate(cert)
+	return nil
+}
+
+// Get retrieves a certificate by ID.
+func (s *MemoryStorage) Get(id string) (*Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.certs[id]
+	if !ok {
+		return nil, ErrCertificateNotFound
+	}
+	return cloneCertificate(c), nil
+}
+
+// Delete removes a certificate.
+func (s *MemoryStorage) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.certs, id)
+	return nil
+}
+
+// List returns a page of certificates, newest first.
+func (s *MemoryStorage) List(offset, limit int) ([]*Certificate, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page, total := paginate(s.certs, offset, limit)
+	return page, total, nil
+}
+
+// Close is a no-op.
+func (s *MemoryStorage) Close() error { return nil }
+
+// paginate sorts certs by issue date (newest first, then by ID) and
+// returns copies of one page.
+func paginate(certs map[string]*Certificate, offset, limit int) ([]*Certificate, int) {
+	all := make([]*Certificate, 0, len(certs))
+	for _, c := range certs {
+		all = append(all, c)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].IssuedAt.Equal(all[j].IssuedAt) {
+			return all[i].IssuedAt.After(all[j].IssuedAt)
+		}
+		return all[i].ID < all[j].ID
+	})
+	offset = min(max(offset, 0), len(all))
+	end := len(all)
+	if limit > 0 {
+		end = min(offset+limit, len(all))
+	}
+	page := make([]*Certificate, 0, end-offset)
+	for _, c := range all[offset:end] {
+		page = append(page, cloneCertificate(c))
+	}
+	return page, len(all)
+}
+
+// certFileVersion is the current FileStorage format version.
+const certFileVersion = 1
+
+// certFile is the on-disk format of FileStorage.
+type certFile struct {
+	Version      int                  `json:"version"`
+	Certificates []*storedCertificate `json:"certificates"`
+}
+
+// storedCertificate adds the fields the API does not serve.
+type storedCertificate struct {
+	*Certificate
+	Request *CertificationRequest `json:"request,omitempty"`
+}
+
+// FileStorage keeps certificates in memory and rewrites a JSON file on
+// every change. The file is replaced atomically via a temporary file and
+// rename, so a crash leaves either the old or the new contents.
+//
+// A FileStorage assumes it is the file's only writer.
+type FileStorage struct {
+	mu    sync.RWMutex
+	path  string
+	certs map[string]*Certificate
+}
+
+// OpenFileStorage loads the file at path, creating it if it does not exist.
+//
+// A file without a version, such as a saved GET /api/certificates
+// response, is migrated: certificates issued before conformant_level was
+// recorded get it from their level string. The file is rewritten in the
+// current format.
+func OpenFileStorage(path string) (*FileStorage, error) {
+	s := &FileStorage{path: path, certs: make(map[string]*Certificate)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, s.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f certFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch f.Version {
+	case certFileVersion:
+	case 0:
+		if err := migrateLegacyCertificates(data, f.Certificates); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported version %d (newest known is %d)", path, f.Version, certFileVersion)
+	}
+	for _, sc := range f.Certificates {
+		c := sc.Certificate
+		if c == nil || c.ID == "" {
+			return nil, fmt.Errorf("%s: certificate without id", path)
+		}
+		c.Request = sc.Request
+		if c.Attestation.Kind == "" {
+			c.Attestation.Kind = AttestationPortal // saved before attestations were recorded
+		}
+		s.certs[c.ID] = c
+	}
+	if f.Version != certFileVersion {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// migrateLegacyCertificates fills in conformant_level, which certificates
+// from before it was added lack, from their "L0-LN" level string. Those
+// levels came from the overall pass ratio; they are kept as issued until
+// the certificate is renewed.
+func migrateLegacyCertificates(data []byte, certs []*storedCertificate) error {
+	var raw struct {
+		Certificates []map[string]json.RawMessage `json:"certificates"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for i, c := range certs {
+		if _, ok := raw.Certificates[i]["conformant_level"]; ok || c.Certificate == nil {
+			continue
+		}
+		c.ConformantLevel = parseLevelName(c.Level)
+	}
+	return nil
+}
+
+// parseLevelName is the inverse of levelName.
+func parseLevelName(level string) int {
+	if level == "L0" {
+		return 0
+	}
+	if rest, ok := strings.CutPrefix(level, "L0-L"); ok {
+		if n, err := strconv.Atoi(rest); err == nil {
+			return n
+		}
+	}
+	return -1
+}
+
+// Put inserts or replaces a certificate and rewrites the file.
+func (s *FileStorage) Put(cert *Certificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, existed := s.certs[cert.ID]
+	s.certs[cert.ID] = cloneCertificate(cert)
+	if err := s.save(); err != nil {
+		if existed {
+			s.certs[cert.ID] = prev
+		} else {
+			delete(s.certs, cert.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get retrieves a certificate by ID.
+func (s *FileStorage) Get(id string) (*Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c, ok := s.certs[id]
+	if !ok {
+		return nil, ErrCertificateNotFound
+	}
+	return cloneCertificate(c), nil
+}
+
+// Delete removes a certificate and rewrites the file.
+func (s *FileStorage) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.certs[id]
+	if !ok {
+		return nil
+	}
+	delete(s.certs, id)
+	if err := s.save(); err != nil {
+		s.certs[id] = prev
+		return err
+	}
+	return nil
+}
+
+// List returns a page of certificates, newest first.
+func (s *FileStorage) List(offset, limit int) ([]*Certificate, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page, total := paginate(s.certs, offset, limit)
+	return page, total, nil
+}
+
+// Close is a no-op; every change is already on disk.
+func (s *FileStorage) Close() error { return nil }
+
+// save atomically rewrites the file. The caller holds s.mu.
+func (s *FileStorage) save() error {
+	all, _ := paginate(s.certs, 0, 0)
+	f := certFile{Version: certFileVersion, Certificates: make([]*storedCertificate, len(all))}
+	for i, c := range all {
+		f.Certificates[i] = &storedCertificate{Certificate: c, Request: c.Request}
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,9 @@
+//go:build !sqlite
+
+package badge
+
+import "errors"
+
+func openSQLite(path string) (Storage, error) {
+	return nil, errors.New("sqlite storage requires a portal built with -tags sqlite")
+}
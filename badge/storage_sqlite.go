@@ -0,0 +1,273 @@
+//go:build sqlite
+
+package badge
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrations upgrade the schema one version at a time. The schema
+// version is kept in PRAGMA user_version; append new steps, never edit
+// existing ones.
+var sqliteMigrations = []string{
+	// 1: the certificate fields as of the first persistent portal.
+	`CREATE TABLE certificates (
+		id                TEXT PRIMARY KEY,
+		name              TEXT NOT NULL,
+		organization      TEXT NOT NULL DEFAULT '',
+		repository        TEXT NOT NULL DEFAULT '',
+		level             TEXT NOT NULL,
+		conformant_level  INTEGER NOT NULL,
+		status            TEXT NOT NULL,
+		passed            INTEGER NOT NULL,
+		failed            INTEGER NOT NULL,
+		total             INTEGER NOT NULL,
+		extensions        TEXT NOT NULL DEFAULT '',
+		badge_url         TEXT NOT NULL,
+		issued_at         TEXT NOT NULL,
+		expires_at        TEXT NOT NULL,
+		fingerprint       TEXT NOT NULL,
+		revoked_at        TEXT,
+		revocation_reason TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX certificates_issued_at ON certificates (issued_at DESC, id);`,
+	// 2: signed certificate tokens.
+	`ALTER TABLE certificates ADD COLUMN token TEXT NOT NULL DEFAULT '';`,
+	// 3: last-modified times for badges; empty for older certificates.
+	`ALTER TABLE certificates ADD COLUMN updated_at TEXT NOT NULL DEFAULT '';`,
+	// 4: the certification request, as JSON, for renewals and notifications.
+	`ALTER TABLE certificates ADD COLUMN request TEXT NOT NULL DEFAULT '';`,
+	// 5: per-level results, failing tests and the attestation, as JSON.
+	// Earlier certificates all come from portal runs.
+	`ALTER TABLE certificates ADD COLUMN levels TEXT NOT NULL DEFAULT '';
+	ALTER TABLE certificates ADD COLUMN failures TEXT NOT NULL DEFAULT '';
+	ALTER TABLE certificates ADD COLUMN attestation TEXT NOT NULL DEFAULT '{"kind":"portal-verified"}';`,
+	// 6: why the last certification run failed.
+	`ALTER TABLE certificates ADD COLUMN run_error TEXT NOT NULL DEFAULT '';`,
+}
+
+// sqliteTime is fixed-width so that timestamps sort as text.
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+const sqliteColumns = `id, name, organization, repository, level, conformant_level, status,
+	passed, failed, total, extensions, badge_url, issued_at, expires_at, fingerprint,
+	revoked_at, revocation_reason, token, updated_at, request, levels, failures, attestation, run_error`
+
+// SQLiteStorage keeps certificates in a SQLite database. It is only
+// available in binaries built with -tags sqlite, which requires cgo.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStorage opens or creates the database at path and migrates it
+// to the current schema.
+func OpenSQLiteStorage(path string) (*SQLiteStorage, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &SQLiteStorage{db: db}, nil
+}
+
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("schema version %d is newer than this portal (%d)", version, len(sqliteMigrations))
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openSQLite(path string) (Storage, error) {
+	return OpenSQLiteStorage(path)
+}
+
+// Put inserts or replaces a certificate.
+func (s *SQLiteStorage) Put(c *Certificate) error {
+	var exts string
+	if c.Extensions != nil {
+		data, err := json.Marshal(c.Extensions)
+		if err != nil {
+			return err
+		}
+		exts = string(data)
+	}
+	var revokedAt any
+	if c.RevokedAt != nil {
+		revokedAt = c.RevokedAt.UTC().Format(sqliteTime)
+	}
+	var request string
+	if c.Request != nil {
+		data, err := json.Marshal(c.Request)
+		if err != nil {
+			return err
+		}
+		request = string(data)
+	}
+	var levels, failures string
+	if len(c.Levels) > 0 {
+		data, err := json.Marshal(c.Levels)
+		if err != nil {
+			return err
+		}
+		levels = string(data)
+	}
+	if len(c.Failures) > 0 {
+		data, err := json.Marshal(c.Failures)
+		if err != nil {
+			return err
+		}
+		failures = string(data)
+	}
+	attestation, err := json.Marshal(c.Attestation)
+	if err != nil {
+		return err
+	}
+	var updatedAt string
+	if !c.UpdatedAt.IsZero() {
+		updatedAt = c.UpdatedAt.UTC().Format(sqliteTime)
+	}
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO certificates (`+sqliteColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.Name, c.Organization, c.Repository, c.Level, c.ConformantLevel, c.Status,
+		c.Passed, c.Failed, c.Total, exts, c.BadgeURL,
+		c.IssuedAt.UTC().Format(sqliteTime), c.ExpiresAt.UTC().Format(sqliteTime),
+		c.Fingerprint, revokedAt, c.RevocationReason, c.Token, updatedAt, request,
+		levels, failures, string(attestation), c.RunError)
+	return err
+}
+
+// Get retrieves a certificate by ID.
+func (s *SQLiteStorage) Get(id string) (*Certificate, error) {
+	c, err := scanCertificate(s.db.QueryRow(`SELECT `+sqliteColumns+` FROM certificates WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCertificateNotFound
+	}
+	return c, err
+}
+
+// Delete removes a certificate.
+func (s *SQLiteStorage) Delete(id string) error {
+	_, err := s.db.Exec(`DELETE FROM certificates WHERE id = ?`, id)
+	return err
+}
+
+// List returns a page of certificates, newest first.
+func (s *SQLiteStorage) List(offset, limit int) ([]*Certificate, int, error) {
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM certificates`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+	rows, err := s.db.Query(`SELECT `+sqliteColumns+` FROM certificates
+		ORDER BY issued_at DESC, id LIMIT ? OFFSET ?`, limit, max(offset, 0))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	certs := []*Certificate{}
+	for rows.Next() {
+		c, err := scanCertificate(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		certs = append(certs, c)
+	}
+	return certs, total, rows.Err()
+}
+
+// Close closes the database.
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+func scanCertificate(row interface{ Scan(...any) error }) (*Certificate, error) {
+	var (
+		c                              Certificate
+		exts, request                  string
+		levels, failures, attestation  string
+		issuedAt, expiresAt, updatedAt string
+		revokedAt                      sql.NullString
+	)
+	err := row.Scan(&c.ID, &c.Name, &c.Organization, &c.Repository, &c.Level, &c.ConformantLevel, &c.Status,
+		&c.Passed, &c.Failed, &c.Total, &exts, &c.BadgeURL, &issuedAt, &expiresAt, &c.Fingerprint,
+		&revokedAt, &c.RevocationReason, &c.Token, &updatedAt, &request,
+		&levels, &failures, &attestation, &c.RunError)
+	if err != nil {
+		return nil, err
+	}
+	if exts != "" {
+		if err := json.Unmarshal([]byte(exts), &c.Extensions); err != nil {
+			return nil, fmt.Errorf("certificate %s: extensions: %w", c.ID, err)
+		}
+	}
+	if c.IssuedAt, err = time.Parse(sqliteTime, issuedAt); err != nil {
+		return nil, fmt.Errorf("certificate %s: %w", c.ID, err)
+	}
+	if c.ExpiresAt, err = time.Parse(sqliteTime, expiresAt); err != nil {
+		return nil, fmt.Errorf("certificate %s: %w", c.ID, err)
+	}
+	if request != "" {
+		if err := json.Unmarshal([]byte(request), &c.Request); err != nil {
+			return nil, fmt.Errorf("certificate %s: request: %w", c.ID, err)
+		}
+	}
+	if levels != "" {
+		if err := json.Unmarshal([]byte(levels), &c.Levels); err != nil {
+			return nil, fmt.Errorf("certificate %s: levels: %w", c.ID, err)
+		}
+	}
+	if failures != "" {
+		if err := json.Unmarshal([]byte(failures), &c.Failures); err != nil {
+			return nil, fmt.Errorf("certificate %s: failures: %w", c.ID, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(attestation), &c.Attestation); err != nil {
+		return nil, fmt.Errorf("certificate %s: attestation: %w", c.ID, err)
+	}
+	if updatedAt != "" {
+		if c.UpdatedAt, err = time.Parse(sqliteTime, updatedAt); err != nil {
+			return nil, fmt.Errorf("certificate %s: %w", c.ID, err)
+		}
+	}
+	if revokedAt.Valid {
+		t, err := time.Parse(sqliteTime, revokedAt.String)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %s: %w", c.ID, err)
+		}
+		c.RevokedAt = &t
+	}
+	return &c, nil
+}
@@ -0,0 +1,73 @@
+//go:build sqlite
+
+package badge
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portal.db")
+	s, err := OpenSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+	s.Close()
+
+	// Reopening skips the applied migrations and keeps the data.
+	s, err = OpenSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, total, _ := s.List(0, 0); total != 4 {
+		t.Errorf("expected 4 certificates after reopening, got %d", total)
+	}
+	if _, err := s.db.Exec(`PRAGMA user_version = 99`); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := OpenSQLiteStorage(path); err == nil {
+		t.Error("expected a newer schema version to be rejected")
+	}
+}
+
+func TestSQLiteStorageMigratesSchema(t *testing.T) {
+	// A database at schema version 1, before certificates carried tokens
+	// and update times.
+	path := filepath.Join(t.TempDir(), "portal.db")
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(sqliteMigrations[0] + `PRAGMA user_version = 1;`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO certificates (id, name, level, conformant_level, status, passed, failed, total, badge_url, issued_at, expires_at, fingerprint)
+		VALUES ('cert_old', 'Old', 'L0', 0, 'pass', 10, 0, 10, '/badge/L0.svg', '2026-05-01T00:00:00.000000000Z', '2026-10-28T00:00:00.000000000Z', 'ff')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := OpenSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := s.Get("cert_old")
+	if err != nil || c.Name != "Old" || c.Token != "" || !c.UpdatedAt.IsZero() || c.Levels != nil || c.Attestation.Kind != AttestationPortal {
+		t.Fatalf("unexpected migrated certificate: %+v, %v", c, err)
+	}
+	c.Token = "a.b.c"
+	if err := s.Put(c); err != nil {
+		t.Fatal(err)
+	}
+	var version int
+	s.db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	if version != len(sqliteMigrations) {
+		t.Errorf("schema version %d, want %d", version, len(sqliteMigrations))
+	}
+}
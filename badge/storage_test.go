@@ -0,0 +1,194 @@
+package badge
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+func testCertificate(i int) *Certificate {
+	issued := time.Date(2026, 10, 1, 12, 0, i, 0, time.UTC)
+	return &Certificate{
+		ID:              fmt.Sprintf("cert_%016d", i),
+		Name:            fmt.Sprintf("Backend%d", i),
+		Level:           "L0-L2",
+		ConformantLevel: 2,
+		Status:          "partial",
+		Passed:          90,
+		Failed:          10,
+		Total:           100,
+		Extensions:      map[string]ExtensionResult{"webhooks": {Passed: 3, Total: 3}},
+		Levels:          []LevelResult{{Level: 0, Passed: 40, Total: 40}, {Level: 1, Passed: 30, Total: 30}, {Level: 2, Passed: 20, Total: 20}, {Level: 3, Failed: 10, Total: 10}},
+		Failures:        []FailedTest{{TestID: "L3-CRON-001", Name: "cron fires", Level: 3, Category: "cron", SpecRef: "ojs-core#section-9", Status: "fail", Message: "timed out"}},
+		IssuedAt:        issued,
+		UpdatedAt:       issued.Add(time.Minute),
+		ExpiresAt:       issued.Add(180 * 24 * time.Hour),
+		Fingerprint:     strings.Repeat("ab", 32),
+		Token:           fmt.Sprintf("header.payload%d.signature", i),
+		Attestation:     Attestation{Kind: AttestationSelf, KeyID: "ed25519:0123456789abcdef", Commit: &lib.CommitInfo{SHA: strings.Repeat("c", 40)}},
+		Request:         &CertificationRequest{ServerURL: "http://backend:8080", Name: fmt.Sprintf("Backend%d", i), CallbackSecret: "s3cret", AutoRenew: true},
+		RunError:        "renewal run: connection refused",
+	}
+}
+
+// testStorage checks the behaviour every Storage implementation shares.
+func testStorage(t *testing.T, s Storage) {
+	t.Helper()
+	for i := range 5 {
+		if err := s.Put(testCertificate(i)); err != nil {
+			t.Fatalf("put %d: %v", i, err)
+		}
+	}
+
+	got, err := s.Get("cert_0000000000000003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testCertificate(3)
+	if got.Name != want.Name || !got.IssuedAt.Equal(want.IssuedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) || got.Extensions["webhooks"].Total != 3 || got.ConformantLevel != 2 || got.Token != want.Token || got.RunError != want.RunError || got.Request == nil || *got.Request != *want.Request {
+		t.Errorf("unexpected certificate: %+v", got)
+	}
+	if !slices.Equal(got.Levels, want.Levels) || !slices.Equal(got.Failures, want.Failures) || got.Attestation.KeyID != want.Attestation.KeyID || *got.Attestation.Commit != *want.Attestation.Commit {
+		t.Errorf("results not stored: levels %+v, failures %+v, attestation %+v", got.Levels, got.Failures, got.Attestation)
+	}
+	if _, err := s.Get("cert_missing"); !errors.Is(err, ErrCertificateNotFound) {
+		t.Errorf("expected ErrCertificateNotFound, got %v", err)
+	}
+
+	// Changes to returned certificates do not leak into storage.
+	got.Name = "changed"
+	got.Extensions["webhooks"] = ExtensionResult{}
+	got.Failures[0].Message = "changed"
+	if again, _ := s.Get(got.ID); again.Name != want.Name || again.Extensions["webhooks"].Total != 3 || again.Failures[0].Message != "timed out" {
+		t.Errorf("storage shares certificates with callers: %+v", again)
+	}
+
+	revoked := testCertificate(3)
+	now := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	revoked.RevokedAt, revoked.RevocationReason = &now, "key compromise"
+	if err := s.Put(revoked); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get(revoked.ID); got.RevokedAt == nil || !got.RevokedAt.Equal(now) || got.RevocationReason != "key compromise" {
+		t.Errorf("revocation not stored: %+v", got)
+	}
+
+	page, total, err := s.List(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || len(page) != 2 || page[0].ID != "cert_0000000000000003" || page[1].ID != "cert_0000000000000002" {
+		t.Errorf("unexpected page (total %d): %v", total, certIDs(page))
+	}
+	if page, _, _ := s.List(4, 10); len(page) != 1 || page[0].ID != "cert_0000000000000000" {
+		t.Errorf("unexpected last page: %v", certIDs(page))
+	}
+	if page, _, _ := s.List(10, 10); len(page) != 0 {
+		t.Errorf("expected empty page past the end, got %v", certIDs(page))
+	}
+
+	if err := s.Delete("cert_0000000000000004"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("cert_missing"); err != nil {
+		t.Errorf("deleting an unknown certificate: %v", err)
+	}
+	if all, total, _ := s.List(0, 0); total != 4 || len(all) != 4 {
+		t.Errorf("expected 4 certificates after delete, got %d", total)
+	}
+}
+
+func certIDs(certs []*Certificate) []string {
+	ids := make([]string, len(certs))
+	for i, c := range certs {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage())
+}
+
+func TestFileStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "certs.json")
+	s, err := OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+	s.Close()
+
+	// Reopening sees the same certificates.
+	s, err = OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := s.List(0, 0); total != 4 {
+		t.Errorf("expected 4 certificates after reopening, got %d", total)
+	}
+	if c, err := s.Get("cert_0000000000000003"); err != nil || c.RevokedAt == nil {
+		t.Errorf("revocation lost on reopen: %+v, %v", c, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*.tmp")); len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version":99,"certificates":[]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStorage(path); err == nil {
+		t.Error("expected a newer file version to be rejected")
+	}
+}
+
+func TestFileStorageMigratesCertificateList(t *testing.T) {
+	// A GET /api/certificates response saved before certificates were
+	// persisted or recorded conformant_level.
+	path := filepath.Join(t.TempDir(), "certs.json")
+	legacy := `{"certificates":[
+		{"id":"cert_a","name":"A","level":"L0-L3","status":"partial","issued_at":"2026-05-01T00:00:00Z","expires_at":"2026-10-28T00:00:00Z"},
+		{"id":"cert_b","name":"B","level":"L0","status":"fail","issued_at":"2026-05-02T00:00:00Z","expires_at":"2026-10-29T00:00:00Z"}
+	],"count":2}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := s.Get("cert_a")
+	b, _ := s.Get("cert_b")
+	if a == nil || b == nil || a.ConformantLevel != 3 || b.ConformantLevel != 0 {
+		t.Fatalf("unexpected migrated certificates: %+v, %+v", a, b)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("file not rewritten in the current format:\n%s", data)
+	}
+}
+
+func TestOpenStorage(t *testing.T) {
+	dir := t.TempDir()
+	for _, spec := range []string{"", "memory", "file:" + filepath.Join(dir, "certs.json")} {
+		s, err := OpenStorage(spec)
+		if err != nil {
+			t.Errorf("%q: %v", spec, err)
+			continue
+		}
+		s.Close()
+	}
+	for _, spec := range []string{"file:", "postgres:x", "sqlite:"} {
+		if _, err := OpenStorage(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
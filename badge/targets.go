@@ -0,0 +1,172 @@
+package badge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// --- Target Networks ---
+
+// The portal connects to addresses its callers choose: certification runs
+// go to server_url and notifications to callback_url. A TargetPolicy keeps
+// those connections away from the portal's own network. Literal addresses
+// are checked when a request arrives; host names are checked on every
+// connection, after resolution, so DNS answers cannot point a run at a
+// denied address.
+
+// ErrTargetDenied is returned for addresses a TargetPolicy does not allow.
+var ErrTargetDenied = errors.New("target address not allowed")
+
+// DefaultDeniedNetworks are the non-public networks: loopback, private,
+// link-local (including cloud metadata endpoints), shared (CGNAT),
+// benchmarking, multicast and reserved ranges, and local-use NAT64.
+// Check also applies them to the IPv4 address embedded in well-known
+// prefix NAT64 (64:ff9b::/96) and 6to4 (2002::/16) addresses.
+var DefaultDeniedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/3"), // multicast and reserved, up to 255.255.255.255
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// TargetPolicy decides which addresses the portal may connect to. An
+// address in Allow is allowed; otherwise one in Deny is refused. The zero
+// policy allows everything.
+type TargetPolicy struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// DefaultTargetPolicy denies DefaultDeniedNetworks.
+func DefaultTargetPolicy() *TargetPolicy {
+	return &TargetPolicy{Deny: append([]netip.Prefix(nil), DefaultDeniedNetworks...)}
+}
+
+// ParseNetworks parses a comma-separated list of CIDR prefixes and
+// addresses, such as "10.1.0.0/16,192.0.2.7".
+func ParseNetworks(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("network %q: %w", s, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("network %q: %w", s, err)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// Check returns ErrTargetDenied if addr may not be connected to. A NAT64
+// or 6to4 address is also checked as the IPv4 address it reaches.
+func (tp *TargetPolicy) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if err := tp.check(addr); err != nil || !addr.Is6() {
+		return err
+	}
+	if v4, ok := embeddedIPv4(addr); ok {
+		if err := tp.check(v4); err != nil {
+			return fmt.Errorf("%w (via %s)", err, addr)
+		}
+	}
+	return nil
+}
+
+func (tp *TargetPolicy) check(addr netip.Addr) error {
+	for _, p := range tp.Allow {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	for _, p := range tp.Deny {
+		if p.Contains(addr) {
+			return fmt.Errorf("%w: %s is in %s", ErrTargetDenied, addr, p)
+		}
+	}
+	return nil
+}
+
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFour   = netip.MustParsePrefix("2002::/16")
+)
+
+// embeddedIPv4 returns the IPv4 address a well-known prefix NAT64 address
+// (RFC 6052) or a 6to4 address (RFC 3056) carries.
+func embeddedIPv4(addr netip.Addr) (netip.Addr, bool) {
+	b := addr.As16()
+	switch {
+	case nat64Prefix.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[12:16])), true
+	case sixToFour.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[2:6])), true
+	}
+	return netip.Addr{}, false
+}
+
+// CheckURL checks that raw is an http or https URL and, if its host is an
+// address or localhost, that the address is allowed. Other host names are
+// checked when they are connected to; see Client.
+func (tp *TargetPolicy) CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", raw)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		host = "127.0.0.1"
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return tp.Check(addr)
+	}
+	return nil
+}
+
+// Client returns an HTTP client whose connections, including redirects,
+// may only reach allowed addresses. It ignores proxy settings, which would
+// hide the final address.
+func (tp *TargetPolicy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrTargetDenied, address)
+			}
+			return tp.Check(ap.Addr())
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
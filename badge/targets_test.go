@@ -0,0 +1,102 @@
+package badge
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTargetPolicyCheck(t *testing.T) {
+	tp := DefaultTargetPolicy()
+	for addr, denied := range map[string]bool{
+		"127.0.0.1":          true,
+		"10.1.2.3":           true,
+		"172.31.0.1":         true,
+		"192.168.1.1":        true,
+		"169.254.169.254":    true, // cloud metadata
+		"100.64.0.1":         true,
+		"0.0.0.0":            true,
+		"255.255.255.255":    true,
+		"::1":                true,
+		"::ffff:10.0.0.1":    true, // IPv4-mapped
+		"fd00::1":            true,
+		"fe80::1":            true,
+		"64:ff9b::a9fe:a9fe": true, // NAT64 of 169.254.169.254
+		"64:ff9b::7f00:1":    true, // NAT64 of 127.0.0.1
+		"64:ff9b:1::a00:1":   true, // local-use NAT64
+		"2002:a00:1::1":      true, // 6to4 of 10.0.0.1
+		"2002:c0a8:101::":    true, // 6to4 of 192.168.1.1
+		"8.8.8.8":            false,
+		"2001:4860::8888":    false,
+		"64:ff9b::808:808":   false, // NAT64 of 8.8.8.8
+		"2002:808:808::1":    false, // 6to4 of 8.8.8.8
+	} {
+		err := tp.Check(netip.MustParseAddr(addr))
+		if got := errors.Is(err, ErrTargetDenied); got != denied {
+			t.Errorf("%s: denied %v, want %v (%v)", addr, got, denied, err)
+		}
+	}
+
+	allow, err := ParseNetworks("10.1.0.0/16, 192.168.1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp.Allow = allow
+	if err := tp.Check(netip.MustParseAddr("10.1.2.3")); err != nil {
+		t.Errorf("allowed network denied: %v", err)
+	}
+	if err := tp.Check(netip.MustParseAddr("192.168.1.6")); err == nil {
+		t.Error("expected 192.168.1.6 to stay denied")
+	}
+	if err := tp.Check(netip.MustParseAddr("64:ff9b::a01:203")); err != nil {
+		t.Errorf("NAT64 address of an allowed network denied: %v", err)
+	}
+	if _, err := ParseNetworks("10.0.0.0/33"); err == nil {
+		t.Error("expected invalid prefix to be rejected")
+	}
+}
+
+func TestTargetPolicyCheckURL(t *testing.T) {
+	tp := DefaultTargetPolicy()
+	for raw, wantDenied := range map[string]bool{
+		"http://127.0.0.1:8080":       true,
+		"http://[::1]/":               true,
+		"http://localhost:8080":       true,
+		"http://api.localhost.":       true,
+		"https://ojs.example.com":     false, // checked when connecting
+		"http://93.184.215.14:8080/x": false,
+	} {
+		if got := errors.Is(tp.CheckURL(raw), ErrTargetDenied); got != wantDenied {
+			t.Errorf("%s: denied %v, want %v", raw, got, wantDenied)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com", "example.com", "http://"} {
+		if err := tp.CheckURL(raw); err == nil || errors.Is(err, ErrTargetDenied) {
+			t.Errorf("%s: expected an invalid URL error, got %v", raw, err)
+		}
+	}
+}
+
+func TestTargetPolicyClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// Names are checked after resolution.
+	url := "http://localhost:" + strconv.Itoa(srv.Listener.Addr().(*net.TCPAddr).Port)
+	_, err := DefaultTargetPolicy().Client(time.Second).Get(url)
+	if !errors.Is(err, ErrTargetDenied) {
+		t.Errorf("expected loopback connection to be denied, got %v", err)
+	}
+
+	allowed := &TargetPolicy{Allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}, Deny: DefaultDeniedNetworks}
+	resp, err := allowed.Client(time.Second).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,216 @@
+package badge
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// --- Signed Certificates ---
+
+// Certificates are issued as compact JWS tokens (RFC 7515) signed with
+// Ed25519 ("EdDSA", RFC 8037). The portal publishes its public keys as a
+// JWKS, so a token can be checked without contacting the portal. Offline
+// verification cannot see revocations; GET /api/verify can.
+
+// CertificateTokenType is the JWS "typ" header of certificate tokens.
+const CertificateTokenType = "ojs-cert+jwt"
+
+var (
+	// ErrInvalidToken is returned for malformed tokens, unknown keys and
+	// bad signatures.
+	ErrInvalidToken = errors.New("invalid certificate token")
+	// ErrTokenExpired is returned for tokens past their expiry.
+	ErrTokenExpired = errors.New("certificate token expired")
+)
+
+// CertificateClaims are the signed contents of a certificate token.
+type CertificateClaims struct {
+	Issuer          string                     `json:"iss,omitempty"`
+	Subject         string                     `json:"sub"` // certificate ID
+	IssuedAt        int64                      `json:"iat"`
+	ExpiresAt       int64                      `json:"exp"`
+	Name            string                     `json:"name"`
+	Organization    string                     `json:"organization,omitempty"`
+	Repository      string                     `json:"repository,omitempty"`
+	Level           string                     `json:"level"`
+	ConformantLevel int                        `json:"conformant_level"`
+	Status          string                     `json:"status"`
+	Passed          int                        `json:"passed"`
+	Failed          int                        `json:"failed"`
+	Total           int                        `json:"total"`
+	Extensions      map[string]ExtensionResult `json:"extensions,omitempty"`
+	Attestation     string                     `json:"attestation"` // Attestation.Kind
+}
+
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// JWK is an Ed25519 public key in JSON Web Key form (RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"` // "OKP"
+	Crv string `json:"crv"` // "Ed25519"
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set, as served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK converts a public key. Its key ID is lib.KeyID(pub), as for
+// report signatures.
+func NewJWK(pub ed25519.PublicKey) JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(pub),
+		Kid: lib.KeyID(pub),
+		Use: "sig",
+		Alg: "EdDSA",
+	}
+}
+
+// PublicKey returns the Ed25519 key, checking that it matches its key ID.
+func (k JWK) PublicKey() (ed25519.PublicKey, error) {
+	if k.Kty != "OKP" || k.Crv != "Ed25519" {
+		return nil, fmt.Errorf("key %s: unsupported key type %s/%s", k.Kid, k.Kty, k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("key %s: malformed x", k.Kid)
+	}
+	pub := ed25519.PublicKey(x)
+	if k.Kid != lib.KeyID(pub) {
+		return nil, fmt.Errorf("key %s: kid does not match the key (%s)", k.Kid, lib.KeyID(pub))
+	}
+	return pub, nil
+}
+
+// Key returns the public key with the given ID.
+func (s JWKS) Key(kid string) (ed25519.PublicKey, error) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k.PublicKey()
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown key %s", ErrInvalidToken, kid)
+}
+
+// CertificateSigner issues certificate tokens.
+type CertificateSigner struct {
+	key    ed25519.PrivateKey
+	kid    string
+	issuer string
+	now    func() time.Time
+}
+
+// NewCertificateSigner creates a signer. issuer, typically the portal's
+// public URL, is recorded in every token's "iss" claim.
+func NewCertificateSigner(key ed25519.PrivateKey, issuer string) *CertificateSigner {
+	return &CertificateSigner{
+		key:    key,
+		kid:    lib.KeyID(key.Public().(ed25519.PublicKey)),
+		issuer: issuer,
+		now:    time.Now,
+	}
+}
+
+// JWKS returns the signer's public key set.
+func (s *CertificateSigner) JWKS() JWKS {
+	return JWKS{Keys: []JWK{NewJWK(s.key.Public().(ed25519.PublicKey))}}
+}
+
+// Sign returns a compact JWS over the certificate's claims.
+func (s *CertificateSigner) Sign(cert *Certificate) (string, error) {
+	header, err := json.Marshal(jwsHeader{Alg: "EdDSA", Kid: s.kid, Typ: CertificateTokenType})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(CertificateClaims{
+		Issuer:          s.issuer,
+		Subject:         cert.ID,
+		IssuedAt:        s.now().Unix(),
+		ExpiresAt:       cert.ExpiresAt.Unix(),
+		Name:            cert.Name,
+		Organization:    cert.Organization,
+		Repository:      cert.Repository,
+		Level:           cert.Level,
+		ConformantLevel: cert.ConformantLevel,
+		Status:          cert.Status,
+		Passed:          cert.Passed,
+		Failed:          cert.Failed,
+		Total:           cert.Total,
+		Extensions:      cert.Extensions,
+		Attestation:     cert.Attestation.Kind,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := b64(header) + "." + b64(claims)
+	sig := ed25519.Sign(s.key, []byte(signingInput))
+	return signingInput + "." + b64(sig), nil
+}
+
+// VerifyCertificateToken checks a token's signature against keys and its
+// expiry at now, and returns its claims.
+func VerifyCertificateToken(token string, keys JWKS, now time.Time) (*CertificateClaims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a compact JWS", ErrInvalidToken)
+	}
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	if header.Alg != "EdDSA" {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+	if header.Typ != CertificateTokenType {
+		return nil, fmt.Errorf("%w: typ %q is not %s", ErrInvalidToken, header.Typ, CertificateTokenType)
+	}
+	pub, err := keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	if !ed25519.Verify(pub, []byte(parts[0]+"."+parts[1]), sig) {
+		return nil, fmt.Errorf("%w: signature does not match key %s", ErrInvalidToken, header.Kid)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+	var claims CertificateClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if exp := time.Unix(claims.ExpiresAt, 0); !now.Before(exp) {
+		return &claims, fmt.Errorf("%w: %s expired at %s", ErrTokenExpired, claims.Subject, exp.UTC().Format(time.RFC3339))
+	}
+	return &claims, nil
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
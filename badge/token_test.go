@@ -0,0 +1,151 @@
+package badge
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T) (*CertificateSigner, ed25519.PrivateKey) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCertificateSigner(key, "https://portal.example"), key
+}
+
+func TestCertificateToken(t *testing.T) {
+	signer, _ := newTestSigner(t)
+	cert := testCertificate(1)
+	token, err := signer.Sign(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := cert.IssuedAt.Add(time.Hour)
+
+	claims, err := VerifyCertificateToken(token, signer.JWKS(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != cert.ID || claims.Issuer != "https://portal.example" || claims.Level != "L0-L2" ||
+		claims.ConformantLevel != 2 || claims.Extensions["webhooks"].Passed != 3 || claims.ExpiresAt != cert.ExpiresAt.Unix() {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	// The JWKS survives a round trip through JSON, as fetched by verifiers.
+	data, _ := json.Marshal(signer.JWKS())
+	var keys JWKS
+	if err := json.Unmarshal(data, &keys); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyCertificateToken(token, keys, now); err != nil {
+		t.Errorf("verifying with decoded JWKS: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	forged := *cert
+	forged.Level, forged.ConformantLevel = "L0-L4", 4
+	forgedToken, _ := signer.Sign(&forged)
+	other, _ := newTestSigner(t)
+
+	for name, tt := range map[string]struct {
+		token string
+		keys  JWKS
+		want  error
+	}{
+		"swapped claims": {parts[0] + "." + strings.Split(forgedToken, ".")[1] + "." + parts[2], signer.JWKS(), ErrInvalidToken},
+		"other key":      {token, other.JWKS(), ErrInvalidToken},
+		"truncated":      {parts[0] + "." + parts[1], signer.JWKS(), ErrInvalidToken},
+		"alg none":       {b64([]byte(`{"alg":"none","kid":"x","typ":"ojs-cert+jwt"}`)) + "." + parts[1] + ".", signer.JWKS(), ErrInvalidToken},
+	} {
+		if _, err := VerifyCertificateToken(tt.token, tt.keys, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tt.want)
+		}
+	}
+
+	if _, err := VerifyCertificateToken(token, signer.JWKS(), cert.ExpiresAt); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expected expired token, got %v", err)
+	}
+}
+
+func TestJWKRejectsMismatchedKid(t *testing.T) {
+	signer, _ := newTestSigner(t)
+	other, _ := newTestSigner(t)
+	k := signer.JWKS().Keys[0]
+	k.Kid = other.JWKS().Keys[0].Kid
+	if _, err := k.PublicKey(); err == nil {
+		t.Error("expected a JWK whose kid does not match its key to be rejected")
+	}
+}
+
+func TestPortalSignedCertificates(t *testing.T) {
+	_, key := newTestSigner(t)
+	p := NewPortalWithConfig(PortalConfig{SigningKey: key, Issuer: "https://portal.example", AdminToken: "s3cret"})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		return rec
+	}
+
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T"}, levelReport([2]int{10, 0}))
+	pending := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "P"}, levelReport())
+	if cert.Token == "" || pending.Token != "" {
+		t.Fatalf("expected a token only for the certificate with results: %q, %q", cert.Token, pending.Token)
+	}
+	uploaded, err := p.store.issue(CertificationRequest{ServerURL: "http://test:8080", Name: "U"}, levelReport([2]int{10, 0}), Attestation{Kind: AttestationSelfReported})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded.Token != "" {
+		t.Error("a self-reported certificate was signed")
+	}
+
+	var keys JWKS
+	json.NewDecoder(get("/.well-known/jwks.json").Body).Decode(&keys)
+	if _, err := VerifyCertificateToken(cert.Token, keys, time.Now()); err != nil {
+		t.Fatalf("token does not verify against the served JWKS: %v", err)
+	}
+
+	verify := func(token string) map[string]any {
+		var resp map[string]any
+		json.NewDecoder(get("/api/verify?token=" + url.QueryEscape(token)).Body).Decode(&resp)
+		return resp
+	}
+	if resp := verify(cert.Token); resp["valid"] != true || resp["id"] != cert.ID {
+		t.Errorf("unexpected verify response: %v", resp)
+	}
+
+	// Updating the results re-signs the certificate.
+	if err := p.UpdateCertificate(cert.ID, levelReport([2]int{10, 0}, [2]int{10, 0})); err != nil {
+		t.Fatal(err)
+	}
+	updated, _ := p.store.Get(cert.ID)
+	if updated.Token == cert.Token || verify(cert.Token)["valid"] != false || verify(updated.Token)["valid"] != true {
+		t.Error("expected only the re-signed token to verify online")
+	}
+
+	req := httptest.NewRequest("POST", "/api/certificates/"+cert.ID+"/revoke", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	if resp := verify(updated.Token); resp["valid"] != false || !strings.Contains(resp["error"].(string), "revoked") {
+		t.Errorf("revoked certificate verified online: %v", resp)
+	}
+
+	// Without a signing key the JWKS is empty.
+	var empty JWKS
+	rec := httptest.NewRecorder()
+	NewPortal().HandleJWKS(rec, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	if err := json.NewDecoder(rec.Body).Decode(&empty); err != nil || len(empty.Keys) != 0 {
+		t.Errorf("expected an empty key set, got %+v, %v", empty, err)
+	}
+}
@@ -0,0 +1,414 @@
+package badge
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// --- Web Pages ---
+
+// The portal serves HTML pages next to its API: a leaderboard, certificate
+// details, token verification and a form for uploading reports produced
+// locally. Templates and styles are embedded in the binary, and the pages
+// load nothing from other origins and run no scripts. The pages only read
+// certificates through the CertificationStore.
+
+// DefaultSpecBaseURL is where the web pages link spec_ref anchors unless
+// PortalConfig.SpecBaseURL says otherwise.
+const DefaultSpecBaseURL = "https://github.com/openjobspec/spec/blob/main/"
+
+// maxUploadSize bounds the form posted to /upload, report included.
+const maxUploadSize = 10 << 20
+
+//go:embed ui
+var uiFiles embed.FS
+
+var uiFuncs = template.FuncMap{
+	"date":      func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+	"unix":      func(sec int64) time.Time { return time.Unix(sec, 0) },
+	"levelName": lib.LevelName,
+	"percent": func(passed, total int) string {
+		if total == 0 {
+			return "–"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(passed)/float64(total))
+	},
+}
+
+// uiPages holds each page's template, parsed together with the layout.
+// Pages define "title" and "content".
+var uiPages = parsePages("leaderboard", "certificate", "verify", "upload", "error")
+
+func parsePages(names ...string) map[string]*template.Template {
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		pages[name] = template.Must(template.New("layout.html").Funcs(uiFuncs).
+			ParseFS(uiFiles, "ui/layout.html", "ui/"+name+".html"))
+	}
+	return pages
+}
+
+// render writes a page. It renders into a buffer first, so that a
+// template error becomes a clean 500 rather than half a page.
+func render(w http.ResponseWriter, status int, page string, data any) {
+	var buf bytes.Buffer
+	if err := uiPages[page].Execute(&buf, data); err != nil {
+		http.Error(w, "rendering page: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Security-Policy", "default-src 'none'; style-src 'self'; img-src 'self'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// errorPage is the data of the "error" page.
+type errorPage struct {
+	Status  int
+	Title   string
+	Message string
+}
+
+func renderError(w http.ResponseWriter, status int, message string) {
+	render(w, status, "error", errorPage{Status: status, Title: http.StatusText(status), Message: message})
+}
+
+// staticHandler serves the embedded stylesheet and images under /static/.
+func staticHandler() http.Handler {
+	static, err := fs.Sub(uiFiles, "ui/static")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix("/static", http.FileServerFS(static))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// leaderboardLevel is one conformant level's section of the leaderboard.
+type leaderboardLevel struct {
+	Level int    // -1 for implementations with no conformant level
+	Title string // e.g. "L0-L2 Scheduled"
+	Rows  []leaderboardRow
+}
+
+type leaderboardRow struct {
+	Cert    *Certificate
+	Expired bool
+}
+
+// HandleLeaderboardPage lists implementations by conformant level.
+// GET /
+func (p *Portal) HandleLeaderboardPage(w http.ResponseWriter, r *http.Request) {
+	levels, err := p.leaderboard(time.Now())
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	render(w, http.StatusOK, "leaderboard", levels)
+}
+
+// leaderboard groups the newest certificate of each implementation,
+// identified by name and organization, by conformant level, highest
+// first. Revoked certificates and those still waiting for their first run
+// are left out. Each kind of attestation is ranked apart, so a submitted
+// report cannot displace an implementation's portal result.
+//
+// Within a level, current certificates come before expired ones, portal
+// runs before self-attested and then self-reported results, and then
+// higher pass ratios first.
+func (p *Portal) leaderboard(now time.Time) ([]leaderboardLevel, error) {
+	seen := make(map[string]bool)
+	byLevel := make(map[int][]leaderboardRow)
+	for offset := 0; ; {
+		certs, total, err := p.store.List(offset, maxListLimit)
+		if err != nil {
+			return nil, fmt.Errorf("listing certificates: %w", err)
+		}
+		for _, c := range certs {
+			if c.RevokedAt != nil || c.Total == 0 {
+				continue
+			}
+			key := strings.ToLower(c.Name) + "\x00" + strings.ToLower(c.Organization) + "\x00" + c.Attestation.Kind
+			if seen[key] {
+				continue // List is newest first
+			}
+			seen[key] = true
+			byLevel[c.ConformantLevel] = append(byLevel[c.ConformantLevel], leaderboardRow{Cert: c, Expired: !now.Before(c.ExpiresAt)})
+		}
+		offset += len(certs)
+		if len(certs) == 0 || offset >= total {
+			break
+		}
+	}
+
+	var levels []leaderboardLevel
+	for lvl := 4; lvl >= -1; lvl-- {
+		rows := byLevel[lvl]
+		if len(rows) == 0 {
+			continue
+		}
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if a.Expired != b.Expired {
+				return !a.Expired
+			}
+			if ka, kb := attestationRank[a.Cert.Attestation.Kind], attestationRank[b.Cert.Attestation.Kind]; ka != kb {
+				return ka < kb
+			}
+			// a.Passed/a.Total > b.Passed/b.Total, without division.
+			if ra, rb := a.Cert.Passed*b.Cert.Total, b.Cert.Passed*a.Cert.Total; ra != rb {
+				return ra > rb
+			}
+			return strings.ToLower(a.Cert.Name) < strings.ToLower(b.Cert.Name)
+		})
+		title := "Not conformant"
+		if lvl >= 0 {
+			title = levelName(lvl) + " " + lib.LevelName(lvl)
+		}
+		levels = append(levels, leaderboardLevel{Level: lvl, Title: title, Rows: rows})
+	}
+	return levels, nil
+}
+
+// attestationRank orders attestations on the leaderboard.
+var attestationRank = map[string]int{AttestationPortal: 0, AttestationSelf: 1, AttestationSelfReported: 2}
+
+// certificatePage is the data of the "certificate" page.
+type certificatePage struct {
+	Cert       *Certificate
+	Status     string // the badge's status: pass, partial, fail, pending, error, expired or revoked
+	StatusText string // the badge's value, e.g. "L0-L2 expired"
+	Extensions []extensionRow
+	Failures   []failureRow
+	Markdown   string // badge snippet for READMEs
+	VerifyURL  string // checks the certificate by ID and fingerprint
+}
+
+type extensionRow struct {
+	Name string
+	ExtensionResult
+}
+
+type failureRow struct {
+	FailedTest
+	Refs []specLink
+}
+
+// specLink is one entry of a spec_ref. URL is empty for entries that are
+// test IDs.
+type specLink struct {
+	Text string
+	URL  string
+}
+
+// HandleCertificatePage shows a certificate: its status, results per level
+// and extension, and the tests it failed, linked to the specification.
+// GET /certificates/{id}
+func (p *Portal) HandleCertificatePage(w http.ResponseWriter, r *http.Request) {
+	cert, err := p.store.Get(r.PathValue("id"))
+	if errors.Is(err, ErrCertificateNotFound) {
+		renderError(w, http.StatusNotFound, "There is no certificate with this ID.")
+		return
+	}
+	if err != nil {
+		renderError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := certificatePage{Cert: cert}
+	_, page.StatusText, page.Status = certificateBadge(cert, time.Now())
+	for name, ext := range cert.Extensions {
+		page.Extensions = append(page.Extensions, extensionRow{Name: name, ExtensionResult: ext})
+	}
+	sort.Slice(page.Extensions, func(i, j int) bool { return page.Extensions[i].Name < page.Extensions[j].Name })
+	for _, f := range cert.Failures {
+		page.Failures = append(page.Failures, failureRow{FailedTest: f, Refs: p.specLinks(f.SpecRef)})
+	}
+	base := p.baseURL(r)
+	page.Markdown = fmt.Sprintf("[![OJS conformance](%s%s)](%s/certificates/%s)", base, cert.BadgeURL, base, cert.ID)
+	page.VerifyURL = "/verify?id=" + cert.ID + "&fingerprint=" + cert.Fingerprint
+	render(w, http.StatusOK, "certificate", page)
+}
+
+// specLinks splits a spec_ref into its entries and links the anchors, such
+// as "ojs-core#section-5.1", to the specification's documents.
+func (p *Portal) specLinks(ref string) []specLink {
+	var links []specLink
+	for _, entry := range strings.Split(ref, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		link := specLink{Text: entry}
+		if doc, anchor, ok := strings.Cut(entry, "#"); ok && strings.HasPrefix(doc, "ojs-") {
+			link.URL = p.specBaseURL + strings.TrimSuffix(doc, ".md") + ".md#" + anchor
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// baseURL is the portal's URL as the request reached it, for snippets that
+// are pasted elsewhere.
+func (p *Portal) baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || p.trustProxy && r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// verifyPage is the data of the "verify" page.
+type verifyPage struct {
+	Token, ID, Fingerprint string
+	Checked                bool // a token or ID was submitted
+	Valid                  bool
+	Error                  string
+	Claims                 *CertificateClaims
+}
+
+// HandleVerifyPage checks a certificate token, or a certificate ID and
+// fingerprint, as GET /api/verify does.
+// GET /verify?token={jws}
+// GET /verify?id={id}&fingerprint={fp}
+func (p *Portal) HandleVerifyPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page := verifyPage{
+		Token:       strings.TrimSpace(q.Get("token")),
+		ID:          strings.TrimSpace(q.Get("id")),
+		Fingerprint: strings.TrimSpace(q.Get("fingerprint")),
+	}
+	switch {
+	case page.Token != "":
+		page.Checked = true
+		claims, err := p.store.VerifyToken(page.Token, time.Now())
+		page.Claims = claims
+		page.Valid = err == nil
+		if err != nil {
+			page.Error = err.Error()
+		}
+	case page.ID != "" || page.Fingerprint != "":
+		if page.ID == "" || page.Fingerprint == "" {
+			page.Error = "Both the certificate ID and its fingerprint are required."
+			render(w, http.StatusBadRequest, "verify", page)
+			return
+		}
+		page.Checked = true
+		page.Valid = p.store.Verify(page.ID, page.Fingerprint)
+		if !page.Valid {
+			page.Error = "No current certificate has this ID and fingerprint."
+		}
+	}
+	render(w, http.StatusOK, "verify", page)
+}
+
+// uploadPage is the data of the "upload" page.
+type uploadPage struct {
+	Name, Organization, Repository string
+	Disabled                       bool // the portal has no API keys
+	Error                          string
+}
+
+// HandleUploadPage shows the form for uploading a report, or explains that
+// uploads are turned off.
+// GET /upload
+func (p *Portal) HandleUploadPage(w http.ResponseWriter, r *http.Request) {
+	render(w, http.StatusOK, "upload", uploadPage{Disabled: len(p.apiKeys) == 0})
+}
+
+// HandleUpload issues a self-reported certificate from a lib.SuiteReport
+// produced locally, such as the -report-file of ojs-conformance-runner.
+// The form carries the report as the "report" file and the
+// implementation's name, organization and repository; the name defaults
+// to the report's backend name. Nothing vouches for an unsigned report, so
+// uploads always need an API key, given as a bearer token, X-API-Key or
+// the "api_key" field, and are turned off on portals without API keys.
+// They count towards the same rate limits as POST /api/certify. The
+// portal does not run the suite, so the certificate is marked
+// self-reported, and it carries no token.
+// POST /upload
+func (p *Portal) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	page := uploadPage{Disabled: len(p.apiKeys) == 0}
+	fail := func(status int, message string) {
+		page.Error = message
+		render(w, status, "upload", page)
+	}
+	if page.Disabled {
+		render(w, http.StatusForbidden, "upload", page)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(http.StatusRequestEntityTooLarge, fmt.Sprintf("The upload is larger than %d MB.", maxUploadSize>>20))
+			return
+		}
+		fail(http.StatusBadRequest, "Invalid form: "+err.Error())
+		return
+	}
+	page.Name = strings.TrimSpace(r.FormValue("name"))
+	page.Organization = strings.TrimSpace(r.FormValue("organization"))
+	page.Repository = strings.TrimSpace(r.FormValue("repository"))
+
+	token := bearerToken(r)
+	if token == "" {
+		token = r.FormValue("api_key")
+	}
+	if _, status, reason := p.admit(w, r, token); status != 0 {
+		fail(status, reason)
+		return
+	}
+
+	file, _, err := r.FormFile("report")
+	if err != nil {
+		fail(http.StatusBadRequest, "Choose a report file to upload.")
+		return
+	}
+	defer file.Close()
+	var report lib.SuiteReport
+	if err := json.NewDecoder(file).Decode(&report); err != nil {
+		fail(http.StatusBadRequest, "The report is not valid JSON: "+err.Error())
+		return
+	}
+	if report.Results.Total == 0 && len(report.Tests) == 0 {
+		fail(http.StatusBadRequest, "The report has no results.")
+		return
+	}
+	if page.Name == "" && report.Backend != nil {
+		page.Name = report.Backend.Name
+	}
+	if page.Name == "" {
+		fail(http.StatusBadRequest, "A name is required.")
+		return
+	}
+
+	req := CertificationRequest{
+		ServerURL:    report.Target,
+		Name:         page.Name,
+		Organization: page.Organization,
+		Repository:   page.Repository,
+	}
+	cert, err := p.store.issue(req, report, Attestation{Kind: AttestationSelfReported, Commit: report.Commit})
+	if err != nil {
+		fail(http.StatusInternalServerError, "Storing the certificate failed: "+err.Error())
+		return
+	}
+	http.Redirect(w, r, "/certificates/"+cert.ID, http.StatusSeeOther)
+}
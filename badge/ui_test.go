@@ -0,0 +1,268 @@
+package badge
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// failingReport is a report whose level 1 test L1-RETRY-001 fails.
+func failingReport() lib.SuiteReport {
+	results := []lib.TestResult{
+		{TestID: "L0-ENV-001", Name: "envelope", Level: 0, Category: "envelope", Status: "pass"},
+		{TestID: "L1-RETRY-001", Name: "retries with backoff", Level: 1, Category: "retry", Status: "fail",
+			SpecRef: "ojs-retry#section-3.2, L0-ENV-001", Failures: []lib.Failure{{Message: "expected 3 attempts, got 1"}}},
+	}
+	report := lib.SuiteReport{
+		Target:  "http://backend:8080",
+		Results: lib.ResultsSummary{Total: 2, Passed: 1, Failed: 1},
+		Tests:   lib.Outcomes(results),
+	}
+	report.Failures = results[1:]
+	return report
+}
+
+func getPage(t *testing.T, mux *http.ServeMux, target string) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	return rec, rec.Body.String()
+}
+
+func TestPortalLeaderboard(t *testing.T) {
+	p := NewPortal()
+	req := func(name string) CertificationRequest {
+		return CertificationRequest{ServerURL: "http://test:8080", Name: name}
+	}
+	issue(t, p, req("Alpha"), levelReport([2]int{10, 0}))
+	issue(t, p, req("alpha"), levelReport([2]int{10, 0}, [2]int{10, 0})) // newer, replaces the first
+	issue(t, p, req("Beta"), levelReport([2]int{10, 0}, [2]int{10, 0}, [2]int{5, 5}))
+	issue(t, p, req("Gamma"), levelReport([2]int{10, 0}, [2]int{10, 0}, [2]int{9, 1}))
+	issue(t, p, req("Pending"), levelReport())
+	revoked := issue(t, p, req("Revoked"), levelReport([2]int{10, 0}))
+	p.store.Revoke(revoked.ID, "")
+	if _, err := p.store.issue(req("Alpha"), levelReport([2]int{10, 0}, [2]int{10, 0}, [2]int{10, 0}), Attestation{Kind: AttestationSelfReported}); err != nil {
+		t.Fatal(err)
+	}
+
+	levels, err := p.leaderboard(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range levels {
+		var names []string
+		for _, row := range l.Rows {
+			name := row.Cert.Name
+			if row.Cert.Attestation.Kind != AttestationPortal {
+				name += "*"
+			}
+			names = append(names, name)
+		}
+		got = append(got, l.Title+": "+strings.Join(names, ", "))
+	}
+	want := []string{
+		"L0-L2 Scheduled: Alpha*",
+		"L0-L1 Reliable: alpha, Gamma, Beta",
+	}
+	if strings.Join(got, "; ") != strings.Join(want, "; ") {
+		t.Errorf("got leaderboard\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	rec, body := getPage(t, mux, "/")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("expected an HTML page, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'none'") {
+		t.Errorf("unexpected Content-Security-Policy %q", csp)
+	}
+	if !strings.Contains(body, "self-reported") || strings.Contains(body, "Revoked") || strings.Contains(body, "Pending") {
+		t.Errorf("unexpected leaderboard page:\n%s", body)
+	}
+}
+
+func TestPortalCertificatePage(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "<Backend>"}, failingReport())
+
+	rec, body := getPage(t, mux, "/certificates/"+cert.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	for _, want := range []string{
+		"&lt;Backend&gt;",
+		`<a href="https://github.com/openjobspec/spec/blob/main/ojs-retry.md#section-3.2">ojs-retry#section-3.2</a>, L0-ENV-001`,
+		"expected 3 attempts, got 1",
+		"L1 Reliable",
+		`(http://example.com` + cert.BadgeURL + `)`,
+		`/verify?id=` + cert.ID + `&amp;fingerprint=` + cert.Fingerprint,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("certificate page lacks %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "self-reported") {
+		t.Error("a portal run is marked self-reported")
+	}
+
+	if rec, _ := getPage(t, mux, "/certificates/cert_missing"); rec.Code != http.StatusNotFound || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("expected an HTML 404, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// Spec links follow SpecBaseURL.
+	p = NewPortalWithConfig(PortalConfig{SpecBaseURL: "https://spec.example/v1/"})
+	if links := p.specLinks("ojs-core.md#section-5.1,L0-ENV-001"); len(links) != 2 ||
+		links[0].URL != "https://spec.example/v1/ojs-core.md#section-5.1" || links[1].URL != "" {
+		t.Errorf("unexpected spec links %+v", links)
+	}
+}
+
+func TestCertificateResultsByLevel(t *testing.T) {
+	cert := issue(t, NewPortal(), CertificationRequest{ServerURL: "http://test:8080", Name: "T"}, failingReport())
+	want := []LevelResult{{Level: 0, Passed: 1, Total: 1}, {Level: 1, Failed: 1, Total: 1}}
+	if len(cert.Levels) != 2 || cert.Levels[0] != want[0] || cert.Levels[1] != want[1] {
+		t.Errorf("got levels %+v, want %+v", cert.Levels, want)
+	}
+	if len(cert.Failures) != 1 || cert.Failures[0].TestID != "L1-RETRY-001" || cert.Failures[0].Message != "expected 3 attempts, got 1" {
+		t.Errorf("unexpected failures %+v", cert.Failures)
+	}
+}
+
+func TestPortalVerifyPage(t *testing.T) {
+	_, key := newTestSigner(t)
+	p := NewPortalWithConfig(PortalConfig{SigningKey: key})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T"}, levelReport([2]int{10, 0}))
+
+	tests := []struct {
+		query string
+		code  int
+		want  string
+	}{
+		{"", http.StatusOK, "Verify a certificate"},
+		{"?token=" + url.QueryEscape(cert.Token), http.StatusOK, "<h2>Valid</h2>"},
+		{"?token=not.a.token", http.StatusOK, "<h2>Not valid</h2>"},
+		{"?id=" + cert.ID + "&fingerprint=" + cert.Fingerprint, http.StatusOK, "<h2>Valid</h2>"},
+		{"?id=" + cert.ID + "&fingerprint=00", http.StatusOK, "<h2>Not valid</h2>"},
+		{"?id=" + cert.ID, http.StatusBadRequest, "fingerprint are required"},
+	}
+	for _, tt := range tests {
+		rec, body := getPage(t, mux, "/verify"+tt.query)
+		if rec.Code != tt.code || !strings.Contains(body, tt.want) {
+			t.Errorf("/verify%s: got %d, want %d and %q:\n%s", tt.query, rec.Code, tt.code, tt.want, body)
+		}
+	}
+}
+
+// uploadForm builds a multipart upload of report with the given fields.
+func uploadForm(t *testing.T, report any, fields map[string]string) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	if report != nil {
+		fw, err := mw.CreateFormFile("report", "report.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(fw).Encode(report)
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestPortalUpload(t *testing.T) {
+	p := NewPortalWithConfig(PortalConfig{APIKeys: []string{"key-1"}})
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	post := func(report any, fields map[string]string) *httptest.ResponseRecorder {
+		body, contentType := uploadForm(t, report, fields)
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	report := failingReport()
+	report.Backend = &lib.BackendInfo{Name: "ojs-backend-test"}
+
+	if rec, body := getPage(t, mux, "/upload"); rec.Code != http.StatusOK || !strings.Contains(body, `name="api_key"`) {
+		t.Errorf("expected the upload form to ask for an API key, got %d", rec.Code)
+	}
+	if rec := post(report, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("upload without a key: expected 401, got %d", rec.Code)
+	}
+	if rec := post("not a report", map[string]string{"api_key": "key-1"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("upload of invalid JSON: expected 400, got %d", rec.Code)
+	}
+	if rec := post(lib.SuiteReport{}, map[string]string{"api_key": "key-1"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("upload without results: expected 400, got %d", rec.Code)
+	}
+
+	rec := post(report, map[string]string{"api_key": "key-1", "organization": "Acme"})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", rec.Code, rec.Body)
+	}
+	id, ok := strings.CutPrefix(rec.Header().Get("Location"), "/certificates/")
+	if !ok {
+		t.Fatalf("unexpected Location %q", rec.Header().Get("Location"))
+	}
+	cert, err := p.store.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Attestation.Kind != AttestationSelfReported || cert.Name != "ojs-backend-test" || cert.Organization != "Acme" || cert.Level != "L0" || len(cert.Failures) != 1 {
+		t.Errorf("unexpected certificate %+v", cert)
+	}
+	if _, body := getPage(t, mux, "/certificates/"+id); !strings.Contains(body, "self-reported") {
+		t.Error("certificate page does not mark the upload self-reported")
+	}
+}
+
+func TestPortalUploadNeedsAPIKeys(t *testing.T) {
+	p := NewPortal()
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+
+	if rec, body := getPage(t, mux, "/upload"); rec.Code != http.StatusOK || strings.Contains(body, "<form") || !strings.Contains(body, "turned off") {
+		t.Errorf("expected the upload page to say uploads are off, got %d", rec.Code)
+	}
+	report := failingReport()
+	report.Backend = &lib.BackendInfo{Name: "ojs-backend-test"}
+	body, contentType := uploadForm(t, report, nil)
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("anonymous upload: expected 403, got %d", rec.Code)
+	}
+	if _, total, _ := p.store.List(0, 10); total != 0 {
+		t.Errorf("anonymous upload issued %d certificate(s)", total)
+	}
+}
+
+func TestPortalStaticAssets(t *testing.T) {
+	mux := http.NewServeMux()
+	NewPortal().RegisterRoutes(mux)
+	rec, body := getPage(t, mux, "/static/style.css")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") || !strings.Contains(body, "body") {
+		t.Errorf("expected the stylesheet, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,73 @@
+package badge
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Text Metrics ---
+
+// Badges are drawn in 11px Verdana, as on shields.io. The renderer may
+// substitute another font, so text elements also carry a textLength that
+// squeezes or stretches it to the measured width.
+
+// verdanaSize is the badge font size in pixels.
+const verdanaSize = 11
+
+// verdanaUnitsPerEm is the em size of the advance widths below.
+const verdanaUnitsPerEm = 2048
+
+// verdanaAdvance holds Verdana's advance widths, in font units, for the
+// printable ASCII characters ' ' (0x20) through '~' (0x7e).
+var verdanaAdvance = [...]uint16{
+	// space ! " # $ % & ' ( ) * + , - . /
+	720, 806, 940, 1676, 1302, 2204, 1488, 550, 930, 930, 1302, 1676, 745, 930, 745, 930,
+	// 0-9
+	1302, 1302, 1302, 1302, 1302, 1302, 1302, 1302, 1302, 1302,
+	// : ; < = > ? @
+	930, 930, 1676, 1676, 1676, 1117, 2048,
+	// A-Z
+	1401, 1405, 1430, 1577, 1294, 1178, 1587, 1540, 862, 931, 1423, 1141, 1726,
+	1532, 1612, 1235, 1612, 1424, 1400, 1262, 1499, 1401, 2025, 1403, 1260, 1403,
+	// [ \ ] ^ _ `
+	930, 930, 930, 1676, 1302, 1302,
+	// a-z
+	1229, 1276, 1067, 1276, 1220, 720, 1276, 1296, 562, 705, 1212, 562, 1992,
+	1296, 1243, 1276, 1276, 874, 1067, 807, 1296, 1212, 1676, 1212, 1212, 1076,
+	// { | } ~
+	1300, 930, 1300, 1676,
+}
+
+// Advance widths for characters outside the table.
+const (
+	// verdanaWide is used for East Asian ideographs and syllables, which
+	// are a full em wide.
+	verdanaWide = verdanaUnitsPerEm
+	// verdanaFallback is used for other unknown characters. It is the
+	// width of "m", so that guesses err towards a badge that is too wide
+	// rather than clipped text.
+	verdanaFallback = 1992
+)
+
+// textWidth returns the width of s in pixels when set in 11px Verdana.
+func textWidth(s string) float64 {
+	var units int
+	for _, r := range s {
+		units += runeAdvance(r)
+	}
+	return float64(units) * verdanaSize / verdanaUnitsPerEm
+}
+
+func runeAdvance(r rune) int {
+	switch {
+	case r >= ' ' && r <= '~':
+		return int(verdanaAdvance[r-' '])
+	case r == utf8.RuneError, unicode.IsControl(r), unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0 // combining marks and format characters take no space
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r >= 0xff01 && r <= 0xff60: // fullwidth forms
+		return verdanaWide
+	default:
+		return verdanaFallback
+	}
+}
@@ -0,0 +1,147 @@
+package badge
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// --- Status Notifications ---
+
+// Certificates whose request names a callback_url are reported there when
+// their status changes. Each delivery is a JSON Notification POSTed with
+// these headers:
+//
+//	X-OJS-Event:     the event name
+//	X-OJS-Delivery:  a random ID, the same for every attempt
+//	X-OJS-Timestamp: Unix seconds when the attempt was sent
+//	X-OJS-Signature: "sha256=" + hex HMAC-SHA256 of "<timestamp>.<body>",
+//	                 keyed with the request's callback_secret
+//
+// Receivers should recompute the signature, compare it in constant time
+// and reject old timestamps.
+
+// NotificationStatusChanged is the event sent when a certificate's status
+// changes between pass, partial, fail, error and expired.
+const NotificationStatusChanged = "certificate.status_changed"
+
+// Notification is the body of a webhook delivery.
+type Notification struct {
+	Event          string    `json:"event"`
+	CertificateID  string    `json:"certificate_id"`
+	Name           string    `json:"name"`
+	PreviousStatus string    `json:"previous_status"` // "pending" before the first run
+	Status         string    `json:"status"`
+	Level          string    `json:"level"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	BadgeURL       string    `json:"badge_url"`
+	Token          string    `json:"token,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// WebhookSignature returns the X-OJS-Signature value for a body sent at
+// timestamp (Unix seconds).
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookNotifier delivers notifications, retrying failed deliveries with
+// exponential backoff. Network errors, 429 and 5xx responses are retried;
+// other responses end the delivery.
+type WebhookNotifier struct {
+	Client *http.Client
+	// MaxAttempts bounds the attempts per delivery.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles after every
+	// further attempt.
+	Backoff time.Duration
+	// OnError, if set, is called for the portal's background deliveries
+	// that failed for good.
+	OnError func(url string, n Notification, err error)
+}
+
+// NewWebhookNotifier creates a notifier that makes up to 5 attempts over
+// about 7.5 minutes.
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 5,
+		Backoff:     30 * time.Second,
+	}
+}
+
+// Deliver POSTs n to url, signed with secret, and retries until it is
+// accepted, the attempts run out or ctx is cancelled.
+func (wn *WebhookNotifier) Deliver(ctx context.Context, url, secret string, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	delivery := hex.EncodeToString(id)
+
+	backoff := wn.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := wn.post(ctx, url, secret, n.Event, delivery, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= max(wn.MaxAttempts, 1) {
+			return fmt.Errorf("webhook %s: attempt %d: %w", url, attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s: %w (after attempt %d: %v)", url, ctx.Err(), attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying.
+func (wn *WebhookNotifier) post(ctx context.Context, url, secret, event, delivery string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ojs-conformance-portal")
+	req.Header.Set("X-OJS-Event", event)
+	req.Header.Set("X-OJS-Delivery", delivery)
+	req.Header.Set("X-OJS-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-OJS-Signature", WebhookSignature(secret, timestamp, body))
+
+	client := wn.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+}
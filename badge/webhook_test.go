@@ -0,0 +1,197 @@
+package badge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac s3cret
+	want := "sha256=97926816e98fbb41ccb1673225ff29a2f35369099990e1b1561651e7bd097ebf"
+	if got := WebhookSignature("s3cret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWebhookDeliverRetries(t *testing.T) {
+	var attempts atomic.Int32
+	deliveries := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get("X-OJS-Timestamp"), 10, 64)
+		if r.Header.Get("X-OJS-Signature") != WebhookSignature("s3cret", ts, body) {
+			t.Errorf("bad signature %q", r.Header.Get("X-OJS-Signature"))
+		}
+		if r.Header.Get("X-OJS-Event") != NotificationStatusChanged {
+			t.Errorf("unexpected event %q", r.Header.Get("X-OJS-Event"))
+		}
+		deliveries[r.Header.Get("X-OJS-Delivery")] = true
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	wn := &WebhookNotifier{MaxAttempts: 5, Backoff: time.Millisecond}
+	n := Notification{Event: NotificationStatusChanged, CertificateID: "cert_1", Status: "pass"}
+	if err := wn.Deliver(context.Background(), srv.URL, "s3cret", n); err != nil {
+		t.Fatal(err)
+	}
+	if attempts.Load() != 3 || len(deliveries) != 1 {
+		t.Errorf("expected 3 attempts of one delivery, got %d attempts, %d deliveries", attempts.Load(), len(deliveries))
+	}
+}
+
+func TestWebhookDeliverGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+	status := http.StatusBadGateway
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	wn := &WebhookNotifier{MaxAttempts: 3, Backoff: time.Millisecond}
+	if err := wn.Deliver(context.Background(), srv.URL, "s", Notification{}); err == nil || attempts.Load() != 3 {
+		t.Errorf("expected failure after 3 attempts, got %v after %d", err, attempts.Load())
+	}
+
+	// Client errors are not retried.
+	attempts.Store(0)
+	status = http.StatusGone
+	if err := wn.Deliver(context.Background(), srv.URL, "s", Notification{}); err == nil || attempts.Load() != 1 {
+		t.Errorf("expected one attempt for 410, got %v after %d", err, attempts.Load())
+	}
+}
+
+// callbackServer records the notifications it receives.
+func callbackServer(t *testing.T) (*httptest.Server, <-chan Notification) {
+	t.Helper()
+	ch := make(chan Notification, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decoding notification: %v", err)
+		}
+		ch <- n
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+func receive(t *testing.T, ch <-chan Notification) Notification {
+	t.Helper()
+	select {
+	case n := <-ch:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+		return Notification{}
+	}
+}
+
+func TestPortalNotifiesStatusChanges(t *testing.T) {
+	srv, notifications := callbackServer(t)
+	p := NewPortalWithConfig(PortalConfig{Targets: &TargetPolicy{}}) // the callback server is on loopback
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T", CallbackURL: srv.URL, CallbackSecret: "s3cret"}, lib.SuiteReport{})
+
+	if err := p.UpdateCertificate(cert.ID, levelReport([2]int{10, 0}, [2]int{5, 1})); err != nil {
+		t.Fatal(err)
+	}
+	n := receive(t, notifications)
+	if n.CertificateID != cert.ID || n.PreviousStatus != "pending" || n.Status != "partial" || n.Level != "L0" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+
+	// An unchanged status sends nothing.
+	if err := p.UpdateCertificate(cert.ID, levelReport([2]int{10, 0}, [2]int{4, 2})); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.UpdateCertificate(cert.ID, levelReport([2]int{10, 0}, [2]int{6, 0})); err != nil {
+		t.Fatal(err)
+	}
+	if n := receive(t, notifications); n.PreviousStatus != "partial" || n.Status != "pass" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+}
+
+func TestPortalNotifiesFailedRuns(t *testing.T) {
+	srv, notifications := callbackServer(t)
+	p := NewPortalWithConfig(PortalConfig{Targets: &TargetPolicy{}}) // the callback server is on loopback
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T", CallbackURL: srv.URL, CallbackSecret: "s3cret"}, lib.SuiteReport{})
+
+	if err := p.failRun(cert.ID, "dial tcp: connection refused"); err != nil {
+		t.Fatal(err)
+	}
+	if n := receive(t, notifications); n.PreviousStatus != "pending" || n.Status != "error" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+}
+
+func TestPortalWaitsForDeliveries(t *testing.T) {
+	attempted := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempted <- struct{}{}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	failed := make(chan error, 1)
+	p := NewPortalWithConfig(PortalConfig{Webhooks: &WebhookNotifier{
+		Client:      srv.Client(),
+		MaxAttempts: 5,
+		Backoff:     time.Hour,
+		OnError:     func(url string, n Notification, err error) { failed <- err },
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	p.StartRunner(ctx, func(ctx context.Context, req CertificationRequest) (lib.SuiteReport, error) {
+		return lib.SuiteReport{}, errors.New("not run")
+	}, 1)
+	cert := issue(t, p, CertificationRequest{ServerURL: "http://test:8080", Name: "T", CallbackURL: srv.URL}, lib.SuiteReport{})
+	if err := p.UpdateCertificate(cert.ID, levelReport([2]int{10, 0})); err != nil {
+		t.Fatal(err)
+	}
+	<-attempted
+
+	// The delivery is waiting to retry; stopping the runner ends it, and
+	// the failure is reported rather than dropped.
+	cancel()
+	waitCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := p.WaitDeliveries(waitCtx); err != nil {
+		t.Fatalf("deliveries did not end: %v", err)
+	}
+	select {
+	case err := <-failed:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the delivery to be cancelled, got %v", err)
+		}
+	default:
+		t.Error("the cancelled delivery was not reported")
+	}
+}
+
+func TestPortalCertifyCallbackValidation(t *testing.T) {
+	p := NewPortal()
+	for _, body := range []string{
+		`{"server_url":"http://x","name":"X","callback_url":"ftp://hooks.example"}`,
+		`{"server_url":"http://x","name":"X","callback_url":"https://hooks.example/ojs"}`,
+	} {
+		rec := httptest.NewRecorder()
+		p.HandleCertify(rec, httptest.NewRequest("POST", "/api/certify", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/badge"
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// verifyCertCmd checks a portal certificate token offline against the
+// portal's published keys.
+func verifyCertCmd(args []string, w io.Writer) (bool, error) {
+	fs := flag.NewFlagSet("verify-cert", flag.ContinueOnError)
+	jwksPath := fs.String("jwks", "", "Portal key set, as served at /.well-known/jwks.json")
+	keyPath := fs.String("key", "", "Portal Ed25519 public key (PKIX PEM), instead of -jwks")
+	issuer := fs.String("issuer", "", "Require this issuer (iss claim)")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 1 {
+		return false, fmt.Errorf("expected one token or certificate file, got %d argument(s)", fs.NArg())
+	}
+	if (*jwksPath == "") == (*keyPath == "") {
+		return false, fmt.Errorf("exactly one of -jwks and -key is required")
+	}
+
+	var keys badge.JWKS
+	if *jwksPath != "" {
+		data, err := os.ReadFile(*jwksPath)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return false, fmt.Errorf("%s: %w", *jwksPath, err)
+		}
+	} else {
+		pub, err := lib.LoadVerifyKey(*keyPath)
+		if err != nil {
+			return false, err
+		}
+		keys.Keys = []badge.JWK{badge.NewJWK(pub)}
+	}
+
+	arg := fs.Arg(0)
+	token, err := readCertToken(arg)
+	if err != nil {
+		return false, err
+	}
+
+	claims, err := badge.VerifyCertificateToken(token, keys, time.Now())
+	if err == nil && *issuer != "" && claims.Issuer != *issuer {
+		err = fmt.Errorf("issued by %q, not %q", claims.Issuer, *issuer)
+	}
+	if err != nil {
+		fmt.Fprintf(w, "FAILED: %s: %v\n", arg, err)
+		return false, nil
+	}
+
+	fmt.Fprintf(w, "OK: %s (%s) issued by %s\n", claims.Subject, claims.Name, claims.Issuer)
+	fmt.Fprintf(w, "  level %s (%s), %d/%d passed, expires %s\n",
+		claims.Level, claims.Status, claims.Passed, claims.Total, time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339))
+	switch claims.Attestation {
+	case badge.AttestationPortal:
+		fmt.Fprintf(w, "  attestation %s: the portal ran the suite\n", claims.Attestation)
+	case badge.AttestationSelf:
+		fmt.Fprintf(w, "  attestation %s: the submitter ran the suite and signed the report\n", claims.Attestation)
+	default:
+		fmt.Fprintf(w, "  attestation %q: unknown; the portal may not have run the suite\n", claims.Attestation)
+	}
+	names := make([]string, 0, len(claims.Extensions))
+	for name := range claims.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ext := claims.Extensions[name]
+		fmt.Fprintf(w, "  extension %s: %d/%d passed\n", name, ext.Passed, ext.Total)
+	}
+	fmt.Fprintln(w, "  revocation is not checked offline; see GET /api/verify?token=")
+	return true, nil
+}
+
+var jwsPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`)
+
+// readCertToken accepts a token, a file holding one, or a certificate JSON
+// file as returned by GET /api/certificates/{id}.
+func readCertToken(arg string) (string, error) {
+	if _, err := os.Stat(arg); err != nil && jwsPattern.MatchString(arg) {
+		return arg, nil
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return "", err
+	}
+	if s := strings.TrimSpace(string(data)); !strings.HasPrefix(s, "{") {
+		return s, nil
+	}
+	var cert badge.Certificate
+	if err := json.Unmarshal(data, &cert); err != nil {
+		return "", fmt.Errorf("%s: %w", arg, err)
+	}
+	if cert.Token == "" {
+		return "", fmt.Errorf("%s: certificate %s has no token", arg, cert.ID)
+	}
+	return cert.Token, nil
+}
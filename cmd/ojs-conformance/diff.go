@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// diffCmd runs the diff subcommand and reports whether the new run
+// regressed.
+func diffCmd(args []string, w io.Writer) (bool, error) {
+	defaults := lib.DefaultDiffOptions()
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	output := fs.String("output", "table", "Output format: table, markdown or json")
+	thresholdPct := fs.Float64("duration-threshold", defaults.DurationThresholdPct, "Percent slowdown of a passing test reported as a duration regression")
+	minDeltaMs := fs.Int64("duration-min-delta", defaults.MinDurationDeltaMs, "Ignore slowdowns smaller than this many milliseconds")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 2 {
+		return false, fmt.Errorf("expected two reports, old and new, got %d argument(s)", fs.NArg())
+	}
+	switch *output {
+	case "table", "markdown", "json":
+	default:
+		return false, fmt.Errorf("-output must be table|markdown|json, got %q", *output)
+	}
+
+	before, err := lib.LoadReport(fs.Arg(0))
+	if err != nil {
+		return false, err
+	}
+	after, err := lib.LoadReport(fs.Arg(1))
+	if err != nil {
+		return false, err
+	}
+	d := lib.DiffReports(before, after, lib.DiffOptions{DurationThresholdPct: *thresholdPct, MinDurationDeltaMs: *minDeltaMs})
+
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(d)
+	case "markdown":
+		err = writeDiffMarkdown(w, d)
+	default:
+		err = writeDiffTable(w, d)
+	}
+	return d.Regressed(), err
+}
+
+// levelText renders a conformant level for humans.
+func levelText(level int) string {
+	if level < 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d (%s)", level, lib.LevelName(level))
+}
+
+func levelCounts(ls lib.LevelSummary) string {
+	return fmt.Sprintf("%d/%d passed, %d failed, %d errored, %d skipped", ls.Passed, ls.Total, ls.Failed, ls.Errored, ls.Skipped)
+}
+
+func writeDiffTable(w io.Writer, d lib.ReportDiff) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Old: %s (%s), %d/%d passed\n", d.Old.Target, d.Old.RunAt, d.Old.Passed, d.Old.Total)
+	fmt.Fprintf(&b, "New: %s (%s), %d/%d passed\n", d.New.Target, d.New.RunAt, d.New.Passed, d.New.Total)
+	if d.ConformantLevelChanged() {
+		fmt.Fprintf(&b, "Conformant level: %s -> %s\n", levelText(d.Old.ConformantLevel), levelText(d.New.ConformantLevel))
+	} else {
+		fmt.Fprintf(&b, "Conformant level: %s (unchanged)\n", levelText(d.New.ConformantLevel))
+	}
+	if !d.Complete {
+		b.WriteString("Note: a report has no per-test list; added/removed tests and durations are not compared.\n")
+	}
+
+	changes := func(title string, list []lib.TestChange) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", title, len(list))
+		for _, c := range list {
+			fmt.Fprintf(&b, "  %-14s %-40s %s -> %s\n", c.TestID, truncate(c.Name, 40), c.OldStatus, c.NewStatus)
+		}
+	}
+	outcomes := func(title string, list []lib.TestOutcome) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", title, len(list))
+		for _, o := range list {
+			fmt.Fprintf(&b, "  %-14s %-40s %s\n", o.TestID, truncate(o.Name, 40), o.Status)
+		}
+	}
+	changes("Newly failing", d.NewlyFailing)
+	changes("Newly passing", d.NewlyPassing)
+	outcomes("Added", d.Added)
+	outcomes("Removed", d.Removed)
+
+	if len(d.Levels) > 0 {
+		b.WriteString("\nLevels:\n")
+		for _, lc := range d.Levels {
+			fmt.Fprintf(&b, "  L%d %-13s %s -> %s\n", lc.Level, lib.LevelName(lc.Level), levelCounts(lc.Old), levelCounts(lc.New))
+		}
+	}
+	if len(d.DurationRegressions) > 0 {
+		fmt.Fprintf(&b, "\nSlower (%d):\n", len(d.DurationRegressions))
+		for _, dc := range d.DurationRegressions {
+			fmt.Fprintf(&b, "  %-14s %-40s %dms -> %dms (+%.0f%%)\n", dc.TestID, truncate(dc.Name, 40), dc.OldMs, dc.NewMs, dc.DeltaPct)
+		}
+	}
+	if len(d.NewlyFailing)+len(d.NewlyPassing)+len(d.Added)+len(d.Removed)+len(d.DurationRegressions) == 0 {
+		b.WriteString("\nNo test changes.\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDiffMarkdown writes the diff for a pull request comment.
+func writeDiffMarkdown(w io.Writer, d lib.ReportDiff) error {
+	var b strings.Builder
+	b.WriteString("## OJS conformance diff\n\n")
+	b.WriteString("| | Old | New |\n|---|---|---|\n")
+	fmt.Fprintf(&b, "| Target | %s | %s |\n", mdCell(d.Old.Target), mdCell(d.New.Target))
+	fmt.Fprintf(&b, "| Passed | %d/%d | %d/%d |\n", d.Old.Passed, d.Old.Total, d.New.Passed, d.New.Total)
+	marker := ""
+	if d.New.ConformantLevel < d.Old.ConformantLevel {
+		marker = " :warning:"
+	}
+	fmt.Fprintf(&b, "| Conformant level | %s | %s%s |\n", levelText(d.Old.ConformantLevel), levelText(d.New.ConformantLevel), marker)
+	if !d.Complete {
+		b.WriteString("\n> A report has no per-test list; added/removed tests and durations are not compared.\n")
+	}
+
+	changes := func(title string, list []lib.TestChange) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n| Test | Name | Old | New |\n|---|---|---|---|\n", title, len(list))
+		for _, c := range list {
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", c.TestID, mdCell(c.Name), c.OldStatus, c.NewStatus)
+		}
+	}
+	outcomes := func(title string, list []lib.TestOutcome) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n### %s (%d)\n\n| Test | Name | Status |\n|---|---|---|\n", title, len(list))
+		for _, o := range list {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", o.TestID, mdCell(o.Name), o.Status)
+		}
+	}
+	changes("Newly failing", d.NewlyFailing)
+	changes("Newly passing", d.NewlyPassing)
+	outcomes("Added", d.Added)
+	outcomes("Removed", d.Removed)
+
+	if len(d.Levels) > 0 {
+		b.WriteString("\n### Levels\n\n| Level | Old | New |\n|---|---|---|\n")
+		for _, lc := range d.Levels {
+			fmt.Fprintf(&b, "| %d %s | %s | %s |\n", lc.Level, lib.LevelName(lc.Level), levelCounts(lc.Old), levelCounts(lc.New))
+		}
+	}
+	if len(d.DurationRegressions) > 0 {
+		fmt.Fprintf(&b, "\n### Slower (%d)\n\n| Test | Name | Old | New | Change |\n|---|---|---|---|---|\n", len(d.DurationRegressions))
+		for _, dc := range d.DurationRegressions {
+			fmt.Fprintf(&b, "| `%s` | %s | %dms | %dms | +%.0f%% |\n", dc.TestID, mdCell(dc.Name), dc.OldMs, dc.NewMs, dc.DeltaPct)
+		}
+	}
+	if len(d.NewlyFailing)+len(d.NewlyPassing)+len(d.Added)+len(d.Removed)+len(d.DurationRegressions) == 0 {
+		b.WriteString("\nNo test changes.\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdCell escapes text for a markdown table cell.
+func mdCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n-3] + "..."
+	}
+	return s
+}
@@ -0,0 +1,171 @@
+// Command ojs-conformance provides offline tooling for the conformance suites.
+//
+// Usage:
+//
+//	go run ./cmd/ojs-conformance lint
+//	go run ./cmd/ojs-conformance lint -suites ./suites -output json
+//	go run ./cmd/ojs-conformance diff main.json branch.json
+//	go run ./cmd/ojs-conformance diff -output markdown main.json branch.json
+//	go run ./cmd/ojs-conformance sign -key ojs-signing.pem report.json
+//	go run ./cmd/ojs-conformance verify -key ojs-signing.pub.pem report.json
+//	go run ./cmd/ojs-conformance verify-cert -jwks jwks.json certificate.json
+//
+// The lint subcommand checks every test file without contacting a server and
+// exits with status 1 if any diagnostics are reported.
+//
+// The diff subcommand compares two JSON reports and exits with status 1 if
+// the second run regressed: a test started failing or the conformant level
+// dropped.
+//
+// The redact subcommand removes hostnames, CI URLs, response bodies and
+// sensitive header values from a report before it is published or signed.
+//
+// The keygen, sign and verify subcommands create an Ed25519 key pair, sign
+// a JSON report with a detached signature or a DSSE envelope, and check
+// such a signature; verify exits with status 1 if the report was modified
+// after signing or the signature is not from the given key.
+//
+// The verify-cert subcommand checks a certificate token issued by the
+// certification portal against the portal's published keys, without
+// contacting it, and exits with status 1 if the token is forged, altered or
+// expired.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "lint":
+		clean, err := lintCmd(os.Args[2:], os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance lint:", err)
+			os.Exit(2)
+		}
+		if !clean {
+			os.Exit(1)
+		}
+	case "diff":
+		regressed, err := diffCmd(os.Args[2:], os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance diff:", err)
+			os.Exit(2)
+		}
+		if regressed {
+			os.Exit(1)
+		}
+	case "redact":
+		if err := redactCmd(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance redact:", err)
+			os.Exit(2)
+		}
+	case "keygen":
+		if err := keygenCmd(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance keygen:", err)
+			os.Exit(2)
+		}
+	case "sign":
+		if err := signCmd(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance sign:", err)
+			os.Exit(2)
+		}
+	case "verify":
+		valid, err := verifyCmd(os.Args[2:], os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance verify:", err)
+			os.Exit(2)
+		}
+		if !valid {
+			os.Exit(1)
+		}
+	case "verify-cert":
+		valid, err := verifyCertCmd(os.Args[2:], os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ojs-conformance verify-cert:", err)
+			os.Exit(2)
+		}
+		if !valid {
+			os.Exit(1)
+		}
+	case "-h", "--help", "help":
+		usage()
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ojs-conformance <lint|diff|redact|keygen|sign|verify|verify-cert> [flags]")
+	fmt.Fprintln(os.Stderr, "  lint [-suites ./suites] [-output text|json]")
+	fmt.Fprintln(os.Stderr, "       Validate suite files without contacting a server.")
+	fmt.Fprintln(os.Stderr, "  diff [-output table|markdown|json] [-duration-threshold 50] [-duration-min-delta 100] old.json new.json")
+	fmt.Fprintln(os.Stderr, "       Compare two conformance reports.")
+	fmt.Fprintln(os.Stderr, "  redact [-anonymous] [-redact-headers re,...] [-redact-pattern re] [-out path] report.json")
+	fmt.Fprintln(os.Stderr, "       Strip hostnames, CI URLs, bodies and sensitive headers from a report.")
+	fmt.Fprintln(os.Stderr, "  keygen [-out ojs-signing.pem]")
+	fmt.Fprintln(os.Stderr, "       Create an Ed25519 signing key pair.")
+	fmt.Fprintln(os.Stderr, "  sign -key key.pem [-format detached|dsse] [-out path] report.json")
+	fmt.Fprintln(os.Stderr, "       Sign a conformance report.")
+	fmt.Fprintln(os.Stderr, "  verify -key key.pub.pem [-signature path] report.json|envelope.json")
+	fmt.Fprintln(os.Stderr, "       Verify a signed conformance report.")
+	fmt.Fprintln(os.Stderr, "  verify-cert -jwks jwks.json|-key portal.pub.pem [-issuer url] token|certificate.json")
+	fmt.Fprintln(os.Stderr, "       Verify a portal certificate token offline.")
+}
+
+// lintReport is the JSON document written by `lint -output json`.
+type lintReport struct {
+	Suites      string           `json:"suites"`
+	Diagnostics []lib.Diagnostic `json:"diagnostics"`
+}
+
+// lintCmd runs the lint subcommand and reports whether the suites are clean.
+func lintCmd(args []string, w io.Writer) (bool, error) {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	suitesDir := fs.String("suites", "./suites", "Path to test suite directory")
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 0 {
+		return false, fmt.Errorf("unexpected arguments: %v (use -suites to choose the suite directory)", fs.Args())
+	}
+	switch *output {
+	case "text", "json":
+	default:
+		return false, fmt.Errorf("-output must be text|json, got %q", *output)
+	}
+
+	diags, err := lib.LintSuites(*suitesDir)
+	if err != nil {
+		return false, err
+	}
+
+	if *output == "json" {
+		if diags == nil {
+			diags = []lib.Diagnostic{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(lintReport{Suites: *suitesDir, Diagnostics: diags}); err != nil {
+			return false, err
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Fprintln(w, d.String())
+		}
+		fmt.Fprintf(w, "%d diagnostic(s)\n", len(diags))
+	}
+	return len(diags) == 0, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// redactCmd writes a copy of a report with infrastructure details removed,
+// for reports produced without the runners' -redact flag.
+func redactCmd(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("redact", flag.ContinueOnError)
+	out := fs.String("out", "", "Write the redacted report to this file instead of stdout")
+	anonymous := fs.Bool("anonymous", false, "Drop every submitter field except key_id")
+	headers := fs.String("redact-headers", "", "Comma-separated header name regexps whose values are redacted, in addition to the defaults")
+	var patterns []string
+	fs.Func("redact-pattern", "Regexp replaced wherever it matches in the report; repeatable", func(s string) error {
+		patterns = append(patterns, s)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one report, got %d argument(s)", fs.NArg())
+	}
+
+	report, err := lib.LoadReport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cfg := lib.DefaultRedactConfig()
+	for _, h := range strings.Split(*headers, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			cfg.Headers = append(cfg.Headers, h)
+		}
+	}
+	cfg.Patterns = patterns
+	redactor, err := lib.NewRedactor(cfg)
+	if err != nil {
+		return err
+	}
+	redactor.Report(&report)
+	if *anonymous {
+		keyID := ""
+		if report.Submitter != nil {
+			keyID = report.Submitter.KeyID
+		}
+		report.Submitter = lib.NewSubmitter("", "", keyID, true)
+	}
+
+	if *out != "" {
+		return lib.WriteReportFile(*out, report)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+// keygenCmd writes a new Ed25519 key pair.
+func keygenCmd(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	out := fs.String("out", "ojs-signing.pem", "Private key path; the public key is written next to it as <name>.pub.pem")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	privPEM, pubPEM, err := lib.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	pubPath := strings.TrimSuffix(*out, ".pem") + ".pub.pem"
+	if err := writeNewFile(*out, privPEM, 0o600); err != nil {
+		return err
+	}
+	if err := writeNewFile(pubPath, pubPEM, 0o644); err != nil {
+		return err
+	}
+	pub, err := lib.LoadVerifyKey(pubPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %s and %s (key %s)\n", *out, pubPath, lib.KeyID(pub))
+	return nil
+}
+
+// writeNewFile refuses to overwrite existing keys.
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// signCmd signs a report with a detached signature or a DSSE envelope.
+func signCmd(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "Ed25519 private key (PKCS #8 PEM)")
+	format := fs.String("format", "detached", "Signature format: detached or dsse")
+	out := fs.String("out", "", "Signature path (default: <report>.sig, or <report>.dsse.json for dsse)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one report, got %d argument(s)", fs.NArg())
+	}
+	if *keyPath == "" {
+		return fmt.Errorf("-key is required")
+	}
+	reportPath := fs.Arg(0)
+
+	key, err := lib.LoadSigningKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	report, err := loadReportStrict(reportPath)
+	if err != nil {
+		return err
+	}
+
+	var sig any
+	switch *format {
+	case "detached":
+		if *out == "" {
+			*out = reportPath + ".sig"
+		}
+		sig, err = lib.SignReport(report, key)
+	case "dsse":
+		if *out == "" {
+			*out = reportPath + ".dsse.json"
+		}
+		sig, err = lib.SignReportEnvelope(report, key)
+	default:
+		return fmt.Errorf("-format must be detached|dsse, got %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	digest, err := lib.ReportDigest(report)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Signed %s (sha256 %s) with key %s: %s\n", reportPath, digest, lib.KeyID(key.Public().(ed25519.PublicKey)), *out)
+	return nil
+}
+
+// verifyCmd checks a report's signature and reports whether it is valid.
+// The argument is either a report, whose signature is read from -signature,
+// or a DSSE envelope, which carries the report itself.
+func verifyCmd(args []string, w io.Writer) (bool, error) {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	keyPath := fs.String("key", "", "Ed25519 public key (PKIX PEM)")
+	sigPath := fs.String("signature", "", "Detached signature or DSSE envelope for the report (default: <report>.sig)")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() != 1 {
+		return false, fmt.Errorf("expected one report or envelope, got %d argument(s)", fs.NArg())
+	}
+	if *keyPath == "" {
+		return false, fmt.Errorf("-key is required")
+	}
+	path := fs.Arg(0)
+
+	pub, err := lib.LoadVerifyKey(*keyPath)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	fail := func(err error) (bool, error) {
+		fmt.Fprintf(w, "FAILED: %s: %v\n", path, err)
+		return false, nil
+	}
+
+	var report lib.SuiteReport
+	if env, ok := parseEnvelope(data); ok {
+		if report, err = lib.VerifyReportEnvelope(env, pub); err != nil {
+			return fail(err)
+		}
+	} else {
+		if report, err = lib.ParseReportStrict(data); err != nil {
+			return fail(fmt.Errorf("not a valid report: %w", err))
+		}
+		if *sigPath == "" {
+			*sigPath = path + ".sig"
+		}
+		sigData, err := os.ReadFile(*sigPath)
+		if err != nil {
+			return false, err
+		}
+		if env, ok := parseEnvelope(sigData); ok {
+			attested, err := lib.VerifyReportEnvelope(env, pub)
+			if err != nil {
+				return fail(err)
+			}
+			want, err := lib.ReportDigest(attested)
+			if err != nil {
+				return false, err
+			}
+			got, err := lib.ReportDigest(report)
+			if err != nil {
+				return false, err
+			}
+			if got != want {
+				return fail(fmt.Errorf("report was modified after signing: sha256 %s, signed %s", got, want))
+			}
+		} else {
+			var sig lib.ReportSignature
+			if err := json.Unmarshal(sigData, &sig); err != nil {
+				return false, fmt.Errorf("%s: %w", *sigPath, err)
+			}
+			if err := lib.VerifyReport(report, sig, pub); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "OK: %s signed by %s\n", path, lib.KeyID(pub))
+	fmt.Fprintf(w, "  target %s, run at %s, %d/%d passed, conformant level %s\n",
+		report.Target, report.RunAt, report.Results.Passed, report.Results.Total, levelText(report.ConformantLevel))
+	if report.Commit != nil {
+		fmt.Fprintf(w, "  commit %s\n", strings.TrimSpace(report.Commit.Repo+" "+report.Commit.SHA))
+	}
+	return true, nil
+}
+
+// parseEnvelope reports whether data is a DSSE envelope.
+func parseEnvelope(data []byte) (lib.Envelope, bool) {
+	var env lib.Envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.PayloadType == "" {
+		return env, false
+	}
+	return env, true
+}
+
+func loadReportStrict(path string) (lib.SuiteReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lib.SuiteReport{}, err
+	}
+	report, err := lib.ParseReportStrict(data)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
@@ -1,42 +1,182 @@
 // Command portal runs the OJS Conformance Certification Portal.
 //
 // It serves the portal HTTP API for certification requests, certificate
-// management, badge generation, and verification.
+// management, badge generation, and verification. Certification requests
+// are queued and run in-process against the submitted server URL using the
+// suites in PORTAL_SUITES (default ./suites), on PORTAL_WORKERS goroutines
+// (default 1).
+//
+// Certificates are kept by the storage named by -store or PORTAL_STORE:
+// "memory" (the default; lost on restart), "file:<path>" for a JSON file
+// rewritten atomically on every change, or "sqlite:<path>" in binaries
+// built with -tags sqlite. Setting PORTAL_ADMIN_TOKEN enables
+// POST /api/certificates/{id}/revoke for callers presenting it as a bearer
+// token.
+//
+// POST /api/certify is open to anyone unless PORTAL_API_KEYS lists the
+// keys it accepts (comma-separated, sent as a bearer token or X-API-Key).
+// Requests are limited per key by PORTAL_RATE_LIMIT_KEY (default 60/h) and
+// per client IP by PORTAL_RATE_LIMIT_IP (default 10/h); set
+// PORTAL_TRUST_PROXY=1 to take the IP from X-Forwarded-For. Each key or IP
+// may have PORTAL_MAX_IN_FLIGHT runs (default 2) queued or running. Runs
+// and webhooks may not reach loopback, private, link-local or other
+// non-public addresses; PORTAL_ALLOW_NETWORKS lists CIDRs to allow anyway
+// and PORTAL_DENY_NETWORKS further CIDRs to deny.
+//
+// Certificates carry a JWS token signed with the Ed25519 key in
+// PORTAL_SIGNING_KEY (PKCS #8 PEM, as written by ojs-conformance keygen),
+// verifiable offline against /.well-known/jwks.json. PORTAL_ISSUER sets the
+// tokens' issuer, typically the portal's public URL. Without a key the
+// portal generates one at startup, and its tokens stop verifying once it
+// restarts.
+//
+// Every PORTAL_RENEW_INTERVAL (default 1h) the portal marks certificates
+// past their expiry as expired, and re-runs the suite for requests that set
+// auto_renew once their certificate is within PORTAL_RENEW_BEFORE (default
+// 336h) of expiry. Requests with a callback_url are sent a signed webhook
+// whenever their certificate's status changes.
+//
+// The portal also serves web pages: a leaderboard at /, certificate pages,
+// a verification page and an upload form for reports produced locally,
+// which issues unsigned certificates marked self-reported. The form needs
+// an API key, so it is off unless PORTAL_API_KEYS is set. Failing tests
+// link to their spec_ref sections under PORTAL_SPEC_URL (default the spec
+// repository on GitHub).
+//
+// Implementations can also submit signed v1.1 reports of their own runs to
+// POST /api/reports, which issues self-attested certificates. Reports must
+// list every test of the suite in PORTAL_SUITES up to the level they reach.
+// PORTAL_REPORT_TRUSTED_KEYS is a comma-separated list of public keys whose
+// reports are accepted (default: any valid signature).
+//
+// Setting PORTAL_LOG_FILE also serves a transparency log of signed
+// conformance reports under /api/log, stored in that file. PORTAL_LOG_KEY
+// names an Ed25519 private key that signs tree heads, and
+// PORTAL_LOG_TRUSTED_KEYS a comma-separated list of public keys whose
+// reports are accepted (default: any valid signature). Submissions to the
+// log pass the same API key and rate limit checks as POST /api/certify.
 //
 // Usage:
 //
 //	go run ./cmd/portal
 //	go run ./cmd/portal -addr :8090
+//	go run ./cmd/portal -store file:./certificates.json
+//	go run -tags sqlite ./cmd/portal -store sqlite:./portal.db
 package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/openjobspec/ojs-conformance/badge"
+	"github.com/openjobspec/ojs-conformance/lib"
+	"github.com/openjobspec/ojs-conformance/tlog"
 )
 
 func main() {
-	addr := ":8090"
-	if v := os.Getenv("PORTAL_ADDR"); v != "" {
-		addr = v
+	addr := flag.String("addr", envOr("PORTAL_ADDR", ":8090"), "Listen address")
+	storeSpec := flag.String("store", envOr("PORTAL_STORE", "memory"), "Certificate storage: memory, file:<path> or sqlite:<path>")
+	flag.Parse()
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	storage, err := badge.OpenStorage(*storeSpec)
+	if err != nil {
+		logger.Error("certificate storage", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
-	if len(os.Args) > 2 && os.Args[1] == "-addr" {
-		addr = os.Args[2]
+	defer storage.Close()
+	logger.Info("certificate storage open", slog.String("store", *storeSpec))
+
+	signingKey, err := loadSigningKey(logger)
+	if err != nil {
+		logger.Error("certificate signing key", slog.String("error", err.Error()))
+		os.Exit(1)
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	access, err := loadAccessConfig()
+	if err != nil {
+		logger.Error("access configuration", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	reportKeys, err := loadVerifyKeys(os.Getenv("PORTAL_REPORT_TRUSTED_KEYS"))
+	if err != nil {
+		logger.Error("report keys", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+
+	suitesDir := "./suites"
+	if v := os.Getenv("PORTAL_SUITES"); v != "" {
+		suitesDir = v
+	}
+	suite, err := lib.LoadTests(suitesDir, lib.LoadOptions{})
+	if err == nil && len(suite) == 0 {
+		err = fmt.Errorf("no tests in %s", suitesDir)
+	}
+	if err != nil {
+		logger.Warn("signed reports are refused: the suite could not be loaded", slog.String("suites", suitesDir), slog.String("error", err.Error()))
+	}
 
-	portal := badge.NewPortal()
+	webhooks := badge.NewWebhookNotifier()
+	webhooks.Client = access.Targets.Client(10 * time.Second)
+	webhooks.OnError = func(url string, n badge.Notification, err error) {
+		logger.Warn("webhook delivery failed", slog.String("certificate_id", n.CertificateID),
+			slog.String("status", n.Status), slog.String("error", err.Error()))
+	}
+	portal := badge.NewPortalWithConfig(badge.PortalConfig{
+		Storage:    storage,
+		AdminToken: os.Getenv("PORTAL_ADMIN_TOKEN"),
+		SigningKey: signingKey,
+		Issuer:     envOr("PORTAL_ISSUER", "ojs-conformance-portal"),
+		Webhooks:   webhooks,
+
+		APIKeys:      access.APIKeys,
+		KeyRateLimit: access.KeyRateLimit,
+		IPRateLimit:  access.IPRateLimit,
+		MaxInFlight:  access.MaxInFlight,
+		Targets:      access.Targets,
+		TrustProxy:   access.TrustProxy,
+
+		SpecBaseURL: os.Getenv("PORTAL_SPEC_URL"),
+		ReportKeys:  reportKeys,
+		Suite:       suite,
+	})
+	logger.Info("certification access",
+		slog.Int("api_keys", len(access.APIKeys)),
+		slog.String("key_rate_limit", access.KeyRateLimit.String()),
+		slog.String("ip_rate_limit", access.IPRateLimit.String()),
+		slog.Int("max_in_flight", access.MaxInFlight))
 	mux := http.NewServeMux()
 	portal.RegisterRoutes(mux)
 
+	if path := os.Getenv("PORTAL_LOG_FILE"); path != "" {
+		log, err := openLog(path)
+		if err != nil {
+			logger.Error("transparency log", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
+		defer log.Close()
+		h := tlog.NewHandler(log)
+		h.Admit = portal.Admit
+		h.RegisterRoutes(mux)
+		if len(access.APIKeys) == 0 && os.Getenv("PORTAL_LOG_TRUSTED_KEYS") == "" {
+			logger.Warn("transparency log accepts reports signed by any key from any client; set PORTAL_API_KEYS or PORTAL_LOG_TRUSTED_KEYS")
+		}
+		logger.Info("transparency log open", slog.String("path", path), slog.Int64("tree_size", log.Size()))
+	}
+
 	// Health endpoint
 	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -44,7 +184,7 @@ func main() {
 	})
 
 	srv := &http.Server{
-		Addr:         addr,
+		Addr:         *addr,
 		Handler:      mux,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 60 * time.Second,
@@ -54,8 +194,37 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	workers := 1
+	if v := os.Getenv("PORTAL_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Error("PORTAL_WORKERS must be a positive integer", slog.String("value", v))
+			os.Exit(1)
+		}
+		workers = n
+	}
+	if err := portal.StartRunner(ctx, badge.SuiteRunner(suitesDir, access.Targets.Client(30*time.Second)), workers); err != nil {
+		logger.Warn("pending certificates", slog.String("error", err.Error()))
+	}
+	logger.Info("certification runner started", slog.String("suites", suitesDir), slog.Int("workers", workers))
+
+	renewInterval, err := envDuration("PORTAL_RENEW_INTERVAL", time.Hour)
+	if err != nil {
+		logger.Error("renewals", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	renewBefore, err := envDuration("PORTAL_RENEW_BEFORE", 14*24*time.Hour)
+	if err != nil {
+		logger.Error("renewals", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
+	portal.StartRenewals(ctx, renewInterval, renewBefore, func(err error) {
+		logger.Warn("renewal check failed", slog.String("error", err.Error()))
+	})
+	logger.Info("renewal scheduler started", slog.Duration("interval", renewInterval), slog.Duration("renew_before", renewBefore))
+
 	go func() {
-		logger.Info("portal starting", slog.String("addr", addr))
+		logger.Info("portal starting", slog.String("addr", *addr))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("server error", slog.String("error", err.Error()))
 			os.Exit(1)
@@ -71,6 +240,131 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		logger.Error("shutdown error", slog.String("error", err.Error()))
 	}
+	// Webhook deliveries stop with ctx; wait for them to report.
+	if err := portal.WaitDeliveries(shutdownCtx); err != nil {
+		logger.Error("webhook deliveries still running", slog.String("error", err.Error()))
+	}
 
 	fmt.Println("portal stopped")
 }
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// loadAccessConfig reads the PORTAL_* variables that control who may
+// request certification and which networks runs may reach.
+func loadAccessConfig() (badge.PortalConfig, error) {
+	var cfg badge.PortalConfig
+	for _, key := range strings.Split(os.Getenv("PORTAL_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			cfg.APIKeys = append(cfg.APIKeys, key)
+		}
+	}
+
+	var err error
+	if cfg.KeyRateLimit, err = badge.ParseRateLimit(envOr("PORTAL_RATE_LIMIT_KEY", "60/h")); err != nil {
+		return cfg, fmt.Errorf("PORTAL_RATE_LIMIT_KEY: %w", err)
+	}
+	if cfg.IPRateLimit, err = badge.ParseRateLimit(envOr("PORTAL_RATE_LIMIT_IP", "10/h")); err != nil {
+		return cfg, fmt.Errorf("PORTAL_RATE_LIMIT_IP: %w", err)
+	}
+	v := envOr("PORTAL_MAX_IN_FLIGHT", "2")
+	if cfg.MaxInFlight, err = strconv.Atoi(v); err != nil || cfg.MaxInFlight < 0 {
+		return cfg, fmt.Errorf("PORTAL_MAX_IN_FLIGHT must be a non-negative integer, got %q", v)
+	}
+	cfg.TrustProxy, _ = strconv.ParseBool(os.Getenv("PORTAL_TRUST_PROXY"))
+
+	cfg.Targets = badge.DefaultTargetPolicy()
+	allow, err := badge.ParseNetworks(os.Getenv("PORTAL_ALLOW_NETWORKS"))
+	if err != nil {
+		return cfg, fmt.Errorf("PORTAL_ALLOW_NETWORKS: %w", err)
+	}
+	deny, err := badge.ParseNetworks(os.Getenv("PORTAL_DENY_NETWORKS"))
+	if err != nil {
+		return cfg, fmt.Errorf("PORTAL_DENY_NETWORKS: %w", err)
+	}
+	cfg.Targets.Allow = allow
+	cfg.Targets.Deny = append(cfg.Targets.Deny, deny...)
+	return cfg, nil
+}
+
+// envDuration reads a positive duration such as "1h" from the environment.
+func envDuration(name string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 1h, got %q", name, v)
+	}
+	return d, nil
+}
+
+// loadSigningKey reads PORTAL_SIGNING_KEY, or generates a key for this
+// process if it is unset.
+func loadSigningKey(logger *slog.Logger) (ed25519.PrivateKey, error) {
+	if path := os.Getenv("PORTAL_SIGNING_KEY"); path != "" {
+		key, err := lib.LoadSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("certificate signing key loaded", slog.String("key_id", lib.KeyID(key.Public().(ed25519.PublicKey))))
+		return key, nil
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	logger.Warn("PORTAL_SIGNING_KEY not set; certificate tokens will not verify after a restart",
+		slog.String("key_id", lib.KeyID(key.Public().(ed25519.PublicKey))))
+	return key, nil
+}
+
+// loadVerifyKeys loads the public keys in a comma-separated list of paths.
+func loadVerifyKeys(paths string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, keyPath := range strings.Split(paths, ",") {
+		if keyPath = strings.TrimSpace(keyPath); keyPath == "" {
+			continue
+		}
+		pub, err := lib.LoadVerifyKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, pub)
+	}
+	return keys, nil
+}
+
+// openLog opens the transparency log configured by the PORTAL_LOG_* variables.
+func openLog(path string) (*tlog.Log, error) {
+	var cfg tlog.Config
+	if keyPath := os.Getenv("PORTAL_LOG_KEY"); keyPath != "" {
+		key, err := lib.LoadSigningKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.SigningKey = key
+	}
+	trusted, err := loadVerifyKeys(os.Getenv("PORTAL_LOG_TRUSTED_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TrustedKeys = trusted
+
+	store, err := tlog.OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	log, err := tlog.Open(store, cfg)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return log, nil
+}
@@ -400,6 +400,9 @@ func matchObjectAssertion(expected map[string]json.RawMessage, actual any) error
 	if rangeRaw, ok := expected["range"]; ok {
 		return matchRangeAssertion(rangeRaw, actual)
 	}
+	if isOperatorObject(expected) {
+		return matchOperators(expected, actual)
+	}
 
 	obj, ok := actual.(map[string]any)
 	if !ok {
@@ -438,16 +441,16 @@ func matchExistsAssertion(expected map[string]json.RawMessage, actual any) error
 		return fmt.Errorf("expected field to not exist, but got %T: %v", actual, actual)
 	}
 
-	// Check $type if present
-	if typeRaw, ok := expected["$type"]; ok {
-		var expectedType string
-		if err := json.Unmarshal(typeRaw, &expectedType); err == nil {
-			actualType := jsonType(actual)
-			if actualType != expectedType {
-				return fmt.Errorf("expected type %q, got %q", expectedType, actualType)
-			}
+	// Apply any $type or comparison operators given alongside $exists
+	ops := make(map[string]json.RawMessage)
+	for key, val := range expected {
+		if operatorNames[key] {
+			ops[key] = val
 		}
 	}
+	if len(ops) > 0 {
+		return matchOperators(ops, actual)
+	}
 
 	return nil
 }
@@ -506,16 +509,11 @@ func matchSizeAssertion(expected map[string]json.RawMessage, actual any) error {
 	}
 
 	var sizeObj map[string]json.RawMessage
-	if err := json.Unmarshal(expected["$size"], &sizeObj); err == nil {
-		if gteRaw, ok := sizeObj["$gte"]; ok {
-			var gte int
-			if err := json.Unmarshal(gteRaw, &gte); err == nil {
-				if len(arr) < gte {
-					return fmt.Errorf("expected array of size >= %d, got %d", gte, len(arr))
-				}
-				return nil
-			}
+	if err := json.Unmarshal(expected["$size"], &sizeObj); err == nil && isOperatorObject(sizeObj) {
+		if err := matchOperators(sizeObj, float64(len(arr))); err != nil {
+			return fmt.Errorf("array size: %w", err)
 		}
+		return nil
 	}
 
 	return fmt.Errorf("unsupported $size format: %s", string(expected["$size"]))
@@ -537,6 +535,185 @@ func matchOrAssertion(expected map[string]json.RawMessage, actual any) error {
 	return fmt.Errorf("value %s did not match any $or alternative", string(b))
 }
 
+// operatorNames lists the object operators that can be combined in a single
+// matcher, e.g. {"$type": "number", "$gte": 1, "$lt": 10}. They are checked
+// in the order given by operatorOrder so failure messages are stable.
+var operatorNames = map[string]bool{
+	"$type": true,
+	"$eq":   true,
+	"$ne":   true,
+	"$gt":   true,
+	"$gte":  true,
+	"$lt":   true,
+	"$lte":  true,
+}
+
+var operatorOrder = []string{"$type", "$eq", "$ne", "$gt", "$gte", "$lt", "$lte"}
+
+var comparisonSymbols = map[string]string{"$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}
+
+// jsonTypeNames are the valid values for the $type operator.
+var jsonTypeNames = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"boolean": true,
+	"null":    true,
+	"array":   true,
+	"object":  true,
+}
+
+// isOperatorObject reports whether every key of a matcher object is a
+// combinable operator. Objects with any other key are matched field by field.
+func isOperatorObject(expected map[string]json.RawMessage) bool {
+	if len(expected) == 0 {
+		return false
+	}
+	for key := range expected {
+		if !operatorNames[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchOperators applies each operator present in expected to actual.
+func matchOperators(expected map[string]json.RawMessage, actual any) error {
+	for _, op := range operatorOrder {
+		operand, ok := expected[op]
+		if !ok {
+			continue
+		}
+		var err error
+		switch op {
+		case "$type":
+			err = matchTypeOperator(operand, actual)
+		case "$eq":
+			err = matchEqualityOperator(operand, actual, true)
+		case "$ne":
+			err = matchEqualityOperator(operand, actual, false)
+		default:
+			err = matchComparisonOperator(op, operand, actual)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchTypeOperator(operand json.RawMessage, actual any) error {
+	var expectedType string
+	if err := json.Unmarshal(operand, &expectedType); err != nil || !jsonTypeNames[expectedType] {
+		return fmt.Errorf("invalid $type value: %s", string(operand))
+	}
+	if actualType := jsonType(actual); actualType != expectedType {
+		return fmt.Errorf("expected type %q, got %q", expectedType, actualType)
+	}
+	return nil
+}
+
+// matchEqualityOperator implements $eq (equal=true) and $ne (equal=false).
+// The operand is compared as a literal JSON value, not as a matcher string,
+// so {"$ne": "any"} means "not the string any".
+func matchEqualityOperator(operand json.RawMessage, actual any, equal bool) error {
+	var expected any
+	if err := json.Unmarshal(operand, &expected); err != nil {
+		return fmt.Errorf("invalid operand: %s", string(operand))
+	}
+	same := jsonEqual(expected, actual)
+	if equal && !same {
+		b, _ := json.Marshal(actual)
+		return fmt.Errorf("expected %s, got %s", string(operand), string(b))
+	}
+	if !equal && same {
+		return fmt.Errorf("expected value not equal to %s", string(operand))
+	}
+	return nil
+}
+
+// matchComparisonOperator implements $gt, $gte, $lt and $lte. Numbers are
+// compared numerically; strings (e.g. RFC 3339 timestamps) lexically.
+func matchComparisonOperator(op string, operand json.RawMessage, actual any) error {
+	symbol := comparisonSymbols[op]
+
+	var cmp int
+	var expectedNum float64
+	var expectedStr string
+	if err := json.Unmarshal(operand, &expectedNum); err == nil {
+		n, ok := toFloat64(actual)
+		if !ok {
+			return fmt.Errorf("expected number %s %v, got %T: %v", symbol, expectedNum, actual, actual)
+		}
+		switch {
+		case n < expectedNum:
+			cmp = -1
+		case n > expectedNum:
+			cmp = 1
+		}
+	} else if err := json.Unmarshal(operand, &expectedStr); err == nil {
+		s, ok := actual.(string)
+		if !ok {
+			return fmt.Errorf("expected string %s %q, got %T: %v", symbol, expectedStr, actual, actual)
+		}
+		cmp = strings.Compare(s, expectedStr)
+	} else {
+		return fmt.Errorf("invalid %s value: %s", op, string(operand))
+	}
+
+	var ok bool
+	switch op {
+	case "$gt":
+		ok = cmp > 0
+	case "$gte":
+		ok = cmp >= 0
+	case "$lt":
+		ok = cmp < 0
+	case "$lte":
+		ok = cmp <= 0
+	}
+	if !ok {
+		b, _ := json.Marshal(actual)
+		return fmt.Errorf("expected value %s %s, got %s", symbol, string(operand), string(b))
+	}
+	return nil
+}
+
+// jsonEqual compares two decoded JSON values, treating numbers of any Go
+// numeric type as equal when their float64 values are equal.
+func jsonEqual(a, b any) bool {
+	if an, ok := toFloat64(a); ok {
+		bn, ok := toFloat64(b)
+		return ok && an == bn
+	}
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, exists := bv[k]
+			if !exists || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
 func jsonType(v any) string {
 	switch v.(type) {
 	case string:
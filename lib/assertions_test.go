@@ -691,3 +691,112 @@ func TestJsonType(t *testing.T) {
 	}
 }
 
+
+// --- Type and comparison operators ---
+
+func TestMatchTypeOperator(t *testing.T) {
+	tests := []struct {
+		matcher string
+		val     any
+		ok      bool
+	}{
+		{`{"$type": "number"}`, 42.0, true},
+		{`{"$type": "number"}`, "42", false},
+		{`{"$type": "array"}`, []any{}, true},
+		{`{"$type": "object"}`, map[string]any{}, true},
+		{`{"$type": "null"}`, nil, true},
+		{`{"$type": "integer"}`, 42.0, false}, // unknown type name
+	}
+	for _, tc := range tests {
+		err := MatchAssertion(raw(tc.matcher), tc.val)
+		if tc.ok && err != nil {
+			t.Errorf("matcher=%s val=%v: unexpected error: %v", tc.matcher, tc.val, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("matcher=%s val=%v: expected error", tc.matcher, tc.val)
+		}
+	}
+}
+
+func TestMatchComparisonOperators(t *testing.T) {
+	tests := []struct {
+		matcher string
+		val     any
+		ok      bool
+	}{
+		{`{"$gte": 1}`, 1.0, true},
+		{`{"$gte": 1}`, 0.0, false},
+		{`{"$gt": 1}`, 1.0, false},
+		{`{"$gt": 1}`, 2.0, true},
+		{`{"$lte": 5}`, 5.0, true},
+		{`{"$lt": 5}`, 5.0, false},
+		{`{"$gte": 1, "$lt": 10}`, 9.0, true},
+		{`{"$gte": 1, "$lt": 10}`, 10.0, false},
+		{`{"$type": "number", "$gt": 0}`, 3.0, true},
+		{`{"$gte": 1}`, "2", false},
+		{`{"$lt": "2024-06-01T00:00:00Z"}`, "2024-01-01T00:00:00Z", true},
+		{`{"$lt": "2024-06-01T00:00:00Z"}`, "2024-07-01T00:00:00Z", false},
+	}
+	for _, tc := range tests {
+		err := MatchAssertion(raw(tc.matcher), tc.val)
+		if tc.ok && err != nil {
+			t.Errorf("matcher=%s val=%v: unexpected error: %v", tc.matcher, tc.val, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("matcher=%s val=%v: expected error", tc.matcher, tc.val)
+		}
+	}
+}
+
+func TestMatchEqualityOperators(t *testing.T) {
+	tests := []struct {
+		matcher string
+		val     any
+		ok      bool
+	}{
+		{`{"$eq": "active"}`, "active", true},
+		{`{"$eq": 3}`, 3.0, true},
+		{`{"$eq": {"a": [1, 2]}}`, map[string]any{"a": []any{1.0, 2.0}}, true},
+		{`{"$ne": "failed"}`, "active", true},
+		{`{"$ne": "failed"}`, "failed", false},
+		{`{"$ne": "any"}`, "active", true}, // operand is a literal, not a matcher
+		{`{"$ne": null}`, nil, false},
+		{`{"$ne": null}`, "x", true},
+	}
+	for _, tc := range tests {
+		err := MatchAssertion(raw(tc.matcher), tc.val)
+		if tc.ok && err != nil {
+			t.Errorf("matcher=%s val=%v: unexpected error: %v", tc.matcher, tc.val, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("matcher=%s val=%v: expected error", tc.matcher, tc.val)
+		}
+	}
+}
+
+func TestMatchComparisonOperators_Message(t *testing.T) {
+	err := MatchAssertion(raw(`{"$gte": 5}`), 3.0)
+	if err == nil || err.Error() != "expected value >= 5, got 3" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestMatchSizeAssertion_Range(t *testing.T) {
+	matcher := raw(`{"$size": {"$gt": 1, "$lte": 3}}`)
+	if err := MatchAssertion(matcher, []any{1.0, 2.0}); err != nil {
+		t.Fatalf("size 2 in (1,3] should pass, got: %v", err)
+	}
+	if err := MatchAssertion(matcher, []any{1.0}); err == nil {
+		t.Fatal("size 1 should fail $gt 1")
+	}
+}
+
+func TestMatchExistsAssertion_WithComparison(t *testing.T) {
+	matcher := raw(`{"$exists": true, "$gte": 1}`)
+	if err := MatchAssertion(matcher, 2.0); err != nil {
+		t.Fatalf("2 >= 1 should pass, got: %v", err)
+	}
+	if err := MatchAssertion(matcher, 0.0); err == nil {
+		t.Fatal("0 >= 1 should fail")
+	}
+}
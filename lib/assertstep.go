@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ActionAssert is the step action for request-free steps that evaluate
+// assertions against the results of earlier steps.
+const ActionAssert = "ASSERT"
+
+// IsAssert reports whether the step is an ASSERT step.
+func (s Step) IsAssert() bool {
+	return strings.EqualFold(s.Action, ActionAssert)
+}
+
+// AssertScope builds the document that ASSERT step body assertions are
+// resolved against. Each completed step appears under its ID:
+//
+//	{"steps": {"step-1": {"response": {"status": 201, "headers": {...}, "body": {...}, "duration_ms": 12}}}}
+//
+// so "$.steps.step-1.response.body.job.id" addresses the same value as the
+// template {{steps.step-1.response.body.job.id}}. Response bodies that are
+// not JSON objects (arrays, scalars) are included as decoded.
+func AssertScope(stepResults map[string]*StepResult) map[string]any {
+	steps := make(map[string]any, len(stepResults))
+	for id, sr := range stepResults {
+		if sr == nil {
+			continue
+		}
+		response := map[string]any{
+			"status":      float64(sr.StatusCode),
+			"duration_ms": float64(sr.DurationMs),
+		}
+		if sr.Parsed != nil {
+			response["body"] = sr.Parsed
+		} else if len(sr.Body) > 0 {
+			var body any
+			if json.Unmarshal(sr.Body, &body) == nil {
+				response["body"] = body
+			}
+		}
+		if len(sr.Headers) > 0 {
+			headers := make(map[string]any, len(sr.Headers))
+			for name := range sr.Headers {
+				headers[strings.ToLower(name)] = sr.Headers.Get(name)
+			}
+			response["headers"] = headers
+		}
+		steps[id] = map[string]any{"response": response}
+	}
+	return map[string]any{"steps": steps}
+}
+
+// CheckAssertStep reports assertions that an ASSERT step cannot evaluate.
+// Only body and body_absent apply, since there is no response of its own.
+func CheckAssertStep(step Step) []Failure {
+	a := step.Assertions
+	if a == nil || (len(a.Body) == 0 && len(a.BodyAbsent) == 0) {
+		return []Failure{{
+			StepID:  step.ID,
+			Message: "ASSERT step has no body or body_absent assertions",
+		}}
+	}
+
+	var unsupported []string
+	if len(a.Status) > 0 || len(a.StatusIn) > 0 {
+		unsupported = append(unsupported, "status")
+	}
+	if len(a.Headers) > 0 {
+		unsupported = append(unsupported, "headers")
+	}
+	if a.TimingMs != nil {
+		unsupported = append(unsupported, "timing_ms")
+	}
+	if len(a.BodyRaw) > 0 || len(a.BodyContains) > 0 {
+		unsupported = append(unsupported, "body_raw/body_contains")
+	}
+	if len(unsupported) > 0 {
+		return []Failure{{
+			StepID:  step.ID,
+			Message: fmt.Sprintf("ASSERT step does not support %s assertions; use $.steps.<id>.response paths in body", strings.Join(unsupported, ", ")),
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAssertScope(t *testing.T) {
+	stepResults := map[string]*StepResult{
+		"step-1": {
+			StepID:     "step-1",
+			StatusCode: 201,
+			Headers:    http.Header{"Content-Type": []string{"application/json"}},
+			Body:       json.RawMessage(`{"job":{"id":"a","created_at":"2026-01-01T00:00:00Z"}}`),
+			Parsed:     map[string]any{"job": map[string]any{"id": "a", "created_at": "2026-01-01T00:00:00Z"}},
+		},
+		"step-2": {
+			StepID:     "step-2",
+			StatusCode: 200,
+			Body:       json.RawMessage(`[{"id":"b"}]`),
+		},
+		"wait": {StepID: "wait"},
+	}
+	scope := AssertScope(stepResults)
+
+	tests := []struct {
+		path    string
+		matcher string
+	}{
+		{"$.steps.step-1.response.status", `201`},
+		{"$.steps.step-1.response.body.job.id", `"a"`},
+		{"$.steps.step-1.response.headers.content-type", `"application/json"`},
+		{"$.steps.step-2.response.body", `{"$size": 1}`},
+		{"$.steps.step-2.response.body[0].id", `"b"`},
+		{"$.steps.wait.response.body", `"absent"`},
+	}
+	for _, tt := range tests {
+		val, _ := ResolveJSONPath(tt.path, scope)
+		if err := MatchAssertion(json.RawMessage(tt.matcher), val); err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+		}
+	}
+}
+
+func TestCheckAssertStep(t *testing.T) {
+	ok := Step{ID: "a", Action: "ASSERT", Assertions: &Assertions{
+		Body: map[string]json.RawMessage{"$.steps.s.response.status": json.RawMessage(`200`)},
+	}}
+	if failures := CheckAssertStep(ok); len(failures) != 0 {
+		t.Errorf("expected no failures, got %+v", failures)
+	}
+
+	empty := Step{ID: "b", Action: "assert"}
+	if !empty.IsAssert() {
+		t.Error("expected action to match case-insensitively")
+	}
+	if failures := CheckAssertStep(empty); len(failures) != 1 {
+		t.Errorf("expected failure for ASSERT step without assertions, got %+v", failures)
+	}
+
+	status := ok
+	status.Assertions = &Assertions{Status: json.RawMessage(`200`), Body: ok.Assertions.Body}
+	if failures := CheckAssertStep(status); len(failures) != 1 {
+		t.Errorf("expected failure for status assertion, got %+v", failures)
+	}
+}
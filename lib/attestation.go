@@ -0,0 +1,295 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// SchemaVersionV11 is the report schema that carries the CTN attestation
+// fields (Commit, Environment, Backend, Submitter).
+const SchemaVersionV11 = "1.1"
+
+// Environment variables read by CaptureCommit and CaptureEnvironment, in
+// addition to the CI providers' own.
+const (
+	// EnvBackendRepo is the path of the backend's git checkout.
+	EnvBackendRepo = "OJS_BACKEND_REPO"
+	// EnvCommitSHA, EnvCommitBranch, EnvCommitTag and EnvCommitRepo set
+	// the commit explicitly, e.g. when the checkout is not available.
+	EnvCommitSHA    = "OJS_COMMIT_SHA"
+	EnvCommitBranch = "OJS_COMMIT_BRANCH"
+	EnvCommitTag    = "OJS_COMMIT_TAG"
+	EnvCommitRepo   = "OJS_COMMIT_REPO"
+	// EnvTags adds free-form environment tags: "region=eu,db=postgres16".
+	EnvTags = "OJS_ENV_TAGS"
+)
+
+// CI provider names used in EnvironmentInfo.CIProvider.
+const (
+	CIGitHubActions = "github-actions"
+	CIBuildkite     = "buildkite"
+	CIGitLab        = "gitlab"
+	CIJenkins       = "jenkins"
+)
+
+// CaptureCommit identifies the backend revision under test, or returns nil
+// if it cannot be determined. Sources, in order of precedence:
+//
+//  1. OJS_COMMIT_SHA and its siblings
+//  2. git metadata of the checkout at OJS_BACKEND_REPO
+//  3. the CI provider's commit variables
+//
+// Fields missing from a source are filled from the later ones as long as
+// they describe the same SHA.
+func CaptureCommit() *CommitInfo {
+	return captureCommit(os.Getenv, runGit)
+}
+
+// CaptureEnvironment describes the machine and CI job running the suite.
+func CaptureEnvironment() *EnvironmentInfo {
+	hostname, _ := os.Hostname()
+	return captureEnvironment(os.Getenv, hostname)
+}
+
+// gitFunc runs git with args in dir and returns its trimmed output.
+type gitFunc func(dir string, args ...string) (string, error)
+
+func runGit(dir string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func captureCommit(getenv func(string) string, git gitFunc) *CommitInfo {
+	sources := []*CommitInfo{
+		{
+			SHA:    getenv(EnvCommitSHA),
+			Branch: getenv(EnvCommitBranch),
+			Tag:    getenv(EnvCommitTag),
+			Repo:   normalizeRepo(getenv(EnvCommitRepo)),
+		},
+	}
+	if dir := getenv(EnvBackendRepo); dir != "" {
+		sources = append(sources, gitCommit(dir, git))
+	}
+	sources = append(sources, ciCommit(getenv))
+
+	var info *CommitInfo
+	for _, src := range sources {
+		if src == nil || src.SHA == "" {
+			continue
+		}
+		if info == nil {
+			c := *src
+			info = &c
+			continue
+		}
+		if src.SHA != info.SHA {
+			continue
+		}
+		if info.Branch == "" {
+			info.Branch = src.Branch
+		}
+		if info.Tag == "" {
+			info.Tag = src.Tag
+		}
+		if info.Repo == "" {
+			info.Repo = src.Repo
+		}
+		if info.CommitTS == "" {
+			info.CommitTS = src.CommitTS
+		}
+		info.Dirty = info.Dirty || src.Dirty
+	}
+	return info
+}
+
+// gitCommit reads HEAD of the checkout at dir. It returns nil if dir is
+// not a git repository.
+func gitCommit(dir string, git gitFunc) *CommitInfo {
+	sha, err := git(dir, "rev-parse", "HEAD")
+	if err != nil || sha == "" {
+		return nil
+	}
+	info := &CommitInfo{SHA: sha}
+	if branch, err := git(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		info.Branch = branch
+	}
+	if tag, err := git(dir, "describe", "--tags", "--exact-match", "HEAD"); err == nil {
+		info.Tag = tag
+	}
+	if url, err := git(dir, "config", "--get", "remote.origin.url"); err == nil {
+		info.Repo = normalizeRepo(url)
+	}
+	if ts, err := git(dir, "log", "-1", "--format=%cI", "HEAD"); err == nil {
+		info.CommitTS = ts
+	}
+	if status, err := git(dir, "status", "--porcelain", "--untracked-files=no"); err == nil && status != "" {
+		info.Dirty = true
+	}
+	return info
+}
+
+// ciCommit reads the commit from the CI provider's variables.
+func ciCommit(getenv func(string) string) *CommitInfo {
+	switch detectCI(getenv) {
+	case CIGitHubActions:
+		info := &CommitInfo{SHA: getenv("GITHUB_SHA")}
+		if getenv("GITHUB_REF_TYPE") == "tag" {
+			info.Tag = getenv("GITHUB_REF_NAME")
+		} else if head := getenv("GITHUB_HEAD_REF"); head != "" {
+			info.Branch = head // pull request source branch
+		} else {
+			info.Branch = getenv("GITHUB_REF_NAME")
+		}
+		if repo := getenv("GITHUB_REPOSITORY"); repo != "" {
+			info.Repo = normalizeRepo(strings.TrimSuffix(orDefault(getenv("GITHUB_SERVER_URL"), "https://github.com"), "/") + "/" + repo)
+		}
+		return info
+	case CIBuildkite:
+		return &CommitInfo{
+			SHA:    getenv("BUILDKITE_COMMIT"),
+			Branch: getenv("BUILDKITE_BRANCH"),
+			Tag:    getenv("BUILDKITE_TAG"),
+			Repo:   normalizeRepo(getenv("BUILDKITE_REPO")),
+		}
+	case CIGitLab:
+		return &CommitInfo{
+			SHA:    getenv("CI_COMMIT_SHA"),
+			Branch: orDefault(getenv("CI_COMMIT_BRANCH"), getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME")),
+			Tag:    getenv("CI_COMMIT_TAG"),
+			Repo:   normalizeRepo(getenv("CI_PROJECT_URL")),
+		}
+	case CIJenkins:
+		return &CommitInfo{
+			SHA:    getenv("GIT_COMMIT"),
+			Branch: strings.TrimPrefix(getenv("GIT_BRANCH"), "origin/"),
+			Tag:    getenv("TAG_NAME"),
+			Repo:   normalizeRepo(getenv("GIT_URL")),
+		}
+	}
+	return nil
+}
+
+// detectCI returns the CI provider running the process, or "".
+func detectCI(getenv func(string) string) string {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHubActions
+	case getenv("BUILDKITE") == "true":
+		return CIBuildkite
+	case getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	case getenv("JENKINS_URL") != "":
+		return CIJenkins
+	}
+	return ""
+}
+
+// ciRunURL links to the CI job or build that ran the suite.
+func ciRunURL(provider string, getenv func(string) string) string {
+	switch provider {
+	case CIGitHubActions:
+		server, repo, run := orDefault(getenv("GITHUB_SERVER_URL"), "https://github.com"), getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID")
+		if repo == "" || run == "" {
+			return ""
+		}
+		return strings.TrimSuffix(server, "/") + "/" + repo + "/actions/runs/" + run
+	case CIBuildkite:
+		return getenv("BUILDKITE_BUILD_URL")
+	case CIGitLab:
+		return orDefault(getenv("CI_JOB_URL"), getenv("CI_PIPELINE_URL"))
+	case CIJenkins:
+		return getenv("BUILD_URL")
+	}
+	return ""
+}
+
+func captureEnvironment(getenv func(string) string, hostname string) *EnvironmentInfo {
+	provider := detectCI(getenv)
+	env := &EnvironmentInfo{
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		GoVersion:  runtime.Version(),
+		Hostname:   hostname,
+		CIProvider: provider,
+		CIRunURL:   ciRunURL(provider, getenv),
+	}
+	for _, pair := range strings.Split(getenv(EnvTags), ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if env.Tags == nil {
+			env.Tags = make(map[string]string)
+		}
+		env.Tags[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return env
+}
+
+// normalizeRepo turns a clone URL into the "host/owner/name" form used in
+// CommitInfo.Repo, dropping the scheme, credentials and ".git" suffix:
+// "git@github.com:acme/ojs-backend.git" and
+// "https://token@github.com/acme/ojs-backend" both become
+// "github.com/acme/ojs-backend".
+func normalizeRepo(url string) string {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return ""
+	}
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	} else if at, colon := strings.Index(url, "@"), strings.Index(url, ":"); at >= 0 && colon > at {
+		// scp-like syntax: git@host:owner/name
+		url = url[:colon] + "/" + url[colon+1:]
+	}
+	if at := strings.Index(url, "@"); at >= 0 && at < strings.Index(url+"/", "/") {
+		url = url[at+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// WriteReportFile writes the report as indented JSON to path atomically:
+// it is written to a temporary file in the same directory, synced and then
+// renamed over path, so readers never see a partial report.
+func WriteReportFile(path string, report SuiteReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
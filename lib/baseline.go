@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Statuses assigned by Baseline.Apply to tests listed in a baseline.
+const (
+	// StatusXFail is a listed test that failed as expected.
+	StatusXFail = "xfail"
+	// StatusXPass is a listed test that passed; its entry can be removed.
+	StatusXPass = "xpass"
+)
+
+// Baseline lists accepted failures so that a run only fails on regressions.
+//
+//	{
+//	  "known_failures": [
+//	    {"test_id": "L1-RET-003", "fields": ["status"], "reason": "#123", "expires": "2026-12-31"}
+//	  ]
+//	}
+type Baseline struct {
+	KnownFailures []KnownFailure `json:"known_failures"`
+}
+
+// KnownFailure is one accepted failure.
+type KnownFailure struct {
+	TestID string `json:"test_id"`
+	// Fields, if set, restricts the entry to failures on these Failure
+	// fields (e.g. "status", "$.state"). A test that also fails on any
+	// other field is a regression.
+	Fields []string `json:"fields,omitempty"`
+	Reason string   `json:"reason,omitempty"`
+	// Expires is a YYYY-MM-DD date from which the entry no longer applies.
+	Expires string `json:"expires,omitempty"`
+}
+
+// baselineDateLayout is the layout of KnownFailure.Expires.
+const baselineDateLayout = "2006-01-02"
+
+// LoadBaseline reads and validates a baseline file.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var b Baseline
+	if err := dec.Decode(&b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, kf := range b.KnownFailures {
+		if kf.TestID == "" {
+			return nil, fmt.Errorf("%s: known_failures[%d]: test_id is required", path, i)
+		}
+		if seen[kf.TestID] {
+			return nil, fmt.Errorf("%s: known_failures[%d]: duplicate test_id %q", path, i, kf.TestID)
+		}
+		seen[kf.TestID] = true
+		if kf.Expires != "" {
+			if _, err := time.Parse(baselineDateLayout, kf.Expires); err != nil {
+				return nil, fmt.Errorf("%s: known_failures[%d]: expires must be YYYY-MM-DD, got %q", path, i, kf.Expires)
+			}
+		}
+	}
+	return &b, nil
+}
+
+// expired reports whether the entry no longer applies at now.
+func (kf KnownFailure) expired(now time.Time) bool {
+	if kf.Expires == "" {
+		return false
+	}
+	t, err := time.Parse(baselineDateLayout, kf.Expires)
+	return err == nil && !now.UTC().Before(t)
+}
+
+// Expired returns the entries that no longer apply at now, for warnings.
+func (b *Baseline) Expired(now time.Time) []KnownFailure {
+	var out []KnownFailure
+	for _, kf := range b.KnownFailures {
+		if kf.expired(now) {
+			out = append(out, kf)
+		}
+	}
+	return out
+}
+
+// Apply returns a copy of results with listed tests reclassified: a failed
+// or errored test becomes "xfail" and a passing test "xpass". A listed test
+// that fails on a field the entry does not name keeps its status and gets
+// an extra failure explaining why it is a regression. Expired entries are
+// ignored.
+func (b *Baseline) Apply(results []TestResult, now time.Time) []TestResult {
+	known := make(map[string]KnownFailure)
+	for _, kf := range b.KnownFailures {
+		if !kf.expired(now) {
+			known[kf.TestID] = kf
+		}
+	}
+
+	out := make([]TestResult, len(results))
+	for i, r := range results {
+		out[i] = r
+		kf, ok := known[r.TestID]
+		if !ok {
+			continue
+		}
+		switch r.Status {
+		case "pass":
+			out[i].Status = StatusXPass
+		case "fail", "error":
+			if unexpected := kf.unexpectedFields(r.Failures); len(unexpected) > 0 {
+				out[i].Failures = append(append([]Failure(nil), r.Failures...), Failure{
+					Field:    "baseline",
+					Expected: strings.Join(kf.Fields, ", "),
+					Actual:   strings.Join(unexpected, ", "),
+					Message:  fmt.Sprintf("Known failure, but also failed on unlisted field(s): %s", strings.Join(unexpected, ", ")),
+				})
+				continue
+			}
+			out[i].Status = StatusXFail
+		}
+	}
+	return out
+}
+
+// unexpectedFields returns the failure fields the entry does not name,
+// sorted. Every field is expected if the entry names none.
+func (kf KnownFailure) unexpectedFields(failures []Failure) []string {
+	if len(kf.Fields) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(kf.Fields))
+	for _, f := range kf.Fields {
+		allowed[f] = true
+	}
+	unexpected := make(map[string]bool)
+	for _, f := range failures {
+		if !allowed[f.Field] {
+			unexpected[f.Field] = true
+		}
+	}
+	return sortedKeys(unexpected)
+}
+
+// Regressions is the number of tests that failed or errored without being
+// covered by a baseline.
+func (s ResultsSummary) Regressions() int {
+	return s.Failed + s.Errored
+}
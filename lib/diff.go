@@ -0,0 +1,241 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// DiffOptions tunes DiffReports.
+type DiffOptions struct {
+	// DurationThresholdPct is how much slower, in percent, a test must get
+	// to count as a duration regression.
+	DurationThresholdPct float64
+	// MinDurationDeltaMs ignores slowdowns smaller than this, so that fast
+	// tests do not flag on noise.
+	MinDurationDeltaMs int64
+}
+
+// DefaultDiffOptions returns the thresholds used by `ojs-conformance diff`.
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{DurationThresholdPct: 50, MinDurationDeltaMs: 100}
+}
+
+// ReportDiff is the difference between two conformance reports.
+type ReportDiff struct {
+	Old DiffRun `json:"old"`
+	New DiffRun `json:"new"`
+
+	// Complete is false when either report lacks per-test outcomes
+	// (reports written before SuiteReport.Tests existed). Tests are then
+	// only known from the failure and skip lists, so added and removed
+	// tests and duration changes cannot be computed.
+	Complete bool `json:"complete"`
+
+	NewlyFailing        []TestChange     `json:"newly_failing"`
+	NewlyPassing        []TestChange     `json:"newly_passing"`
+	Added               []TestOutcome    `json:"added"`
+	Removed             []TestOutcome    `json:"removed"`
+	Levels              []LevelChange    `json:"levels"`
+	DurationRegressions []DurationChange `json:"duration_regressions"`
+}
+
+// DiffRun identifies one side of a diff.
+type DiffRun struct {
+	Target          string `json:"target"`
+	RunAt           string `json:"run_at"`
+	Conformant      bool   `json:"conformant"`
+	ConformantLevel int    `json:"conformant_level"`
+	Total           int    `json:"total"`
+	Passed          int    `json:"passed"`
+}
+
+// TestChange is a test whose outcome changed between the runs.
+type TestChange struct {
+	TestID    string `json:"test_id"`
+	Name      string `json:"name"`
+	Level     int    `json:"level"`
+	OldStatus string `json:"old_status"`
+	NewStatus string `json:"new_status"`
+}
+
+// LevelChange is a conformance level whose summary changed.
+type LevelChange struct {
+	Level int          `json:"level"`
+	Old   LevelSummary `json:"old"`
+	New   LevelSummary `json:"new"`
+}
+
+// DurationChange is a test that got slower than the thresholds allow.
+type DurationChange struct {
+	TestID   string  `json:"test_id"`
+	Name     string  `json:"name"`
+	OldMs    int64   `json:"old_ms"`
+	NewMs    int64   `json:"new_ms"`
+	DeltaPct float64 `json:"delta_pct"`
+}
+
+// LoadReport reads a conformance report written with -output json or
+// -report-file.
+func LoadReport(path string) (SuiteReport, error) {
+	var report SuiteReport
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
+
+// ConformantLevelChanged reports whether the runs reached different levels.
+func (d ReportDiff) ConformantLevelChanged() bool {
+	return d.Old.ConformantLevel != d.New.ConformantLevel
+}
+
+// Regressed reports whether the new run is worse: a test started failing
+// or the conformant level dropped.
+func (d ReportDiff) Regressed() bool {
+	return len(d.NewlyFailing) > 0 || d.New.ConformantLevel < d.Old.ConformantLevel
+}
+
+// isFailing reports whether a status counts as failing for a diff. Known
+// failures still fail.
+func isFailing(status string) bool {
+	return status == "fail" || status == "error" || status == StatusXFail
+}
+
+func isPassing(status string) bool {
+	return status == "pass" || status == StatusXPass
+}
+
+// reportOutcomes returns the report's tests by ID, in the order they first
+// appear, and whether the list is complete.
+func reportOutcomes(r SuiteReport) (map[string]TestOutcome, []string, bool) {
+	byID := make(map[string]TestOutcome)
+	var order []string
+	add := func(o TestOutcome) {
+		if _, ok := byID[o.TestID]; !ok {
+			order = append(order, o.TestID)
+		}
+		byID[o.TestID] = o
+	}
+	if len(r.Tests) > 0 {
+		for _, o := range r.Tests {
+			add(o)
+		}
+		return byID, order, true
+	}
+	for _, list := range [][]TestResult{r.Failures, r.Skipped} {
+		for _, o := range Outcomes(list) {
+			add(o)
+		}
+	}
+	return byID, order, false
+}
+
+// DiffReports compares two runs of the suite, before and after a change.
+func DiffReports(before, after SuiteReport, opts DiffOptions) ReportDiff {
+	d := ReportDiff{
+		Old:                 diffRun(before),
+		New:                 diffRun(after),
+		NewlyFailing:        []TestChange{},
+		NewlyPassing:        []TestChange{},
+		Added:               []TestOutcome{},
+		Removed:             []TestOutcome{},
+		Levels:              []LevelChange{},
+		DurationRegressions: []DurationChange{},
+	}
+
+	oldTests, oldOrder, oldComplete := reportOutcomes(before)
+	newTests, newOrder, newComplete := reportOutcomes(after)
+	d.Complete = oldComplete && newComplete
+
+	for _, id := range newOrder {
+		n := newTests[id]
+		o, inOld := oldTests[id]
+		switch {
+		case !inOld && d.Complete:
+			d.Added = append(d.Added, n)
+			continue
+		case !inOld:
+			// Absent from an incomplete report's failure and skip lists:
+			// passing, or not run at all.
+			o = TestOutcome{Status: "pass"}
+		}
+		change := TestChange{TestID: id, Name: n.Name, Level: n.Level, OldStatus: o.Status, NewStatus: n.Status}
+		if isFailing(n.Status) && !isFailing(o.Status) {
+			d.NewlyFailing = append(d.NewlyFailing, change)
+		}
+		if isPassing(n.Status) && isFailing(o.Status) {
+			d.NewlyPassing = append(d.NewlyPassing, change)
+		}
+		if d.Complete && isPassing(o.Status) && isPassing(n.Status) {
+			if dc, slower := durationRegression(o, n, opts); slower {
+				d.DurationRegressions = append(d.DurationRegressions, dc)
+			}
+		}
+	}
+
+	for _, id := range oldOrder {
+		o := oldTests[id]
+		if _, inNew := newTests[id]; inNew {
+			continue
+		}
+		if d.Complete {
+			d.Removed = append(d.Removed, o)
+		} else if isFailing(o.Status) {
+			// The test no longer appears among the failures.
+			d.NewlyPassing = append(d.NewlyPassing, TestChange{
+				TestID: id, Name: o.Name, Level: o.Level, OldStatus: o.Status, NewStatus: "pass",
+			})
+		}
+	}
+
+	var levels []int
+	for lvl := range before.Results.ByLevel {
+		levels = append(levels, lvl)
+	}
+	for lvl := range after.Results.ByLevel {
+		if _, ok := before.Results.ByLevel[lvl]; !ok {
+			levels = append(levels, lvl)
+		}
+	}
+	sort.Ints(levels)
+	for _, lvl := range levels {
+		o, n := before.Results.ByLevel[lvl], after.Results.ByLevel[lvl]
+		if o != n {
+			d.Levels = append(d.Levels, LevelChange{Level: lvl, Old: o, New: n})
+		}
+	}
+
+	sort.SliceStable(d.DurationRegressions, func(i, j int) bool {
+		return d.DurationRegressions[i].DeltaPct > d.DurationRegressions[j].DeltaPct
+	})
+	return d
+}
+
+func diffRun(r SuiteReport) DiffRun {
+	return DiffRun{
+		Target:          r.Target,
+		RunAt:           r.RunAt,
+		Conformant:      r.Conformant,
+		ConformantLevel: r.ConformantLevel,
+		Total:           r.Results.Total,
+		Passed:          r.Results.Passed,
+	}
+}
+
+func durationRegression(o, n TestOutcome, opts DiffOptions) (DurationChange, bool) {
+	delta := n.DurationMs - o.DurationMs
+	if o.DurationMs <= 0 || delta < opts.MinDurationDeltaMs {
+		return DurationChange{}, false
+	}
+	pct := float64(delta) / float64(o.DurationMs) * 100
+	if pct < opts.DurationThresholdPct {
+		return DurationChange{}, false
+	}
+	return DurationChange{TestID: n.TestID, Name: n.Name, OldMs: o.DurationMs, NewMs: n.DurationMs, DeltaPct: pct}, true
+}
@@ -0,0 +1,104 @@
+package lib
+
+import (
+	"testing"
+)
+
+func diffReport(level int, tests ...TestOutcome) SuiteReport {
+	r := SuiteReport{ConformantLevel: level, Tests: tests, Results: ResultsSummary{ByLevel: map[int]LevelSummary{}}}
+	for _, t := range tests {
+		ls := r.Results.ByLevel[t.Level]
+		ls.Total++
+		if isPassing(t.Status) {
+			ls.Passed++
+		} else if isFailing(t.Status) {
+			ls.Failed++
+		}
+		r.Results.ByLevel[t.Level] = ls
+	}
+	return r
+}
+
+func TestDiffReports(t *testing.T) {
+	before := diffReport(1,
+		TestOutcome{TestID: "L0-ENV-001", Status: "pass", DurationMs: 100},
+		TestOutcome{TestID: "L0-ENV-002", Status: "fail", DurationMs: 100},
+		TestOutcome{TestID: "L1-RET-001", Status: "pass", DurationMs: 1000, Level: 1},
+		TestOutcome{TestID: "L1-RET-002", Status: "pass", DurationMs: 20, Level: 1},
+		TestOutcome{TestID: "L1-RET-003", Status: "pass", Level: 1},
+	)
+	after := diffReport(0,
+		TestOutcome{TestID: "L0-ENV-001", Status: "error", DurationMs: 100},
+		TestOutcome{TestID: "L0-ENV-002", Status: "pass", DurationMs: 100},
+		TestOutcome{TestID: "L1-RET-001", Status: "pass", DurationMs: 1600, Level: 1},
+		// Three times slower, but below the 100ms minimum delta.
+		TestOutcome{TestID: "L1-RET-002", Status: "pass", DurationMs: 60, Level: 1},
+		TestOutcome{TestID: "L1-RET-004", Status: "fail", Level: 1},
+	)
+
+	d := DiffReports(before, after, DefaultDiffOptions())
+	if !d.Complete {
+		t.Error("expected complete diff")
+	}
+	if len(d.NewlyFailing) != 1 || d.NewlyFailing[0].TestID != "L0-ENV-001" || d.NewlyFailing[0].NewStatus != "error" {
+		t.Errorf("unexpected newly failing: %+v", d.NewlyFailing)
+	}
+	if len(d.NewlyPassing) != 1 || d.NewlyPassing[0].TestID != "L0-ENV-002" {
+		t.Errorf("unexpected newly passing: %+v", d.NewlyPassing)
+	}
+	if len(d.Added) != 1 || d.Added[0].TestID != "L1-RET-004" {
+		t.Errorf("unexpected added: %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].TestID != "L1-RET-003" {
+		t.Errorf("unexpected removed: %+v", d.Removed)
+	}
+	if len(d.DurationRegressions) != 1 || d.DurationRegressions[0].TestID != "L1-RET-001" || d.DurationRegressions[0].DeltaPct != 60 {
+		t.Errorf("unexpected duration regressions: %+v", d.DurationRegressions)
+	}
+	// Level 0 still has one pass and one failure; level 1 gained a failure.
+	if len(d.Levels) != 1 || d.Levels[0].Level != 1 {
+		t.Errorf("unexpected level changes: %+v", d.Levels)
+	}
+	if !d.ConformantLevelChanged() || !d.Regressed() {
+		t.Error("expected conformant level drop to be a regression")
+	}
+}
+
+func TestDiffReportsLevels(t *testing.T) {
+	before := diffReport(0,
+		TestOutcome{TestID: "L0-ENV-001", Status: "pass"},
+		TestOutcome{TestID: "L5-EXT-001", Status: "pass", Level: 5},
+	)
+	after := diffReport(0,
+		TestOutcome{TestID: "L0-ENV-001", Status: "pass"},
+		TestOutcome{TestID: "L5-EXT-001", Status: "fail", Level: 5},
+		TestOutcome{TestID: "L99-EXT-001", Status: "pass", Level: 99},
+	)
+
+	d := DiffReports(before, after, DefaultDiffOptions())
+	if len(d.Levels) != 2 || d.Levels[0].Level != 5 || d.Levels[1].Level != 99 {
+		t.Fatalf("unexpected level changes: %+v", d.Levels)
+	}
+	if d.Levels[1].Old.Total != 0 || d.Levels[1].New.Passed != 1 {
+		t.Errorf("unexpected level 99 change: %+v", d.Levels[1])
+	}
+}
+
+func TestDiffReportsWithoutTestList(t *testing.T) {
+	before := SuiteReport{Failures: []TestResult{{TestID: "A", Status: "fail"}, {TestID: "B", Status: "fail"}}}
+	after := SuiteReport{Failures: []TestResult{{TestID: "B", Status: "fail"}, {TestID: "C", Status: "error"}}}
+
+	d := DiffReports(before, after, DefaultDiffOptions())
+	if d.Complete {
+		t.Error("expected incomplete diff")
+	}
+	if len(d.NewlyFailing) != 1 || d.NewlyFailing[0].TestID != "C" {
+		t.Errorf("unexpected newly failing: %+v", d.NewlyFailing)
+	}
+	if len(d.NewlyPassing) != 1 || d.NewlyPassing[0].TestID != "A" {
+		t.Errorf("unexpected newly passing: %+v", d.NewlyPassing)
+	}
+	if len(d.Added) != 0 || len(d.Removed) != 0 {
+		t.Errorf("expected no added or removed tests without a test list, got %+v / %+v", d.Added, d.Removed)
+	}
+}
@@ -0,0 +1,444 @@
+package lib
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Output formats accepted by the runners' -output flag. FormatTable is
+// rendered by the runners themselves; the others by Formatters.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+	FormatTAP   = "tap"
+	FormatSARIF = "sarif"
+)
+
+// Formatter writes the results of a run in one output format. results holds
+// every test in run order; report is the summary built from them.
+type Formatter func(w io.Writer, report SuiteReport, results []TestResult) error
+
+// Formatters maps format names to their Formatter.
+var Formatters = map[string]Formatter{
+	FormatJSON:  WriteJSON,
+	FormatJUnit: WriteJUnit,
+	FormatTAP:   WriteTAP,
+	FormatSARIF: WriteSARIF,
+}
+
+// Output is one destination parsed from the -output flag.
+type Output struct {
+	Format string
+	// Path is the file to write, or empty for stdout.
+	Path string
+}
+
+// ParseOutputs parses a comma-separated list of outputs such as
+// "table,junit=report.xml,sarif=ojs.sarif". A format without "=path" is
+// written to stdout; at most one output may go there, and table output can
+// only go there.
+func ParseOutputs(s string) ([]Output, error) {
+	var outputs []Output
+	stdout := ""
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		format, path, _ := strings.Cut(part, "=")
+		o := Output{Format: strings.TrimSpace(format), Path: strings.TrimSpace(path)}
+		if _, ok := Formatters[o.Format]; !ok && o.Format != FormatTable {
+			return nil, fmt.Errorf("unknown output format %q (want %s)", o.Format, strings.Join(FormatNames(), ", "))
+		}
+		if o.Format == FormatTable && o.Path != "" {
+			return nil, fmt.Errorf("table output can only be written to stdout")
+		}
+		if o.Path == "" {
+			if stdout != "" {
+				return nil, fmt.Errorf("both %s and %s write to stdout; give one of them a file with format=path", stdout, o.Format)
+			}
+			stdout = o.Format
+		}
+		outputs = append(outputs, o)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("no output format given")
+	}
+	return outputs, nil
+}
+
+// FormatNames returns every accepted format name, sorted.
+func FormatNames() []string {
+	names := []string{FormatTable}
+	for name := range Formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteOutput writes a non-table output to its file, or to stdout if it has
+// no path.
+func WriteOutput(o Output, report SuiteReport, results []TestResult) error {
+	format, ok := Formatters[o.Format]
+	if !ok {
+		return fmt.Errorf("unknown output format %q", o.Format)
+	}
+	if o.Path == "" {
+		return format(os.Stdout, report, results)
+	}
+	f, err := os.Create(o.Path)
+	if err != nil {
+		return err
+	}
+	if err := format(f, report, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteJSON writes the report as indented JSON.
+func WriteJSON(w io.Writer, report SuiteReport, _ []TestResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// failureText renders a failure as one line, the way the table output does,
+// with expected and actual values when the failure has them.
+func failureText(f Failure) string {
+	var b strings.Builder
+	if f.StepID != "" {
+		fmt.Fprintf(&b, "[%s] ", f.StepID)
+	}
+	b.WriteString(f.Message)
+	if f.Expected != "" || f.Actual != "" {
+		fmt.Fprintf(&b, " (expected %s, actual %s)", f.Expected, f.Actual)
+	}
+	return b.String()
+}
+
+func failuresText(failures []Failure) string {
+	lines := make([]string, len(failures))
+	for i, f := range failures {
+		lines[i] = failureText(f)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// firstMessage is the message of a test's first failure, or fallback.
+func firstMessage(r TestResult, fallback string) string {
+	if len(r.Failures) > 0 && r.Failures[0].Message != "" {
+		return r.Failures[0].Message
+	}
+	return fallback
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// JUnit XML, in the dialect understood by Jenkins, GitLab and most CI
+// dashboards.
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+
+	durationMs int64
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes JUnit XML with one testsuite per level and category,
+// ordered by level, then category.
+func WriteJUnit(w io.Writer, report SuiteReport, results []TestResult) error {
+	root := junitTestSuites{Name: "OJS Conformance", Time: seconds(report.DurationMs)}
+	index := make(map[string]int)
+	for _, r := range results {
+		name := fmt.Sprintf("L%d %s / %s", r.Level, LevelName(r.Level), r.Category)
+		i, ok := index[name]
+		if !ok {
+			i = len(root.Suites)
+			index[name] = i
+			root.Suites = append(root.Suites, junitTestSuite{Name: name, Timestamp: report.RunAt})
+		}
+		suite := &root.Suites[i]
+
+		tc := junitTestCase{
+			Name:      r.TestID + ": " + r.Name,
+			Classname: fmt.Sprintf("level-%d.%s", r.Level, r.Category),
+			Time:      seconds(r.DurationMs),
+			File:      r.FilePath,
+		}
+		msg := &junitMessage{Message: firstMessage(r, r.Status), Text: failuresText(r.Failures)}
+		switch r.Status {
+		case "fail":
+			msg.Type = "assertion"
+			tc.Failure = msg
+			suite.Failures++
+		case "error":
+			msg.Type = "error"
+			tc.Error = msg
+			suite.Errors++
+		case "skip":
+			tc.Skipped = &junitMessage{Message: firstMessage(r, "")}
+			suite.Skipped++
+		case StatusXFail:
+			// JUnit has no expected-failure element; report it as skipped
+			// so that dashboards do not count it as broken.
+			tc.Skipped = &junitMessage{Message: "known failure: " + firstMessage(r, ""), Text: failuresText(r.Failures)}
+			suite.Skipped++
+		}
+		suite.Tests++
+		suite.durationMs += r.DurationMs
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	sort.SliceStable(root.Suites, func(i, j int) bool { return root.Suites[i].Name < root.Suites[j].Name })
+	for i := range root.Suites {
+		s := &root.Suites[i]
+		s.Time = seconds(s.durationMs)
+		root.Tests += s.Tests
+		root.Failures += s.Failures
+		root.Errors += s.Errors
+		root.Skipped += s.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteTAP writes a TAP version 14 stream. Failed and errored tests carry a
+// YAML diagnostic block; skipped tests use the SKIP directive, and known
+// failures (xfail, xpass) the TODO directive.
+func WriteTAP(w io.Writer, report SuiteReport, results []TestResult) error {
+	var b strings.Builder
+	b.WriteString("TAP version 14\n")
+	fmt.Fprintf(&b, "1..%d\n", len(results))
+	for i, r := range results {
+		desc := tapEscape(r.TestID + " " + r.Name)
+		switch r.Status {
+		case "pass":
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, desc)
+		case "skip":
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, desc, tapEscape(firstMessage(r, "")))
+		case StatusXFail:
+			fmt.Fprintf(&b, "not ok %d - %s # TODO known failure: %s\n", i+1, desc, tapEscape(firstMessage(r, "")))
+		case StatusXPass:
+			fmt.Fprintf(&b, "ok %d - %s # TODO known failure now passes\n", i+1, desc)
+		default:
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, desc)
+			b.WriteString("  ---\n")
+			fmt.Fprintf(&b, "  message: %s\n", yamlString(firstMessage(r, r.Status)))
+			severity := "fail"
+			if r.Status == "error" {
+				severity = "error"
+			}
+			fmt.Fprintf(&b, "  severity: %s\n", severity)
+			fmt.Fprintf(&b, "  spec_ref: %s\n", yamlString(r.SpecRef))
+			fmt.Fprintf(&b, "  file: %s\n", yamlString(r.FilePath))
+			fmt.Fprintf(&b, "  duration_ms: %d\n", r.DurationMs)
+			if len(r.Failures) > 0 {
+				b.WriteString("  failures:\n")
+				for _, f := range r.Failures {
+					fmt.Fprintf(&b, "    - step_id: %s\n", yamlString(f.StepID))
+					fmt.Fprintf(&b, "      field: %s\n", yamlString(f.Field))
+					fmt.Fprintf(&b, "      expected: %s\n", yamlString(f.Expected))
+					fmt.Fprintf(&b, "      actual: %s\n", yamlString(f.Actual))
+					fmt.Fprintf(&b, "      message: %s\n", yamlString(f.Message))
+				}
+			}
+			b.WriteString("  ...\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tapEscape keeps a description on one line and escapes the characters TAP
+// gives meaning to.
+func tapEscape(s string) string {
+	s = strings.NewReplacer("\\", "\\\\", "#", "\\#", "\n", " ", "\r", " ").Replace(s)
+	return strings.TrimSpace(s)
+}
+
+// yamlString quotes s as a YAML double-quoted scalar. JSON string syntax is
+// a subset of it.
+func yamlString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// SARIF 2.1.0, with one rule per spec_ref and one result per failed,
+// errored or known-failing test.
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID        string          `json:"ruleId"`
+	Level         string          `json:"level"`
+	BaselineState string          `json:"baselineState,omitempty"`
+	Message       sarifMessage    `json:"message"`
+	Locations     []sarifLocation `json:"locations,omitempty"`
+	Properties    map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// WriteSARIF writes a SARIF 2.1.0 log. Each failed or errored test is a
+// result whose rule ID is the test's spec_ref (its test ID if it has none),
+// located at the suite file that defines it. Known failures are results of
+// level "note" with baselineState "unchanged".
+func WriteSARIF(w io.Writer, report SuiteReport, results []TestResult) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "ojs-conformance",
+			Version:        report.TestSuiteVersion,
+			InformationURI: "https://github.com/openjobspec/ojs-conformance",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	rules := make(map[string]bool)
+	for _, r := range results {
+		if r.Status != "fail" && r.Status != "error" && r.Status != StatusXFail {
+			continue
+		}
+		ruleID := r.SpecRef
+		if ruleID == "" {
+			ruleID = r.TestID
+		}
+		if !rules[ruleID] {
+			rules[ruleID] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               ruleID,
+				ShortDescription: sarifMessage{Text: "OJS specification " + ruleID},
+			})
+		}
+
+		res := sarifResult{
+			RuleID:  ruleID,
+			Level:   "error",
+			Message: sarifMessage{Text: fmt.Sprintf("%s %s: %s", r.TestID, r.Name, failuresText(r.Failures))},
+			Properties: map[string]any{
+				"test_id":  r.TestID,
+				"level":    r.Level,
+				"category": r.Category,
+				"status":   r.Status,
+			},
+		}
+		if r.FilePath != "" {
+			res.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: sarifURI(r.FilePath)},
+			}}}
+		}
+		if r.Status == StatusXFail {
+			res.Level = "note"
+			res.BaselineState = "unchanged"
+		}
+		run.Results = append(run.Results, res)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// sarifURI turns a suite file path into a relative URI with forward
+// slashes, as code-scanning tools expect.
+func sarifURI(path string) string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	return strings.TrimPrefix(path, "./")
+}
@@ -0,0 +1,131 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func formatTestResults() (SuiteReport, []TestResult) {
+	results := []TestResult{
+		{TestID: "L0-ENV-001", Name: "envelope ok", Level: 0, Category: "envelope", SpecRef: "ojs-core#section-5.2", Status: "pass", DurationMs: 12, FilePath: "suites/level-0/envelope/a.json"},
+		{TestID: "L0-ENV-002", Name: "missing # type", Level: 0, Category: "envelope", SpecRef: "ojs-core#section-5.2", Status: "fail", DurationMs: 30, FilePath: "suites/level-0/envelope/b.json",
+			Failures: []Failure{{StepID: "enqueue", Field: "status", Expected: "400", Actual: "201", Message: "Expected status 400, got 201"}}},
+		{TestID: "L1-RET-001", Name: "retry", Level: 1, Category: "retry", Status: "error", DurationMs: 5,
+			Failures: []Failure{{Field: "reset", Message: "State reset failed: boom"}}},
+		{TestID: "L0-ENV-003", Name: "skipped", Level: 0, Category: "envelope", Status: "skip",
+			Failures: []Failure{{Message: "requires extension"}}},
+	}
+	report := SuiteReport{TestSuiteVersion: "1.0", RunAt: "2026-10-16T00:00:00Z", DurationMs: 1500}
+	return report, results
+}
+
+func TestParseOutputs(t *testing.T) {
+	outputs, err := ParseOutputs("table, junit=out/junit.xml,sarif=ojs.sarif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Output{{Format: "table"}, {Format: "junit", Path: "out/junit.xml"}, {Format: "sarif", Path: "ojs.sarif"}}
+	if len(outputs) != len(want) {
+		t.Fatalf("got %+v", outputs)
+	}
+	for i := range want {
+		if outputs[i] != want[i] {
+			t.Errorf("output %d: got %+v, want %+v", i, outputs[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "xml", "table,json", "table=out.txt"} {
+		if _, err := ParseOutputs(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	report, results := formatTestResults()
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, report, results); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 4 || doc.Failures != 1 || doc.Errors != 1 || doc.Skipped != 1 || doc.Time != "1.500" {
+		t.Errorf("unexpected totals: %+v", doc)
+	}
+	if len(doc.Suites) != 2 || doc.Suites[0].Name != "L0 Core / envelope" || doc.Suites[1].Name != "L1 Reliable / retry" {
+		t.Fatalf("expected one suite per level and category, got %+v", doc.Suites)
+	}
+	env := doc.Suites[0]
+	if env.Tests != 3 || env.Time != "0.042" {
+		t.Errorf("unexpected envelope suite: %+v", env)
+	}
+	failed := env.Cases[1]
+	if failed.Failure == nil || failed.Failure.Message != "Expected status 400, got 201" {
+		t.Fatalf("expected failure element, got %+v", failed)
+	}
+	if failed.Failure.Text != "[enqueue] Expected status 400, got 201 (expected 400, actual 201)" {
+		t.Errorf("unexpected failure text: %q", failed.Failure.Text)
+	}
+	if env.Cases[2].Skipped == nil || doc.Suites[1].Cases[0].Error == nil {
+		t.Error("expected skipped and error elements")
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	report, results := formatTestResults()
+	var buf bytes.Buffer
+	if err := WriteTAP(&buf, report, results); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	for _, line := range []string{
+		"TAP version 14\n1..4\n",
+		"ok 1 - L0-ENV-001 envelope ok\n",
+		"not ok 2 - L0-ENV-002 missing \\# type\n  ---\n  message: \"Expected status 400, got 201\"\n  severity: fail\n",
+		"    - step_id: \"enqueue\"\n      field: \"status\"\n",
+		"not ok 3 - L1-RET-001 retry\n  ---\n  message: \"State reset failed: boom\"\n  severity: error\n",
+		"ok 4 - L0-ENV-003 skipped # SKIP requires extension\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, got)
+		}
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	report, results := formatTestResults()
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, report, results); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Results) != 2 {
+		t.Fatalf("expected results for the failed and errored tests only, got %+v", run.Results)
+	}
+	if r := run.Results[0]; r.RuleID != "ojs-core#section-5.2" || r.Level != "error" ||
+		r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "suites/level-0/envelope/b.json" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	// Without a spec_ref the test ID is the rule.
+	if run.Results[1].RuleID != "L1-RET-001" || run.Results[1].Locations != nil {
+		t.Errorf("unexpected result: %+v", run.Results[1])
+	}
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "L1-RET-001" {
+		t.Errorf("expected sorted rules, got %+v", run.Tool.Driver.Rules)
+	}
+}
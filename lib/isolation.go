@@ -0,0 +1,407 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TagGlobalState marks tests that observe or change server-wide state
+// (admin stats, cron schedules, schema registrations, ...). They never run
+// concurrently with other tests.
+const TagGlobalState = "global-state"
+
+// RunNamespaceTemplate is replaced with the test's run namespace wherever
+// templates are resolved, e.g. "queue": "orders-{{run.ns}}".
+const RunNamespaceTemplate = "{{run.ns}}"
+
+// defaultQueue is the queue jobs land in when no options.queue is given.
+const defaultQueue = "default"
+
+// RunNamespace returns the namespace for the test at position index in a
+// run. It is unique within the run even if test IDs are duplicated.
+func RunNamespace(tc TestCase, index int) string {
+	return fmt.Sprintf("%s-%d", strings.ToLower(tc.TestID), index)
+}
+
+// IsGlobalState reports whether the test is tagged global-state.
+func (tc TestCase) IsGlobalState() bool {
+	for _, tag := range tc.Tags {
+		if tag == TagGlobalState {
+			return true
+		}
+	}
+	return false
+}
+
+// Isolatable reports whether Isolate can keep the test from interfering
+// with tests running at the same time. That is not the case for tests
+// tagged global-state, or for tests that use the default queue, either by
+// name or by enqueueing or fetching without naming a queue.
+func (tc TestCase) Isolatable() bool {
+	if tc.IsGlobalState() {
+		return false
+	}
+	_, usesDefault := tc.isolatedNames()
+	return !usesDefault
+}
+
+// WithRunNamespace returns a copy of the test with {{run.ns}} replaced by ns
+// in step paths, headers, bodies and assertions.
+func (tc TestCase) WithRunNamespace(ns string) TestCase {
+	return tc.rewrite(newNameRewriter(ns, nil))
+}
+
+// Isolate returns a copy of the test in which {{run.ns}} is replaced by ns
+// and every queue name, worker ID, unique key and rate-limit key named in a
+// request body is suffixed with "-<ns>", both where it is sent and where it
+// appears in paths and in the expected values of those fields. Other
+// values that happen to equal one of the names are left as they are.
+func (tc TestCase) Isolate(ns string) TestCase {
+	names, _ := tc.isolatedNames()
+	return tc.rewrite(newNameRewriter(ns, names))
+}
+
+// isolatedNames collects the identifiers Isolate suffixes and reports
+// whether the test relies on the default queue.
+func (tc TestCase) isolatedNames() ([]string, bool) {
+	set := make(map[string]bool)
+	usesDefault := false
+
+	tc.eachStep(func(s *Step) {
+		if len(s.Body) == 0 {
+			if strings.HasSuffix(s.Path, "/workers/fetch") {
+				usesDefault = true
+			}
+			return
+		}
+		var body any
+		if json.Unmarshal(s.Body, &body) != nil {
+			return
+		}
+		if obj, ok := body.(map[string]any); ok && strings.HasSuffix(s.Path, "/workers/fetch") && obj["queues"] == nil {
+			usesDefault = true
+		}
+		collectIsolatedNames(body, "", set, &usesDefault)
+	})
+
+	if set[defaultQueue] {
+		usesDefault = true
+		delete(set, defaultQueue)
+	}
+	for name := range set {
+		if strings.Contains(name, RunNamespaceTemplate) {
+			delete(set, name)
+		}
+	}
+	return sortedKeys(set), usesDefault
+}
+
+// collectIsolatedNames walks a request body. parent is the key of the
+// enclosing object, used to find "key" inside "unique" and "rate_limit".
+func collectIsolatedNames(v any, parent string, set map[string]bool, usesDefault *bool) {
+	switch t := v.(type) {
+	case map[string]any:
+		// A job envelope without options.queue goes to the default queue.
+		if _, isJob := t["type"].(string); isJob && t["args"] != nil {
+			opts, _ := t["options"].(map[string]any)
+			if q, _ := opts["queue"].(string); q == "" {
+				*usesDefault = true
+			}
+		}
+		for key, val := range t {
+			switch s := val.(type) {
+			case string:
+				switch {
+				case key == "queue", key == "worker_id":
+					set[s] = true
+				case key == "key" && (parent == "unique" || parent == "rate_limit"):
+					set[s] = true
+				}
+			case []any:
+				if key == "queues" {
+					for _, q := range s {
+						if name, ok := q.(string); ok {
+							set[name] = true
+						}
+					}
+				}
+			}
+			collectIsolatedNames(val, key, set, usesDefault)
+		}
+	case []any:
+		for _, el := range t {
+			collectIsolatedNames(el, parent, set, usesDefault)
+		}
+	}
+}
+
+// nameRewriter replaces {{run.ns}} and suffixes isolated names. Names are
+// only rewritten where they name a queue, worker or key: in the fields
+// isolatedField picks out, in path segments and query values, and in
+// JSONPath filters; a payload value or expectation that happens to equal a
+// queue name is left alone.
+type nameRewriter struct {
+	ns       string
+	names    []string
+	isolated map[string]string
+	inPath   []*regexp.Regexp
+	inFilter *strings.Replacer
+}
+
+func newNameRewriter(ns string, names []string) *nameRewriter {
+	r := &nameRewriter{ns: ns, names: names, isolated: make(map[string]string, len(names))}
+	var quoted []string
+	// Longest names first so that "orders" does not pre-empt "orders-eu".
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		isolated := name + "-" + ns
+		r.isolated[name] = isolated
+		quoted = append(quoted,
+			`"`+name+`"`, `"`+isolated+`"`,
+			`'`+name+`'`, `'`+isolated+`'`,
+		)
+		r.inPath = append(r.inPath, regexp.MustCompile(`(^|[/=,])`+regexp.QuoteMeta(name)+`($|[/?&,])`))
+	}
+	r.inFilter = strings.NewReplacer(quoted...)
+	return r
+}
+
+// isolatedField reports whether a string under key, in an object under
+// parent, names something Isolate suffixes: a queue (queue, queues[],
+// queue.name, queues[].name), a worker ID, or a unique or rate-limit key.
+func isolatedField(key, parent string) bool {
+	switch key {
+	case "queue", "queues", "worker_id":
+		return true
+	case "key":
+		return parent == "unique" || parent == "rate_limit"
+	case "name":
+		return parent == "queue" || parent == "queues"
+	}
+	return false
+}
+
+// jsonPathFilter matches the bracketed parts of a JSONPath: indexes and
+// filter expressions.
+var jsonPathFilter = regexp.MustCompile(`\[[^\]]*\]`)
+
+// jsonPath rewrites an assertion's JSONPath, where names appear as quoted
+// literals in filter expressions, e.g. $.queues[?(@.name=='orders')].
+func (r *nameRewriter) jsonPath(path string) string {
+	path = strings.ReplaceAll(path, RunNamespaceTemplate, r.ns)
+	return jsonPathFilter.ReplaceAllStringFunc(path, r.inFilter.Replace)
+}
+
+// pathField returns the last field of a JSONPath and the field before it,
+// the key and parent of the value the path selects.
+func pathField(path string) (key, parent string) {
+	fields := strings.FieldsFunc(jsonPathFilter.ReplaceAllString(path, "."), func(c rune) bool { return c == '.' || c == '$' })
+	if n := len(fields); n > 0 {
+		key = fields[n-1]
+		if n > 1 {
+			parent = fields[n-2]
+		}
+	}
+	return key, parent
+}
+
+// value rewrites a raw JSON value found under key, in an object under
+// parent. The value is edited in place, so its formatting is kept. Matcher
+// objects such as {"$eq": ...} are transparent: their operands are
+// rewritten as the matched field would be. A value that is not valid JSON
+// only has {{run.ns}} replaced.
+func (r *nameRewriter) value(s, key, parent string) string {
+	s = strings.ReplaceAll(s, RunNamespaceTemplate, r.ns)
+	if len(r.isolated) == 0 {
+		return s
+	}
+
+	type frame struct {
+		object      bool
+		key, parent string // of the container
+		field       string // the current key, in an object
+		wantKey     bool
+	}
+	var (
+		stack []*frame
+		out   strings.Builder
+		last  int
+	)
+	dec := json.NewDecoder(strings.NewReader(s))
+	for {
+		from := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return s
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if top != nil && top.object && top.wantKey {
+			top.field, top.wantKey = tok.(string), false
+			continue
+		}
+
+		// tok starts a value; find the field it is under.
+		vkey, vparent := key, parent
+		switch {
+		case top == nil:
+		case !top.object:
+			vkey, vparent = top.key, top.parent
+		case strings.HasPrefix(top.field, "$"):
+			vkey, vparent = top.key, top.parent
+			top.wantKey = true
+		default:
+			vkey, vparent = top.field, top.key
+			top.wantKey = true
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			stack = append(stack, &frame{object: t == '{', key: vkey, parent: vparent, wantKey: t == '{'})
+		case string:
+			isolated, ok := r.isolated[t]
+			if !ok || !isolatedField(vkey, vparent) {
+				continue
+			}
+			to := int(dec.InputOffset())
+			start := int(from) + strings.IndexByte(s[from:to], '"')
+			quoted, _ := json.Marshal(isolated)
+			out.WriteString(s[last:start])
+			out.Write(quoted)
+			last = to
+		}
+	}
+	if last == 0 {
+		return s
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+// path rewrites a request path, where names appear as path segments or
+// query parameter values.
+func (r *nameRewriter) path(s string) string {
+	s = strings.ReplaceAll(s, RunNamespaceTemplate, r.ns)
+	for i, re := range r.inPath {
+		isolated := r.names[i] + "-" + r.ns
+		// Applied twice so that adjacent matches sharing a separator
+		// ("a,a") are both rewritten.
+		for n := 0; n < 2; n++ {
+			s = re.ReplaceAllString(s, "${1}"+isolated+"${2}")
+		}
+	}
+	return s
+}
+
+// raw rewrites a raw JSON value under key, in an object under parent.
+func (r *nameRewriter) raw(m json.RawMessage, key, parent string) json.RawMessage {
+	if len(m) == 0 {
+		return m
+	}
+	return json.RawMessage(r.value(string(m), key, parent))
+}
+
+// assertions rewrites a map of JSONPaths to expected values. resource is
+// the field the response body as a whole is under.
+func (r *nameRewriter) assertions(m map[string]json.RawMessage, resource string) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage, len(m))
+	for path, v := range m {
+		key, parent := pathField(path)
+		if parent == "" && key != "" {
+			parent = resource
+		}
+		out[r.jsonPath(path)] = r.raw(v, key, parent)
+	}
+	return out
+}
+
+// resourceField returns "queue" for the paths of a single queue, such as
+// /ojs/v1/queues/orders/pause, whose bodies describe that queue.
+func resourceField(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segments[:len(segments)-1] {
+		if seg == "queues" && segments[i+1] != "" {
+			return "queue"
+		}
+	}
+	return ""
+}
+
+func (r *nameRewriter) step(s Step) Step {
+	resource := resourceField(s.Path)
+	s.Path = r.path(s.Path)
+	s.Body = r.raw(s.Body, resource, "")
+	if s.Headers != nil {
+		headers := make(map[string]string, len(s.Headers))
+		for k, v := range s.Headers {
+			headers[k] = strings.ReplaceAll(v, RunNamespaceTemplate, r.ns)
+		}
+		s.Headers = headers
+	}
+	if s.Expect != nil {
+		s.Expect = r.assertions(s.Expect, resource)
+	}
+	if s.Assertions != nil {
+		a := *s.Assertions
+		if a.Body != nil {
+			a.Body = r.assertions(a.Body, resource)
+		}
+		a.Headers = r.raw(a.Headers, "", "")
+		a.BodyRaw = r.raw(a.BodyRaw, resource, "")
+		s.Assertions = &a
+	}
+	return s
+}
+
+func (tc TestCase) rewrite(r *nameRewriter) TestCase {
+	out := tc
+	if tc.Setup != nil {
+		out.Setup = &Setup{Steps: mapSteps(tc.Setup.Steps, r.step)}
+	}
+	out.Steps = mapSteps(tc.Steps, r.step)
+	if tc.Teardown != nil {
+		out.Teardown = &Setup{Steps: mapSteps(tc.Teardown.Steps, r.step)}
+	}
+	return out
+}
+
+func mapSteps(steps []Step, fn func(Step) Step) []Step {
+	if steps == nil {
+		return nil
+	}
+	out := make([]Step, len(steps))
+	for i, s := range steps {
+		out[i] = fn(s)
+	}
+	return out
+}
+
+// eachStep calls fn for setup, main and teardown steps in execution order.
+func (tc TestCase) eachStep(fn func(*Step)) {
+	if tc.Setup != nil {
+		for i := range tc.Setup.Steps {
+			fn(&tc.Setup.Steps[i])
+		}
+	}
+	for i := range tc.Steps {
+		fn(&tc.Steps[i])
+	}
+	if tc.Teardown != nil {
+		for i := range tc.Teardown.Steps {
+			fn(&tc.Teardown.Steps[i])
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func isolationTestCase() TestCase {
+	return TestCase{
+		TestID: "L0-OPS-001",
+		Steps: []Step{
+			{
+				ID:     "enqueue",
+				Action: "POST",
+				Path:   "/ojs/v1/jobs",
+				Body:   json.RawMessage(`{"type": "test.echo", "args": ["orders", {"queue": "orders"}], "options": {"queue": "orders", "unique": {"key": "u-1"}, "rate_limit": {"key": "rl"}}, "meta": {"key": "rl"}}`),
+				Assertions: &Assertions{Body: map[string]json.RawMessage{
+					"$.job.queue":   json.RawMessage(`"orders"`),
+					"$.job.args[0]": json.RawMessage(`"orders"`),
+					"$.job.meta":    json.RawMessage(`{"key": "rl"}`),
+				}},
+			},
+			{
+				ID:     "fetch",
+				Action: "POST",
+				Path:   "/ojs/v1/workers/fetch",
+				Body:   json.RawMessage(`{"queues": ["orders", "orders-eu"], "worker_id": "w-1"}`),
+			},
+			{
+				ID:     "stats",
+				Action: "GET",
+				Path:   "/ojs/v1/queues/orders/stats?worker=w-1",
+				Headers: map[string]string{
+					"X-Run": "{{run.ns}}",
+				},
+				Assertions: &Assertions{Body: map[string]json.RawMessage{
+					"$.queues[?(@.name=='orders')].paused": json.RawMessage(`false`),
+					"$.queue.name":                         json.RawMessage(`{"$eq": "orders"}`),
+					"$.queues":                             json.RawMessage(`["orders", "orders-eu"]`),
+					"$.description":                        json.RawMessage(`"orders"`),
+					"$.name":                               json.RawMessage(`"orders"`),
+				}},
+			},
+		},
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	tc := isolationTestCase()
+	if !tc.Isolatable() {
+		t.Fatal("expected test to be isolatable")
+	}
+	got := tc.Isolate("ns")
+
+	enqueue := got.Steps[0]
+	var body map[string]any
+	if err := json.Unmarshal(enqueue.Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	opts := body["options"].(map[string]any)
+	if opts["queue"] != "orders-ns" {
+		t.Errorf("expected queue orders-ns, got %v", opts["queue"])
+	}
+	if key := opts["unique"].(map[string]any)["key"]; key != "u-1-ns" {
+		t.Errorf("expected unique key u-1-ns, got %v", key)
+	}
+	if key := opts["rate_limit"].(map[string]any)["key"]; key != "rl-ns" {
+		t.Errorf("expected rate limit key rl-ns, got %v", key)
+	}
+	if m := string(enqueue.Assertions.Body["$.job.queue"]); m != `"orders-ns"` {
+		t.Errorf("expected assertion to follow rename, got %s", m)
+	}
+	// Payload values that equal a queue name or key are not queue names.
+	if want := `{"type": "test.echo", "args": ["orders", {"queue": "orders-ns"}], "options": {"queue": "orders-ns", "unique": {"key": "u-1-ns"}, "rate_limit": {"key": "rl-ns"}}, "meta": {"key": "rl"}}`; string(enqueue.Body) != want {
+		t.Errorf("unexpected enqueue body:\n got %s\nwant %s", enqueue.Body, want)
+	}
+	if m := string(enqueue.Assertions.Body["$.job.args[0]"]); m != `"orders"` {
+		t.Errorf("expected payload assertion to be kept, got %s", m)
+	}
+	if m := string(enqueue.Assertions.Body["$.job.meta"]); m != `{"key": "rl"}` {
+		t.Errorf("expected payload assertion to be kept, got %s", m)
+	}
+
+	if b := string(got.Steps[1].Body); b != `{"queues": ["orders-ns", "orders-eu-ns"], "worker_id": "w-1-ns"}` {
+		t.Errorf("unexpected fetch body: %s", b)
+	}
+
+	stats := got.Steps[2]
+	if stats.Path != "/ojs/v1/queues/orders-ns/stats?worker=w-1-ns" {
+		t.Errorf("unexpected path: %s", stats.Path)
+	}
+	if stats.Headers["X-Run"] != "ns" {
+		t.Errorf("expected {{run.ns}} to resolve, got %q", stats.Headers["X-Run"])
+	}
+	if _, ok := stats.Assertions.Body["$.queues[?(@.name=='orders-ns')].paused"]; !ok {
+		t.Errorf("expected filter expression to follow rename, got %v", stats.Assertions.Body)
+	}
+	for path, want := range map[string]string{
+		"$.queue.name":  `{"$eq": "orders-ns"}`,
+		"$.queues":      `["orders-ns", "orders-eu-ns"]`,
+		"$.description": `"orders"`,
+		"$.name":        `"orders-ns"`, // the queue the path names
+	} {
+		if m := string(stats.Assertions.Body[path]); m != want {
+			t.Errorf("%s: got %s, want %s", path, m, want)
+		}
+	}
+
+	// The original must be untouched.
+	if string(tc.Steps[1].Body) != `{"queues": ["orders", "orders-eu"], "worker_id": "w-1"}` {
+		t.Errorf("Isolate modified the original test: %s", tc.Steps[1].Body)
+	}
+}
+
+func TestWithRunNamespace(t *testing.T) {
+	tc := isolationTestCase()
+	got := tc.WithRunNamespace("ns")
+	if got.Steps[2].Headers["X-Run"] != "ns" {
+		t.Errorf("expected {{run.ns}} to resolve, got %q", got.Steps[2].Headers["X-Run"])
+	}
+	if string(got.Steps[1].Body) != string(tc.Steps[1].Body) {
+		t.Errorf("WithRunNamespace should not rename queues, got %s", got.Steps[1].Body)
+	}
+}
+
+func TestIsolatable(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   TestCase
+		want bool
+	}{
+		{"named queues", isolationTestCase(), true},
+		{"global-state tag", TestCase{Tags: []string{TagGlobalState}}, false},
+		{"implicit default queue", TestCase{Steps: []Step{
+			{Path: "/ojs/v1/jobs", Body: json.RawMessage(`{"type": "test.echo", "args": []}`)},
+		}}, false},
+		{"explicit default queue", TestCase{Steps: []Step{
+			{Path: "/ojs/v1/workers/fetch", Body: json.RawMessage(`{"queues": ["default"]}`)},
+		}}, false},
+		{"fetch without queues", TestCase{Steps: []Step{
+			{Path: "/ojs/v1/workers/fetch", Body: json.RawMessage(`{"worker_id": "w"}`)},
+		}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tc.Isolatable(); got != tt.want {
+				t.Errorf("Isolatable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lint rule names reported in Diagnostic.Rule.
+const (
+	RuleSchema          = "schema"
+	RuleLevel           = "level"
+	RuleTestIDFormat    = "test-id-format"
+	RuleTestIDDuplicate = "test-id-duplicate"
+	RuleTestIDLevel     = "test-id-level"
+	RuleTestIDCategory  = "test-id-category"
+	RuleStepIDDuplicate = "step-id-duplicate"
+	RuleStepRef         = "step-ref"
+	RuleParallelWith    = "parallel-with"
+	RuleSpecRef         = "spec-ref"
+)
+
+var (
+	testIDPattern  = regexp.MustCompile(`^(L(\d+)|EXT)-([A-Z0-9]+)-\d+$`)
+	stepRefPattern = regexp.MustCompile(`\{\{steps\.([^.}]+)\.([^}]*)\}\}`)
+	// specRefPattern matches "<document>#<anchor>", e.g. "ojs-core#section-5.1"
+	// or "ojs-ext-attest.md#policy".
+	specRefPattern = regexp.MustCompile(`^ojs-[a-z0-9-]+(\.md)?#[A-Za-z0-9._-]+$`)
+)
+
+// Diagnostic is a single finding from LintSuites.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Pointer string `json:"pointer"` // RFC 6901 JSON pointer into the file
+	TestID  string `json:"test_id,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s#%s: %s [%s]", d.File, d.Pointer, d.Message, d.Rule)
+}
+
+// LintSuites walks dir and checks every JSON test file without contacting a
+// server. Each file is first checked with ValidateTestFile; files that decode
+// into a TestCase are then checked together with LintTests, even if their
+// level cannot be read, so their IDs still count as taken and referenced.
+// The returned error is non-nil only if the tree cannot be read.
+func LintSuites(dir string) ([]Diagnostic, error) {
+	var diags []Diagnostic
+	var tests []TestCase
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		var tc TestCase
+		if err := json.Unmarshal(data, &tc); err != nil {
+			diags = append(diags, Diagnostic{File: path, Rule: RuleSchema, Message: err.Error()})
+			return nil
+		}
+		tc.FilePath = path
+		for _, p := range ValidateTestFile(path, data) {
+			diags = append(diags, Diagnostic{File: p.File, Pointer: p.Pointer, TestID: tc.TestID, Rule: RuleSchema, Message: p.Message})
+		}
+		if _, err := tc.ParseLevel(); err != nil {
+			diags = append(diags, Diagnostic{File: path, Pointer: "/level", TestID: tc.TestID, Rule: RuleLevel, Message: err.Error()})
+			tc.LevelInt = -1
+		}
+		tests = append(tests, tc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	diags = append(diags, LintTests(tests)...)
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].File != diags[j].File {
+			return diags[i].File < diags[j].File
+		}
+		return diags[i].Pointer < diags[j].Pointer
+	})
+	return diags, nil
+}
+
+// LintTests checks loaded test cases for problems that only show up at run
+// time: duplicate or inconsistent test IDs, duplicate step IDs, template
+// references to steps that have not run yet, dangling parallel_with targets
+// and malformed spec_ref values. A negative LevelInt marks a level that
+// could not be read; the test ID is not checked against it.
+func LintTests(tests []TestCase) []Diagnostic {
+	var diags []Diagnostic
+
+	ids := make(map[string]bool, len(tests))
+	for _, tc := range tests {
+		ids[tc.TestID] = true
+	}
+
+	diags = append(diags, lintTestIDs(tests)...)
+	for _, tc := range tests {
+		diags = append(diags, lintSteps(tc)...)
+		diags = append(diags, lintSpecRef(tc, ids)...)
+	}
+	return diags
+}
+
+// lintTestIDs checks that test IDs are unique, well-formed and agree with the
+// test's level, and that every test sharing an ID prefix (e.g. "L0-ENV") uses
+// the same category.
+func lintTestIDs(tests []TestCase) []Diagnostic {
+	var diags []Diagnostic
+	firstFile := make(map[string]string)
+	categories := make(map[string]map[string]int)
+
+	for _, tc := range tests {
+		diag := func(rule, format string, args ...any) {
+			diags = append(diags, Diagnostic{File: tc.FilePath, Pointer: "/test_id", TestID: tc.TestID, Rule: rule, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if prev, ok := firstFile[tc.TestID]; ok {
+			diag(RuleTestIDDuplicate, "test_id %q is already defined in %s", tc.TestID, prev)
+		} else {
+			firstFile[tc.TestID] = tc.FilePath
+		}
+
+		m := testIDPattern.FindStringSubmatch(tc.TestID)
+		if m == nil {
+			diag(RuleTestIDFormat, "test_id %q does not match L<level>-<AREA>-<NNN> or EXT-<AREA>-<NNN>", tc.TestID)
+			continue
+		}
+
+		if tc.LevelInt < 0 {
+			// The level itself was reported.
+		} else if m[1] == "EXT" {
+			if tc.LevelInt != 99 && !strings.HasPrefix(tc.Category, "ext-") {
+				diag(RuleTestIDLevel, "extension test_id %q needs level \"ext\" or an ext-* category", tc.TestID)
+			}
+		} else if n, _ := strconv.Atoi(m[2]); n != tc.LevelInt {
+			diag(RuleTestIDLevel, "test_id %q implies level %d, but level is %s", tc.TestID, n, strings.TrimSpace(string(tc.Level)))
+		}
+
+		prefix := m[1] + "-" + m[3]
+		if categories[prefix] == nil {
+			categories[prefix] = make(map[string]int)
+		}
+		categories[prefix][tc.Category]++
+	}
+
+	// The most common category for a prefix wins; ties go to the first
+	// category in lexical order so that output is deterministic.
+	expected := make(map[string]string, len(categories))
+	for prefix, counts := range categories {
+		best := ""
+		for _, cat := range sortedKeys(counts) {
+			if best == "" || counts[cat] > counts[best] {
+				best = cat
+			}
+		}
+		expected[prefix] = best
+	}
+	for _, tc := range tests {
+		m := testIDPattern.FindStringSubmatch(tc.TestID)
+		if m == nil {
+			continue
+		}
+		prefix := m[1] + "-" + m[3]
+		if want := expected[prefix]; tc.Category != want {
+			diags = append(diags, Diagnostic{
+				File:    tc.FilePath,
+				Pointer: "/category",
+				TestID:  tc.TestID,
+				Rule:    RuleTestIDCategory,
+				Message: fmt.Sprintf("category %q differs from %q used by other %s-* tests", tc.Category, want, prefix),
+			})
+		}
+	}
+	return diags
+}
+
+// lintSteps walks setup, steps and teardown in execution order so that
+// template references can be checked against the steps that have already run.
+func lintSteps(tc TestCase) []Diagnostic {
+	var diags []Diagnostic
+	diag := func(ptr, rule, format string, args ...any) {
+		diags = append(diags, Diagnostic{File: tc.FilePath, Pointer: ptr, TestID: tc.TestID, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	type located struct {
+		ptr  string
+		step Step
+	}
+	var ordered []located
+	if tc.Setup != nil {
+		for i, s := range tc.Setup.Steps {
+			ordered = append(ordered, located{fmt.Sprintf("/setup/steps/%d", i), s})
+		}
+	}
+	for i, s := range tc.Steps {
+		ordered = append(ordered, located{fmt.Sprintf("/steps/%d", i), s})
+	}
+	if tc.Teardown != nil {
+		for i, s := range tc.Teardown.Steps {
+			ordered = append(ordered, located{fmt.Sprintf("/teardown/steps/%d", i), s})
+		}
+	}
+
+	defined := make(map[string]bool)
+	for _, l := range ordered {
+		if l.step.ID != "" {
+			defined[l.step.ID] = true
+		}
+	}
+	mainSteps := make(map[string]bool, len(tc.Steps))
+	for _, s := range tc.Steps {
+		mainSteps[s.ID] = true
+	}
+
+	seen := make(map[string]string)
+	for _, l := range ordered {
+		s := l.step
+		if s.ID != "" {
+			if prev, ok := seen[s.ID]; ok {
+				diag(l.ptr+"/id", RuleStepIDDuplicate, "step id %q is already used at %s", s.ID, prev)
+			}
+		}
+
+		for _, ref := range stepTemplateRefs(l.ptr, s) {
+			switch {
+			case ref.rest != "response.body" && !strings.HasPrefix(ref.rest, "response.body."):
+				diag(ref.ptr, RuleStepRef, "template %q must have the form {{steps.<id>.response.body[.<path>]}}", ref.template)
+			case !defined[ref.stepID]:
+				diag(ref.ptr, RuleStepRef, "template %q references unknown step %q", ref.template, ref.stepID)
+			case seen[ref.stepID] == "":
+				diag(ref.ptr, RuleStepRef, "template %q references step %q, which has not run yet", ref.template, ref.stepID)
+			}
+		}
+
+		if s.ParallelWith != "" {
+			switch {
+			case s.ParallelWith == s.ID:
+				diag(l.ptr+"/parallel_with", RuleParallelWith, "step %q cannot run in parallel with itself", s.ID)
+			case !mainSteps[s.ParallelWith]:
+				diag(l.ptr+"/parallel_with", RuleParallelWith, "parallel_with target %q is not a step of this test", s.ParallelWith)
+			}
+		}
+
+		if s.ID != "" && seen[s.ID] == "" {
+			seen[s.ID] = l.ptr
+		}
+	}
+	return diags
+}
+
+type stepTemplateRef struct {
+	ptr      string
+	template string
+	stepID   string
+	rest     string
+}
+
+// stepTemplateRefs returns every {{steps.<id>...}} template in the fields the
+// runners resolve templates in: path, headers, body and assertions.
+func stepTemplateRefs(ptr string, s Step) []stepTemplateRef {
+	var refs []stepTemplateRef
+	scan := func(fieldPtr, text string) {
+		for _, m := range stepRefPattern.FindAllStringSubmatch(text, -1) {
+			refs = append(refs, stepTemplateRef{ptr: fieldPtr, template: m[0], stepID: m[1], rest: m[2]})
+		}
+	}
+
+	scan(ptr+"/path", s.Path)
+	for _, name := range sortedKeys(s.Headers) {
+		scan(pointerJoin(ptr+"/headers", name), s.Headers[name])
+	}
+	if len(s.Body) > 0 {
+		scan(ptr+"/body", string(s.Body))
+	}
+	if s.Assertions != nil {
+		for _, path := range sortedKeys(s.Assertions.Body) {
+			bptr := pointerJoin(ptr+"/assertions/body", path)
+			scan(bptr, path)
+			scan(bptr, string(s.Assertions.Body[path]))
+		}
+		if len(s.Assertions.Headers) > 0 {
+			scan(ptr+"/assertions/headers", string(s.Assertions.Headers))
+		}
+	}
+	for _, key := range sortedKeys(s.Expect) {
+		scan(pointerJoin(ptr+"/expect", key), string(s.Expect[key]))
+	}
+	return refs
+}
+
+// lintSpecRef checks spec_ref, a comma-separated list of specification
+// anchors ("ojs-core#section-5.1") and related test IDs.
+func lintSpecRef(tc TestCase, ids map[string]bool) []Diagnostic {
+	var diags []Diagnostic
+	diag := func(format string, args ...any) {
+		diags = append(diags, Diagnostic{File: tc.FilePath, Pointer: "/spec_ref", TestID: tc.TestID, Rule: RuleSpecRef, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(tc.SpecRef) == "" {
+		diag("spec_ref is empty")
+		return diags
+	}
+	for _, ref := range strings.Split(tc.SpecRef, ",") {
+		ref = strings.TrimSpace(ref)
+		switch {
+		case specRefPattern.MatchString(ref):
+		case testIDPattern.MatchString(ref):
+			if !ids[ref] {
+				diag("spec_ref references unknown test %q", ref)
+			}
+		default:
+			diag("spec_ref entry %q is not of the form <document>#<anchor> or a test ID", ref)
+		}
+	}
+	return diags
+}
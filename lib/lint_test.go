@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"testing"
+)
+
+func TestLintSuitesClean(t *testing.T) {
+	dir := t.TempDir()
+	writeSuiteFile(t, dir, "a.json", `{
+		"test_id": "L0-ENV-001", "level": 0, "category": "envelope",
+		"spec_ref": "ojs-core#section-5.1",
+		"steps": [
+			{"id": "enqueue", "action": "POST", "path": "/ojs/v1/jobs", "assertions": {"status": 201}},
+			{"id": "get", "action": "GET", "path": "/ojs/v1/jobs/{{steps.enqueue.response.body.job.id}}",
+			 "assertions": {"body": {"$.job.id": "{{steps.enqueue.response.body.job.id}}"}}}
+		]
+	}`)
+	writeSuiteFile(t, dir, "b.json", `{
+		"test_id": "EXT-ADM-001", "level": "ext", "category": "admin-api",
+		"spec_ref": "ojs-ext-attest.md#policy, L0-ENV-001",
+		"steps": [
+			{"id": "a", "action": "POST", "path": "/x", "parallel_with": "b"},
+			{"id": "b", "action": "POST", "path": "/x", "parallel_with": "a"}
+		]
+	}`)
+
+	diags, err := LintSuites(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+}
+
+// A file whose level cannot be read is still checked against the others.
+func TestLintSuitesBadLevel(t *testing.T) {
+	dir := t.TempDir()
+	writeSuiteFile(t, dir, "a.json", `{
+		"test_id": "L0-ENV-001", "level": 0, "category": "envelope",
+		"spec_ref": "ojs-core#section-5.1, L0-ENV-002",
+		"steps": [{"id": "a", "action": "GET", "path": "/x"}]
+	}`)
+	writeSuiteFile(t, dir, "b.json", `{
+		"test_id": "L0-ENV-002", "level": "zero", "category": "envelope",
+		"spec_ref": "ojs-core#section-5.1",
+		"steps": [{"id": "a", "action": "GET", "path": "/x"}]
+	}`)
+	writeSuiteFile(t, dir, "c.json", `{
+		"test_id": "L0-ENV-001", "level": [0], "category": "envelope",
+		"spec_ref": "ojs-core#section-5.1",
+		"steps": [{"id": "a", "action": "GET", "path": "/x"}]
+	}`)
+
+	diags, err := LintSuites(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := make(map[string]int)
+	for _, d := range diags {
+		rules[d.Rule]++
+	}
+	// L0-ENV-002 is defined, and L0-ENV-001 is defined twice.
+	if rules[RuleLevel] != 2 || rules[RuleTestIDDuplicate] != 1 || len(diags) != 3 {
+		t.Errorf("expected two level diagnostics and a duplicate ID, got %v", diags)
+	}
+}
+
+func TestLintTests(t *testing.T) {
+	tests := []TestCase{
+		{
+			TestID: "L1-RTR-001", LevelInt: 1, Category: "retry", SpecRef: "ojs-retry#section-2", FilePath: "a.json",
+			Setup: &Setup{Steps: []Step{{ID: "seed", Path: "/seed"}}},
+			Steps: []Step{
+				{ID: "s1", Path: "/jobs/{{steps.seed.response.body.id}}"},
+				{ID: "s2", Path: "/jobs/{{steps.s3.response.body.id}}"},
+				{ID: "s3", Body: []byte(`{"id": "{{steps.nope.response.body.id}}"}`)},
+				{ID: "s3", ParallelWith: "ghost"},
+				{ID: "s5", Headers: map[string]string{"X-Id": "{{steps.s1.status}}"}},
+				{ID: "s6", Body: []byte(`{"copy": "{{steps.s1.response.body}}"}`)},
+			},
+		},
+		{TestID: "L1-RTR-001", LevelInt: 1, Category: "retry", SpecRef: "ojs-retry#section-2", FilePath: "b.json"},
+		{TestID: "L2-RTR-002", LevelInt: 1, Category: "retry", SpecRef: "ojs-retry", FilePath: "c.json"},
+		{TestID: "L1-RTR-003", LevelInt: 1, Category: "backoff", SpecRef: "L1-RTR-999", FilePath: "d.json"},
+		{TestID: "bad-id", Category: "x", SpecRef: "ojs-core#section-1", FilePath: "e.json"},
+		{TestID: "EXT-XX-001", LevelInt: 0, Category: "xx", SpecRef: "ojs-xx#a", FilePath: "f.json"},
+	}
+
+	type key struct{ file, pointer, rule string }
+	want := map[key]bool{
+		{"a.json", "/steps/1/path", RuleStepRef}:               true, // later step
+		{"a.json", "/steps/2/body", RuleStepRef}:               true, // unknown step
+		{"a.json", "/steps/3/id", RuleStepIDDuplicate}:         true,
+		{"a.json", "/steps/3/parallel_with", RuleParallelWith}: true,
+		{"a.json", "/steps/4/headers/X-Id", RuleStepRef}:       true, // malformed
+		{"b.json", "/test_id", RuleTestIDDuplicate}:            true,
+		{"c.json", "/test_id", RuleTestIDLevel}:                true,
+		{"c.json", "/spec_ref", RuleSpecRef}:                   true,
+		{"d.json", "/category", RuleTestIDCategory}:            true,
+		{"d.json", "/spec_ref", RuleSpecRef}:                   true,
+		{"e.json", "/test_id", RuleTestIDFormat}:               true,
+		{"f.json", "/test_id", RuleTestIDLevel}:                true,
+	}
+
+	diags := LintTests(tests)
+	got := make(map[key]bool)
+	for _, d := range diags {
+		k := key{d.File, d.Pointer, d.Rule}
+		if !want[k] {
+			t.Errorf("unexpected diagnostic: %v", d)
+		}
+		got[k] = true
+	}
+	for k := range want {
+		if !got[k] {
+			t.Errorf("missing diagnostic %+v", k)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadOptions controls how LoadTests reads suite files.
+type LoadOptions struct {
+	// Strict rejects unknown keys in tests, steps and assertions, and
+	// matchers the assertion engine would not interpret as written.
+	Strict bool
+}
+
+// LoadTests recursively loads all JSON test files from a directory,
+// sorted by test_id for deterministic ordering.
+//
+// In strict mode every file is validated with ValidateTestFile before it is
+// decoded. Problems from all files are collected and returned together as a
+// *ValidationError, so a suite author sees every issue in one pass.
+func LoadTests(dir string, opts LoadOptions) ([]TestCase, error) {
+	var tests []TestCase
+	var problems []Problem
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		if opts.Strict {
+			if p := ValidateTestFile(path, data); len(p) > 0 {
+				problems = append(problems, p...)
+				return nil
+			}
+		}
+
+		var tc TestCase
+		if err := json.Unmarshal(data, &tc); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		tc.FilePath = path
+		if _, err := tc.ParseLevel(); err != nil {
+			return fmt.Errorf("parsing %s: level: %w", path, err)
+		}
+		if err := tc.NormalizeExpect(); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+		tests = append(tests, tc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].TestID < tests[j].TestID
+	})
+
+	return tests, nil
+}
+
+// Problem is a single finding from suite validation.
+type Problem struct {
+	File    string `json:"file"`
+	Pointer string `json:"pointer"` // RFC 6901 JSON pointer into the file
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s#%s: %s", p.File, p.Pointer, p.Message)
+}
+
+// ValidationError is returned by LoadTests in strict mode when one or more
+// suite files fail validation.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d suite validation problem(s):", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
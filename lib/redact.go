@@ -0,0 +1,348 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Redacted replaces every value removed by a Redactor.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactHeaders are the header name patterns redacted by
+// DefaultRedactConfig: credentials, and headers naming proxies and hosts.
+var DefaultRedactHeaders = []string{
+	"authorization", "proxy-authorization", "cookie", "set-cookie",
+	"x-api-key", ".*token.*", ".*secret.*",
+	"server", "via", "forwarded", "x-forwarded-.*", "x-real-ip",
+}
+
+// RedactConfig selects what a Redactor removes from reports before they
+// are published.
+type RedactConfig struct {
+	// Hostnames clears Environment.Hostname and Commit.Repo, and replaces
+	// the hosts of Target, Backend.URL and Targets, and the addresses they
+	// resolve to, wherever they appear: in failure details, header values,
+	// bodies and environment tags as well as in the URLs themselves.
+	Hostnames bool
+	// Targets are the servers under test, as URLs or host:port addresses.
+	// Results redacted before their report is built only know these hosts.
+	Targets []string
+	// CIURLs clears Environment.CIRunURL.
+	CIURLs bool
+	// Bodies drops response bodies from step results.
+	Bodies bool
+	// Headers are regular expressions matched case-insensitively against
+	// whole header names; matching headers keep their name, but their
+	// values are replaced.
+	Headers []string
+	// Patterns are regular expressions replaced wherever they match in the
+	// target, failure details, header values, bodies and environment tags.
+	Patterns []string
+}
+
+// DefaultRedactConfig redacts hostnames, CI URLs, bodies and
+// DefaultRedactHeaders.
+func DefaultRedactConfig() RedactConfig {
+	return RedactConfig{
+		Hostnames: true,
+		CIURLs:    true,
+		Bodies:    true,
+		Headers:   append([]string(nil), DefaultRedactHeaders...),
+	}
+}
+
+// Redactor strips infrastructure details from reports and results.
+type Redactor struct {
+	cfg      RedactConfig
+	headers  []*regexp.Regexp
+	patterns []*regexp.Regexp
+	hosts    []*regexp.Regexp
+}
+
+// NewRedactor compiles cfg. It returns nil if cfg redacts nothing.
+func NewRedactor(cfg RedactConfig) (*Redactor, error) {
+	if !cfg.Hostnames && !cfg.CIURLs && !cfg.Bodies && len(cfg.Headers) == 0 && len(cfg.Patterns) == 0 {
+		return nil, nil
+	}
+	r := &Redactor{cfg: cfg}
+	for _, h := range cfg.Headers {
+		re, err := regexp.Compile("(?i)^(?:" + h + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("header pattern %q: %w", h, err)
+		}
+		r.headers = append(r.headers, re)
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	if cfg.Hostnames {
+		r.hosts = hostPatterns(nil, cfg.Targets)
+	}
+	return r, nil
+}
+
+// Report redacts the report in place, including its Failures and Skipped
+// results.
+func (r *Redactor) Report(report *SuiteReport) {
+	if r.cfg.Hostnames {
+		targets := []string{report.Target}
+		if report.Backend != nil {
+			targets = append(targets, report.Backend.URL)
+		}
+		withHosts := *r
+		withHosts.hosts = hostPatterns(r.hosts, targets)
+		r = &withHosts
+		if report.Commit != nil {
+			c := *report.Commit
+			c.Repo = ""
+			report.Commit = &c
+		}
+	}
+	report.Target = r.target(report.Target)
+	if report.Backend != nil {
+		b := *report.Backend
+		b.URL = r.target(b.URL)
+		report.Backend = &b
+	}
+	if report.Environment != nil {
+		env := *report.Environment
+		if r.cfg.Hostnames {
+			env.Hostname = ""
+		}
+		if r.cfg.CIURLs {
+			env.CIRunURL = ""
+		}
+		if env.Tags != nil {
+			tags := make(map[string]string, len(env.Tags))
+			for k, v := range env.Tags {
+				tags[k] = r.text(v)
+			}
+			env.Tags = tags
+		}
+		report.Environment = &env
+	}
+	report.Failures = r.results(report.Failures)
+	report.Skipped = r.results(report.Skipped)
+}
+
+// Results redacts the results in place. Step results are copied, so slices
+// shared with other results are left alone.
+func (r *Redactor) Results(results []TestResult) {
+	for i := range results {
+		res := &results[i]
+		for j := range res.Failures {
+			f := &res.Failures[j]
+			f.Expected = r.text(f.Expected)
+			f.Actual = r.text(f.Actual)
+			f.Message = r.text(f.Message)
+		}
+		steps := make([]StepResult, len(res.StepResults))
+		for j, sr := range res.StepResults {
+			steps[j] = r.step(sr)
+		}
+		if res.StepResults != nil {
+			res.StepResults = steps
+		}
+	}
+}
+
+func (r *Redactor) results(results []TestResult) []TestResult {
+	if results == nil {
+		return nil
+	}
+	out := make([]TestResult, len(results))
+	copy(out, results)
+	for i := range out {
+		out[i].Failures = append([]Failure(nil), out[i].Failures...)
+	}
+	r.Results(out)
+	return out
+}
+
+func (r *Redactor) step(sr StepResult) StepResult {
+	if sr.Headers != nil {
+		headers := make(map[string][]string, len(sr.Headers))
+		for name, values := range sr.Headers {
+			redactAll := r.header(name)
+			out := make([]string, len(values))
+			for i, v := range values {
+				if redactAll {
+					out[i] = Redacted
+				} else {
+					out[i] = r.text(v)
+				}
+			}
+			headers[name] = out
+		}
+		sr.Headers = headers
+	}
+	switch {
+	case r.cfg.Bodies:
+		sr.Body = nil
+		sr.Parsed = nil
+	case len(sr.Body) > 0 && (len(r.patterns) > 0 || len(r.hosts) > 0):
+		body := []byte(r.text(string(sr.Body)))
+		if !json.Valid(body) {
+			// A pattern matched JSON syntax; keep the result well-formed.
+			body, _ = json.Marshal(Redacted)
+		}
+		sr.Body = body
+		sr.Parsed = nil
+	}
+	return sr
+}
+
+func (r *Redactor) header(name string) bool {
+	for _, re := range r.headers {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// text replaces the target hosts and every pattern match in s.
+func (r *Redactor) text(s string) string {
+	for _, re := range r.hosts {
+		s = replaceHost(re, s)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, Redacted)
+	}
+	return s
+}
+
+// target redacts a server URL, replacing its host when Hostnames is set
+// but keeping the scheme, port and path.
+func (r *Redactor) target(s string) string {
+	if r.cfg.Hostnames && s != "" {
+		if u, err := url.Parse(s); err == nil && u.Host != "" {
+			u.User = nil
+			if port := u.Port(); port != "" {
+				u.Host = "redacted:" + port
+			} else {
+				u.Host = "redacted"
+			}
+			s = u.String()
+		}
+	}
+	return r.text(s)
+}
+
+// lookupHost resolves target hosts for hostPatterns; tests replace it.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// hostPatterns adds patterns for the hosts of targets, and the addresses
+// they resolve to, to hosts. A pattern matches the host anywhere;
+// replaceHost keeps the matches that are whole names.
+func hostPatterns(hosts []*regexp.Regexp, targets []string) []*regexp.Regexp {
+	out := append([]*regexp.Regexp(nil), hosts...)
+	seen := make(map[string]bool)
+	for _, re := range hosts {
+		seen[re.String()] = true
+	}
+	add := func(host string) {
+		if host == "" {
+			return
+		}
+		re := regexp.MustCompile(`(?i)` + regexp.QuoteMeta(host))
+		if !seen[re.String()] {
+			seen[re.String()] = true
+			out = append(out, re)
+		}
+	}
+	for _, t := range targets {
+		host := targetHost(t)
+		add(host)
+		if host == "" || net.ParseIP(host) != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		addrs, _ := lookupHost(ctx, host)
+		cancel()
+		for _, a := range addrs {
+			add(a)
+		}
+	}
+	return out
+}
+
+// replaceHost replaces the matches of a host pattern in s that are whole
+// names, so the host of http://ojs:8080 is replaced in "ojs:8080" and
+// "ojs." but not inside ojs-backend or ojs.internal. Only the host is
+// matched, so occurrences separated by a single character are each
+// replaced.
+func replaceHost(re *regexp.Regexp, s string) string {
+	var b strings.Builder
+	last := 0
+	for from := 0; from < len(s); {
+		loc := re.FindStringIndex(s[from:])
+		if loc == nil || loc[0] == loc[1] {
+			break
+		}
+		start, end := from+loc[0], from+loc[1]
+		if wholeName(s, start, end) {
+			b.WriteString(s[last:start])
+			b.WriteString("redacted")
+			last, from = end, end
+		} else {
+			from = start + 1
+		}
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// wholeName reports whether s[start:end] is not part of a longer host
+// name: no name character or dot comes before it, and none comes after it
+// except a dot that ends a sentence.
+func wholeName(s string, start, end int) bool {
+	if start > 0 && (nameChar(s[start-1]) || s[start-1] == '.') {
+		return false
+	}
+	if end < len(s) && s[end] == '.' {
+		end++
+	}
+	return end == len(s) || !nameChar(s[end])
+}
+
+func nameChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-'
+}
+
+// targetHost returns the host of a server URL or host:port address.
+func targetHost(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return host
+	}
+	return ""
+}
+
+// NewSubmitter builds the report's SubmitterInfo from the runners'
+// -submitter-* flags. It returns nil if none are set. In anonymous mode
+// every field except KeyID is dropped.
+func NewSubmitter(org, contact, keyID string, anonymous bool) *SubmitterInfo {
+	if anonymous {
+		return &SubmitterInfo{KeyID: keyID, Anonymous: true}
+	}
+	if org == "" && contact == "" && keyID == "" {
+		return nil
+	}
+	return &SubmitterInfo{Org: org, Contact: contact, KeyID: keyID}
+}
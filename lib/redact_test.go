@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func redactTestResults() []TestResult {
+	return []TestResult{{
+		TestID: "L0-ENV-002",
+		Status: "fail",
+		Failures: []Failure{{
+			Field:    "body.error.details",
+			Expected: "absent",
+			Actual:   "db at pg-01.corp.acme.net refused connection",
+			Message:  "unexpected error from pg-01.corp.acme.net",
+		}},
+		StepResults: []StepResult{{
+			StepID:     "push",
+			StatusCode: 500,
+			Headers: http.Header{
+				"Authorization":   {"Bearer s3cret"},
+				"X-Upstream-Host": {"pg-01.corp.acme.net"},
+				"Content-Type":    {"application/json"},
+				"X-Internal-Node": {"node-7"},
+			},
+			Body: json.RawMessage(`{"error":{"details":"db at pg-01.corp.acme.net refused connection"}}`),
+		}},
+	}}
+}
+
+func TestRedactorDefaults(t *testing.T) {
+	r, err := NewRedactor(DefaultRedactConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := redactTestResults()
+	report := SuiteReport{
+		Target:      "http://ojs.corp.acme.net:8080/v1",
+		Backend:     &BackendInfo{Name: "ojs-backend-redis", URL: "https://ojs.corp.acme.net"},
+		Environment: &EnvironmentInfo{OS: "linux", Hostname: "build-42.corp", CIProvider: CIBuildkite, CIRunURL: "https://buildkite.com/acme/ojs/builds/7"},
+		Failures:    results,
+	}
+	r.Report(&report)
+
+	if report.Target != "http://redacted:8080/v1" || report.Backend.URL != "https://redacted" {
+		t.Errorf("hosts not redacted: %q, %q", report.Target, report.Backend.URL)
+	}
+	if report.Environment.Hostname != "" || report.Environment.CIRunURL != "" || report.Environment.CIProvider != CIBuildkite {
+		t.Errorf("unexpected environment: %+v", report.Environment)
+	}
+	sr := report.Failures[0].StepResults[0]
+	if sr.Body != nil {
+		t.Errorf("expected body to be dropped, got %s", sr.Body)
+	}
+	if sr.Headers.Get("Authorization") != Redacted || sr.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers: %v", sr.Headers)
+	}
+
+	// The report's copies were redacted, not the caller's results.
+	if results[0].StepResults[0].Body == nil || results[0].StepResults[0].Headers.Get("Authorization") == Redacted {
+		t.Error("Report modified the original results")
+	}
+}
+
+func TestRedactorTargetHosts(t *testing.T) {
+	defer func(orig func(context.Context, string) ([]string, error)) { lookupHost = orig }(lookupHost)
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		if host == "ojs-prod.corp.internal" {
+			return []string{"10.2.3.4"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	cfg := DefaultRedactConfig()
+	cfg.Bodies = false
+	cfg.Targets = []string{"http://ojs-prod.corp.internal:8080"}
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []TestResult{{
+		TestID: "L0-ENV-001",
+		Status: "error",
+		Failures: []Failure{{
+			StepID:  "enqueue",
+			Message: `HTTP request failed: Post "http://ojs-prod.corp.internal:8080/ojs/v1/jobs": dial tcp 10.2.3.4:8080: connect: connection refused`,
+		}},
+		StepResults: []StepResult{{
+			StepID:  "enqueue",
+			Headers: http.Header{"Location": {"http://ojs-prod.corp.internal:8080/ojs/v1/jobs/1"}},
+			Body:    json.RawMessage(`{"self":"http://10.2.3.4:8080/ojs/v1/jobs/1","queue":"ojs-prod.corp.internal.default"}`),
+		}},
+	}}
+	r.Results(results)
+
+	want := `HTTP request failed: Post "http://redacted:8080/ojs/v1/jobs": dial tcp redacted:8080: connect: connection refused`
+	if got := results[0].Failures[0].Message; got != want {
+		t.Errorf("message = %s, want %s", got, want)
+	}
+	sr := results[0].StepResults[0]
+	if got := sr.Headers.Get("Location"); got != "http://redacted:8080/ojs/v1/jobs/1" {
+		t.Errorf("Location = %s", got)
+	}
+	if want := `{"self":"http://redacted:8080/ojs/v1/jobs/1","queue":"ojs-prod.corp.internal.default"}`; string(sr.Body) != want {
+		t.Errorf("body = %s, want %s", sr.Body, want)
+	}
+
+	// Report also redacts the hosts of its own target and backend, and
+	// the commit repository.
+	r, _ = NewRedactor(DefaultRedactConfig())
+	report := SuiteReport{
+		Target:      "http://ojs-prod.corp.internal:8080",
+		Commit:      &CommitInfo{Repo: "git@git.corp.internal:platform/ojs-backend.git", SHA: "abc123"},
+		Environment: &EnvironmentInfo{Tags: map[string]string{"upstream": "10.2.3.4"}},
+		Failures:    []TestResult{{TestID: "L0-ENV-001", Failures: []Failure{{Message: "lookup ojs-prod.corp.internal failed"}}}},
+	}
+	r.Report(&report)
+	if report.Commit.Repo != "" || report.Commit.SHA != "abc123" {
+		t.Errorf("unexpected commit: %+v", report.Commit)
+	}
+	if report.Environment.Tags["upstream"] != "redacted" || report.Failures[0].Failures[0].Message != "lookup redacted failed" {
+		t.Errorf("hosts not redacted: %v, %q", report.Environment.Tags, report.Failures[0].Failures[0].Message)
+	}
+}
+
+func TestRedactorHostBoundaries(t *testing.T) {
+	cfg := DefaultRedactConfig()
+	cfg.Targets = []string{"http://ojs:8080"}
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for in, want := range map[string]string{
+		"ojs":                    "redacted",
+		"dial ojs:8080.":         "dial redacted:8080.",
+		"lookup OJS.":            "lookup redacted.",
+		"ojs,ojs":                "redacted,redacted",
+		"ojs/ojs/ojs":            "redacted/redacted/redacted",
+		"http://ojs:8080/ojs":    "http://redacted:8080/redacted",
+		"ojs-backend ojs.corp":   "ojs-backend ojs.corp",
+		"myojs ojs2 ojs-ojs":     "myojs ojs2 ojs-ojs",
+		"queue ojsojs, then ojs": "queue ojsojs, then redacted",
+	} {
+		if got := r.text(in); got != want {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRedactorPatternsAndHeaders(t *testing.T) {
+	r, err := NewRedactor(RedactConfig{
+		Headers:  []string{"x-internal-.*"},
+		Patterns: []string{`[a-z0-9-]+\.corp\.acme\.net`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := redactTestResults()
+	r.Results(results)
+
+	f := results[0].Failures[0]
+	if strings.Contains(f.Actual+f.Message, "acme") || f.Expected != "absent" {
+		t.Errorf("failure not redacted: %+v", f)
+	}
+	sr := results[0].StepResults[0]
+	if sr.Headers.Get("X-Internal-Node") != Redacted || sr.Headers.Get("X-Upstream-Host") != Redacted {
+		t.Errorf("unexpected headers: %v", sr.Headers)
+	}
+	if sr.Headers.Get("Authorization") != "Bearer s3cret" {
+		t.Error("header outside the configured patterns was redacted")
+	}
+	if want := `{"error":{"details":"db at [REDACTED] refused connection"}}`; string(sr.Body) != want {
+		t.Errorf("body = %s, want %s", sr.Body, want)
+	}
+
+	// A match spanning JSON syntax still leaves a valid body.
+	r, _ = NewRedactor(RedactConfig{Patterns: []string{`"details":"db`}})
+	results = redactTestResults()
+	r.Results(results)
+	if body := results[0].StepResults[0].Body; !json.Valid(body) {
+		t.Errorf("invalid body after redaction: %s", body)
+	}
+}
+
+func TestNewRedactor(t *testing.T) {
+	if r, err := NewRedactor(RedactConfig{}); r != nil || err != nil {
+		t.Errorf("expected nil redactor for empty config, got %v, %v", r, err)
+	}
+	if _, err := NewRedactor(RedactConfig{Patterns: []string{"("}}); err == nil {
+		t.Error("expected invalid pattern to be rejected")
+	}
+}
+
+func TestNewSubmitter(t *testing.T) {
+	if s := NewSubmitter("", "", "", false); s != nil {
+		t.Errorf("expected nil submitter, got %+v", s)
+	}
+	s := NewSubmitter("Acme", "ojs@acme.dev", "ed25519:0011223344556677", true)
+	if *s != (SubmitterInfo{KeyID: "ed25519:0011223344556677", Anonymous: true}) {
+		t.Errorf("anonymous submitter kept identifying fields: %+v", s)
+	}
+	s = NewSubmitter("Acme", "ojs@acme.dev", "", false)
+	if s.Org != "Acme" || s.Contact != "ojs@acme.dev" || s.Anonymous {
+		t.Errorf("unexpected submitter: %+v", s)
+	}
+}
@@ -0,0 +1,322 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// Resetter restores the server under test to a clean state between tests.
+type Resetter interface {
+	Reset(ctx context.Context) error
+}
+
+// ResetFunc adapts a function to the Resetter interface.
+type ResetFunc func(ctx context.Context) error
+
+// Reset implements Resetter.
+func (f ResetFunc) Reset(ctx context.Context) error { return f(ctx) }
+
+// ResetConfig selects the reset strategies a runner uses. Every configured
+// strategy runs before each test, in the order Redis, PostgreSQL, HTTP,
+// exec.
+type ResetConfig struct {
+	// RedisURL is flushed with FLUSHDB, e.g. redis://localhost:6379/0.
+	RedisURL string
+
+	// PostgresURL and PostgresTables select tables to TRUNCATE. Tables may
+	// be schema-qualified ("ojs.jobs").
+	PostgresURL    string
+	PostgresTables []string
+
+	// HTTPURL receives a POST that must answer 2xx.
+	HTTPURL string
+
+	// Exec is a shell command run with "sh -c" that must exit 0.
+	Exec string
+
+	// Retries is how often a failed HTTP reset or exec hook is retried.
+	Retries int
+
+	// Timeout bounds each strategy's reset, retries included.
+	Timeout time.Duration
+}
+
+// DefaultResetRetries is the runners' default for ResetConfig.Retries.
+const DefaultResetRetries = 2
+
+// defaultResetTimeout is used when ResetConfig.Timeout is zero.
+const defaultResetTimeout = 10 * time.Second
+
+// NewResetter connects the strategies in cfg and returns a chain running
+// all of them, or nil if none is configured. Call Close on the chain when
+// the run is done.
+func NewResetter(ctx context.Context, cfg ResetConfig) (*ResetChain, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultResetTimeout
+	}
+
+	chain := &ResetChain{Timeout: timeout}
+	if cfg.RedisURL != "" {
+		r, err := NewRedisResetter(ctx, cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		chain.Resetters = append(chain.Resetters, r)
+	}
+	if cfg.PostgresURL != "" || len(cfg.PostgresTables) > 0 {
+		r, err := NewPostgresResetter(ctx, cfg.PostgresURL, cfg.PostgresTables)
+		if err != nil {
+			chain.Close()
+			return nil, err
+		}
+		chain.Resetters = append(chain.Resetters, r)
+	}
+	if cfg.HTTPURL != "" {
+		chain.Resetters = append(chain.Resetters, &HTTPResetter{
+			URL:     cfg.HTTPURL,
+			Client:  &http.Client{Timeout: timeout},
+			Retries: cfg.Retries,
+		})
+	}
+	if cfg.Exec != "" {
+		chain.Resetters = append(chain.Resetters, &ExecResetter{Command: cfg.Exec, Retries: cfg.Retries})
+	}
+
+	if len(chain.Resetters) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// ResetChain runs several Resetters in order and stops at the first error.
+type ResetChain struct {
+	Resetters []Resetter
+	// Timeout, if set, bounds each Resetter's call.
+	Timeout time.Duration
+}
+
+// Reset implements Resetter.
+func (c *ResetChain) Reset(ctx context.Context) error {
+	for _, r := range c.Resetters {
+		rctx, cancel := ctx, context.CancelFunc(func() {})
+		if c.Timeout > 0 {
+			rctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		}
+		err := r.Reset(rctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the connections held by the chain's Resetters.
+func (c *ResetChain) Close() error {
+	var errs []error
+	for _, r := range c.Resetters {
+		if closer, ok := r.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RedisResetter flushes the current Redis database.
+type RedisResetter struct {
+	Client *redis.Client
+}
+
+// NewRedisResetter connects to url and checks the connection.
+func NewRedisResetter(ctx context.Context, url string) (*RedisResetter, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connecting to Redis: %w", err)
+	}
+	return &RedisResetter{Client: client}, nil
+}
+
+// Reset implements Resetter.
+func (r *RedisResetter) Reset(ctx context.Context) error {
+	if err := r.Client.FlushDB(ctx).Err(); err != nil {
+		return fmt.Errorf("redis FLUSHDB: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis client.
+func (r *RedisResetter) Close() error {
+	return r.Client.Close()
+}
+
+// PostgresResetter empties a fixed set of tables with TRUNCATE ... RESTART
+// IDENTITY CASCADE.
+type PostgresResetter struct {
+	Conn   *pgx.Conn
+	Tables []string
+}
+
+// NewPostgresResetter connects to url and checks that every table exists.
+func NewPostgresResetter(ctx context.Context, url string, tables []string) (*PostgresResetter, error) {
+	if url == "" {
+		return nil, errors.New("postgres reset: no connection URL given")
+	}
+	if len(tables) == 0 {
+		return nil, errors.New("postgres reset: no tables given")
+	}
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to PostgreSQL: %w", err)
+	}
+	for _, table := range tables {
+		var found bool
+		if err := conn.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&found); err != nil {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("postgres reset: looking up table %q: %w", table, err)
+		}
+		if !found {
+			conn.Close(ctx)
+			return nil, fmt.Errorf("postgres reset: table %q does not exist", table)
+		}
+	}
+	return &PostgresResetter{Conn: conn, Tables: tables}, nil
+}
+
+// Reset implements Resetter.
+func (r *PostgresResetter) Reset(ctx context.Context) error {
+	if _, err := r.Conn.Exec(ctx, truncateStatement(r.Tables)); err != nil {
+		return fmt.Errorf("postgres TRUNCATE: %w", err)
+	}
+	return nil
+}
+
+// Close closes the PostgreSQL connection.
+func (r *PostgresResetter) Close() error {
+	return r.Conn.Close(context.Background())
+}
+
+// truncateStatement quotes each table name, splitting schema-qualified
+// names on the dot.
+func truncateStatement(tables []string) string {
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = pgx.Identifier(strings.Split(table, ".")).Sanitize()
+	}
+	return "TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE"
+}
+
+// HTTPResetter POSTs to a reset endpoint and requires a 2xx answer. Failed
+// attempts are retried Retries times with a short linear backoff.
+type HTTPResetter struct {
+	URL     string
+	Client  *http.Client
+	Retries int
+}
+
+// Reset implements Resetter.
+func (r *HTTPResetter) Reset(ctx context.Context) error {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return retryReset(ctx, r.Retries, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return nil
+	}, "POST "+r.URL)
+}
+
+// ExecResetter runs a user command through "sh -c". A non-zero exit is a
+// failure; the command's output is included in the error.
+type ExecResetter struct {
+	Command string
+	Retries int
+}
+
+// Reset implements Resetter.
+func (r *ExecResetter) Reset(ctx context.Context) error {
+	return retryReset(ctx, r.Retries, func() error {
+		var out bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", r.Command)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(out.String()); msg != "" {
+				return fmt.Errorf("%w: %s", err, lastLine(msg))
+			}
+			return err
+		}
+		return nil
+	}, "exec "+r.Command)
+}
+
+// retryReset calls attempt until it succeeds, retries are used up or ctx is
+// done.
+func retryReset(ctx context.Context, retries int, attempt func() error, what string) error {
+	var err error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %w (after %d attempt(s))", what, err, i)
+			case <-time.After(time.Duration(i) * 100 * time.Millisecond):
+			}
+		}
+		if err = attempt(); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w (after %d attempt(s))", what, err, retries+1)
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// ResetErrorResult is the result recorded for a test that did not run
+// because the state reset before it failed.
+func ResetErrorResult(tc TestCase, err error) TestResult {
+	return TestResult{
+		TestID:   tc.TestID,
+		Name:     tc.Name,
+		Level:    tc.LevelInt,
+		Category: tc.Category,
+		SpecRef:  tc.SpecRef,
+		FilePath: tc.FilePath,
+		Status:   "error",
+		Failures: []Failure{{
+			Field:   "reset",
+			Message: fmt.Sprintf("State reset failed: %v", err),
+		}},
+	}
+}
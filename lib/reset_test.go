@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPResetterRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if calls < 3 {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	r := &HTTPResetter{URL: srv.URL, Retries: 2}
+	if err := r.Reset(context.Background()); err != nil {
+		t.Fatalf("expected success on third attempt, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestHTTPResetterStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "reset disabled", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := (&HTTPResetter{URL: srv.URL}).Reset(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "status 404: reset disabled") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestExecResetter(t *testing.T) {
+	if err := (&ExecResetter{Command: "true"}).Reset(context.Background()); err != nil {
+		t.Errorf("expected success, got %v", err)
+	}
+
+	err := (&ExecResetter{Command: "echo starting; echo db locked >&2; exit 3"}).Reset(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exit status 3: db locked") {
+		t.Errorf("expected exit status and last output line, got %v", err)
+	}
+}
+
+func TestResetChainStopsAtFirstError(t *testing.T) {
+	var order []string
+	step := func(name string, err error) Resetter {
+		return ResetFunc(func(context.Context) error {
+			order = append(order, name)
+			return err
+		})
+	}
+	chain := &ResetChain{Resetters: []Resetter{
+		step("redis", nil),
+		step("http", errors.New("boom")),
+		step("exec", nil),
+	}}
+	if err := chain.Reset(context.Background()); err == nil || err.Error() != "boom" {
+		t.Errorf("expected error from second resetter, got %v", err)
+	}
+	if strings.Join(order, ",") != "redis,http" {
+		t.Errorf("expected chain to stop after failure, got %v", order)
+	}
+}
+
+func TestNewResetterNone(t *testing.T) {
+	chain, err := NewResetter(context.Background(), ResetConfig{})
+	if chain != nil || err != nil {
+		t.Errorf("expected no chain without strategies, got %v, %v", chain, err)
+	}
+
+	if _, err := NewResetter(context.Background(), ResetConfig{PostgresURL: "postgres://localhost/ojs"}); err == nil {
+		t.Error("expected error for postgres URL without tables")
+	}
+}
+
+func TestTruncateStatement(t *testing.T) {
+	got := truncateStatement([]string{"ojs.jobs", "workers"})
+	want := `TRUNCATE TABLE "ojs"."jobs", "workers" RESTART IDENTITY CASCADE`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"context"
+	"sync"
+)
+
+// Scheduler runs test cases, optionally several at a time.
+type Scheduler struct {
+	// Parallel is the number of tests that may run at once. Values below 2
+	// run every test serially, in order.
+	Parallel int
+
+	// Reset, if set, restores the server to a clean state. It is called
+	// before every test that runs on its own, and once before a batch of
+	// concurrent tests, since resetting mid-batch would break the tests
+	// still running. If a reset fails, the tests it was meant to prepare are
+	// not run and are reported with status "error" instead.
+	Reset Resetter
+}
+
+// Run calls run for every test and returns the results in the order of
+// tests, however they were scheduled.
+//
+// In parallel mode, Isolatable tests run concurrently with their names
+// isolated by TestCase.Isolate; the remaining tests run one at a time once
+// the concurrent batch has finished. In serial mode each test only has
+// {{run.ns}} resolved.
+func (s Scheduler) Run(tests []TestCase, run func(TestCase) TestResult) []TestResult {
+	results := make([]TestResult, len(tests))
+
+	exclusive := func(i int) {
+		if err := s.reset(); err != nil {
+			results[i] = ResetErrorResult(tests[i], err)
+			return
+		}
+		results[i] = run(tests[i].WithRunNamespace(RunNamespace(tests[i], i)))
+	}
+
+	if s.Parallel < 2 {
+		for i := range tests {
+			exclusive(i)
+		}
+		return results
+	}
+
+	var concurrent, serial []int
+	for i, tc := range tests {
+		if tc.Isolatable() {
+			concurrent = append(concurrent, i)
+		} else {
+			serial = append(serial, i)
+		}
+	}
+
+	if len(concurrent) > 0 {
+		s.runConcurrent(concurrent, tests, results, run)
+	}
+
+	for _, i := range serial {
+		exclusive(i)
+	}
+	return results
+}
+
+// runConcurrent resets once and runs the tests at the given indices on
+// s.Parallel workers.
+func (s Scheduler) runConcurrent(indices []int, tests []TestCase, results []TestResult, run func(TestCase) TestResult) {
+	if err := s.reset(); err != nil {
+		for _, i := range indices {
+			results[i] = ResetErrorResult(tests[i], err)
+		}
+		return
+	}
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.Parallel && w < len(indices); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = run(tests[i].Isolate(RunNamespace(tests[i], i)))
+			}
+		}()
+	}
+	for _, i := range indices {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+func (s Scheduler) reset() error {
+	if s.Reset == nil {
+		return nil
+	}
+	return s.Reset.Reset(context.Background())
+}
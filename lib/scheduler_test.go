@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerPreservesOrder(t *testing.T) {
+	var tests []TestCase
+	for i := 0; i < 20; i++ {
+		tc := TestCase{TestID: fmt.Sprintf("L0-OPS-%03d", i), Steps: []Step{
+			{Path: "/ojs/v1/workers/fetch", Body: json.RawMessage(`{"queues": ["q"]}`)},
+		}}
+		if i%5 == 0 {
+			tc.Tags = []string{TagGlobalState}
+		}
+		tests = append(tests, tc)
+	}
+
+	var running, maxRunning int32
+	var exclusiveOverlap atomic.Bool
+	var mu sync.Mutex
+	resets := 0
+
+	s := Scheduler{Parallel: 4, Reset: ResetFunc(func(context.Context) error {
+		mu.Lock()
+		resets++
+		mu.Unlock()
+		return nil
+	})}
+	results := s.Run(tests, func(tc TestCase) TestResult {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		if tc.IsGlobalState() && n != 1 {
+			exclusiveOverlap.Store(true)
+		}
+		time.Sleep(2 * time.Millisecond)
+		return TestResult{TestID: tc.TestID, Name: string(tc.Steps[0].Body)}
+	})
+
+	for i, r := range results {
+		if r.TestID != tests[i].TestID {
+			t.Fatalf("result %d is %s, want %s", i, r.TestID, tests[i].TestID)
+		}
+	}
+	if maxRunning < 2 || maxRunning > 4 {
+		t.Errorf("expected between 2 and 4 concurrent tests, got %d", maxRunning)
+	}
+	if exclusiveOverlap.Load() {
+		t.Error("global-state test ran concurrently with another test")
+	}
+	// One reset before the concurrent batch plus one per exclusive test.
+	if resets != 1+4 {
+		t.Errorf("expected 5 resets, got %d", resets)
+	}
+	if results[1].Name != `{"queues": ["q-l0-ops-001-1"]}` {
+		t.Errorf("expected concurrent test to be isolated, got %s", results[1].Name)
+	}
+	if results[0].Name != `{"queues": ["q"]}` {
+		t.Errorf("expected exclusive test to keep its names, got %s", results[0].Name)
+	}
+}
+
+func TestSchedulerSerial(t *testing.T) {
+	tests := []TestCase{{TestID: "A"}, {TestID: "B"}, {TestID: "C"}}
+	var order []string
+	results := Scheduler{}.Run(tests, func(tc TestCase) TestResult {
+		order = append(order, tc.TestID)
+		return TestResult{TestID: tc.TestID}
+	})
+	if fmt.Sprint(order) != "[A B C]" || len(results) != 3 {
+		t.Errorf("expected serial in-order execution, got %v", order)
+	}
+}
+
+func TestSchedulerResetFailure(t *testing.T) {
+	tests := []TestCase{
+		{TestID: "L0-OPS-001", Name: "first", Steps: []Step{
+			{Path: "/ojs/v1/workers/fetch", Body: json.RawMessage(`{"queues": ["q"]}`)},
+		}},
+		{TestID: "L0-OPS-002", Tags: []string{TagGlobalState}},
+		{TestID: "L0-OPS-003", Tags: []string{TagGlobalState}},
+	}
+
+	// The first reset (before the concurrent batch) and the third fail.
+	calls := 0
+	reset := ResetFunc(func(context.Context) error {
+		calls++
+		if calls != 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	var ran []string
+	results := Scheduler{Parallel: 2, Reset: reset}.Run(tests, func(tc TestCase) TestResult {
+		ran = append(ran, tc.TestID)
+		return TestResult{TestID: tc.TestID, Status: "pass"}
+	})
+
+	if fmt.Sprint(ran) != "[L0-OPS-002]" {
+		t.Errorf("expected only the test after the successful reset to run, got %v", ran)
+	}
+	for _, i := range []int{0, 2} {
+		r := results[i]
+		if r.Status != "error" || r.TestID != tests[i].TestID || len(r.Failures) != 1 || r.Failures[0].Field != "reset" {
+			t.Errorf("expected reset error for %s, got %+v", tests[i].TestID, r)
+		}
+	}
+	if results[0].Name != "first" {
+		t.Errorf("expected error result to keep test metadata, got %+v", results[0])
+	}
+	if results[1].Status != "pass" {
+		t.Errorf("expected L0-OPS-002 to pass, got %+v", results[1])
+	}
+}
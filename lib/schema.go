@@ -77,6 +77,28 @@ type Step struct {
 	Assertions   *Assertions       `json:"assertions,omitempty"`
 	Description  string            `json:"description,omitempty"`
 	ParallelWith string            `json:"parallel_with,omitempty"`
+
+	// Expect and Capture belong to the variable dialect used by some
+	// extension suites. Expect is folded into Assertions by NormalizeExpect;
+	// Capture maps variable names to JSONPaths evaluated against the
+	// response body, for later ${name} substitution.
+	Expect  map[string]json.RawMessage `json:"expect,omitempty"`
+	Capture map[string]string          `json:"capture,omitempty"`
+
+	// Poll re-issues the request until the step's assertions pass, instead
+	// of asserting once after a fixed delay_ms or WAIT.
+	Poll *PollConfig `json:"poll,omitempty"`
+}
+
+// PollConfig controls how a polled step is retried. The timeout is capped
+// at TimingConfig.MaxWaitMs and defaults to it; the interval defaults to
+// DefaultPollIntervalMs and is multiplied by Backoff (if > 1) after each
+// attempt, up to MaxIntervalMs.
+type PollConfig struct {
+	TimeoutMs     int     `json:"timeout_ms,omitempty"`
+	IntervalMs    int     `json:"interval_ms,omitempty"`
+	Backoff       float64 `json:"backoff,omitempty"`
+	MaxIntervalMs int     `json:"max_interval_ms,omitempty"`
 }
 
 // Assertions defines expected outcomes for a step.
@@ -187,6 +209,12 @@ type StepResult struct {
 	Body       json.RawMessage     `json:"body"`
 	DurationMs int64               `json:"duration_ms"`
 	Parsed     map[string]any      `json:"-"` // parsed JSON body
+
+	// Set for polled steps: how many times the request was issued and how
+	// long it took from the first attempt until the assertions passed (or
+	// polling gave up).
+	PollAttempts  int   `json:"poll_attempts,omitempty"`
+	PollElapsedMs int64 `json:"poll_elapsed_ms,omitempty"`
 }
 
 // TestResult holds the outcome of running a single test case.
@@ -196,7 +224,7 @@ type TestResult struct {
 	Level       int           `json:"level"`
 	Category    string        `json:"category"`
 	SpecRef     string        `json:"spec_ref"`
-	Status      string        `json:"status"` // "pass", "fail", "skip", "error"
+	Status      string        `json:"status"` // "pass", "fail", "skip", "error", "xfail", "xpass"
 	DurationMs  int64         `json:"duration_ms"`
 	Failures    []Failure     `json:"failures,omitempty"`
 	StepResults []StepResult  `json:"step_results,omitempty"`
@@ -239,6 +267,36 @@ type SuiteReport struct {
 	Environment         *EnvironmentInfo   `json:"environment,omitempty"`
 	Backend             *BackendInfo       `json:"backend,omitempty"`
 	Submitter           *SubmitterInfo     `json:"submitter,omitempty"`
+
+	// Per-test outcomes in run order, without step detail. Lets tools such
+	// as `ojs-conformance diff` tell passing tests from absent ones.
+	Tests []TestOutcome `json:"tests,omitempty"`
+}
+
+// TestOutcome is the slim per-test record kept in SuiteReport.Tests.
+type TestOutcome struct {
+	TestID     string `json:"test_id"`
+	Name       string `json:"name"`
+	Level      int    `json:"level"`
+	Category   string `json:"category"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Outcomes returns the TestOutcome of every result.
+func Outcomes(results []TestResult) []TestOutcome {
+	out := make([]TestOutcome, len(results))
+	for i, r := range results {
+		out[i] = TestOutcome{
+			TestID:     r.TestID,
+			Name:       r.Name,
+			Level:      r.Level,
+			Category:   r.Category,
+			Status:     r.Status,
+			DurationMs: r.DurationMs,
+		}
+	}
+	return out
 }
 
 // CommitInfo identifies the source revision the backend under test was built from.
@@ -272,11 +330,12 @@ type BackendInfo struct {
 }
 
 // SubmitterInfo identifies the party submitting the report (for CTN attribution).
-// All fields are optional; --anonymous mode SHOULD strip every field except KeyID.
+// All fields are optional; -anonymous mode strips every field except KeyID
+// (see NewSubmitter).
 type SubmitterInfo struct {
 	Org       string `json:"org,omitempty"`        // e.g. "Acme Corp"
 	Contact   string `json:"contact,omitempty"`    // email or URL
-	KeyID     string `json:"key_id,omitempty"`     // signing key identifier
+	KeyID     string `json:"key_id,omitempty"`     // signing key identifier, see KeyID
 	Anonymous bool   `json:"anonymous,omitempty"`
 }
 
@@ -287,6 +346,8 @@ type ResultsSummary struct {
 	Failed   int                    `json:"failed"`
 	Skipped  int                    `json:"skipped"`
 	Errored  int                    `json:"errored"`
+	XFailed  int                    `json:"xfailed,omitempty"`
+	XPassed  int                    `json:"xpassed,omitempty"`
 	ByLevel  map[int]LevelSummary   `json:"by_level"`
 }
 
@@ -297,6 +358,8 @@ type LevelSummary struct {
 	Failed  int  `json:"failed"`
 	Skipped int  `json:"skipped"`
 	Errored int  `json:"errored"`
+	XFailed int  `json:"xfailed,omitempty"`
+	XPassed int  `json:"xpassed,omitempty"`
 	AllPass bool `json:"all_pass"`
 }
 
@@ -0,0 +1,450 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Report signatures cover the canonical encoding of a SuiteReport (see
+// CanonicalReport) and come in two shapes: a detached ReportSignature, or
+// a DSSE envelope around an in-toto statement whose predicate is the report.
+const (
+	SignatureAlgorithm = "ed25519"
+
+	// DSSEPayloadType is the payload type of in-toto statements in DSSE.
+	DSSEPayloadType = "application/vnd.in-toto+json"
+	// InTotoStatementType is the in-toto Statement v1 "_type".
+	InTotoStatementType = "https://in-toto.io/Statement/v1"
+	// ReportPredicateType identifies a conformance report predicate.
+	ReportPredicateType = "https://openjobspec.org/attestations/conformance-report/v1"
+	// ReportSubjectName names the report in the statement's subject.
+	ReportSubjectName = "ojs-conformance-report"
+)
+
+// ReportSignature is a detached signature over a report.
+type ReportSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	// Digest is the hex SHA-256 of the canonical report, so a mismatch can
+	// be told apart from a bad signature.
+	Digest    string `json:"sha256"`
+	Signature string `json:"signature"` // base64
+}
+
+// Envelope is a DSSE envelope (https://github.com/secure-systems-lab/dsse).
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     string              `json:"payload"` // base64
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is one signature in a DSSE envelope.
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"` // base64
+}
+
+// Statement is an in-toto v1 statement attesting a conformance report.
+type Statement struct {
+	Type          string          `json:"_type"`
+	Subject       []Subject       `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// Subject is an in-toto statement subject.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// CanonicalReport returns the bytes that report signatures cover: the
+// report encoded as compact JSON with object keys sorted and no HTML
+// escaping. Formatting, key order and empty omitted fields in the file on
+// disk therefore do not affect the signature; any change of value does.
+func CanonicalReport(report SuiteReport) ([]byte, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ParseReportStrict decodes a report that is about to be verified. Unlike
+// LoadReport it rejects unknown fields: they would not be covered by the
+// signature.
+func ParseReportStrict(data []byte) (SuiteReport, error) {
+	var report SuiteReport
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&report); err != nil {
+		return report, err
+	}
+	if dec.More() {
+		return report, fmt.Errorf("unexpected data after report")
+	}
+	return report, nil
+}
+
+// KeyID identifies a public key: "ed25519:" and the first 16 hex digits of
+// the SHA-256 of its PKIX encoding.
+func KeyID(pub ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return SignatureAlgorithm + ":" + hex.EncodeToString(sum[:8])
+}
+
+// GenerateSigningKey creates an Ed25519 key pair and returns both halves
+// PEM-encoded: the private key as PKCS #8, the public key as PKIX. These
+// are the formats `openssl genpkey -algorithm ed25519` and `openssl pkey
+// -pubout` produce.
+func GenerateSigningKey() (privPEM, pubPEM []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), nil
+}
+
+// LoadSigningKey reads a PEM-encoded PKCS #8 Ed25519 private key.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: expected a PRIVATE KEY, found %s", path, block.Type)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+// LoadVerifyKey reads a PEM-encoded Ed25519 public key. A private key file
+// is accepted too and its public half used.
+func LoadVerifyKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := ParseVerifyKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pub, nil
+}
+
+// ParseVerifyKey is LoadVerifyKey for PEM data already in memory.
+func ParseVerifyKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		var priv any
+		if priv, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			if priv, ok := priv.(ed25519.PrivateKey); ok {
+				key = priv.Public()
+			}
+		}
+	default:
+		return nil, fmt.Errorf("expected a PUBLIC KEY, found %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 key")
+	}
+	return pub, nil
+}
+
+// MarshalVerifyKey PEM-encodes a public key as ParseVerifyKey reads it.
+func MarshalVerifyKey(pub ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	return block, nil
+}
+
+// SignReport creates a detached signature over the report.
+func SignReport(report SuiteReport, key ed25519.PrivateKey) (ReportSignature, error) {
+	if err := checkSubmitterKey(report, key.Public().(ed25519.PublicKey)); err != nil {
+		return ReportSignature{}, err
+	}
+	canonical, err := CanonicalReport(report)
+	if err != nil {
+		return ReportSignature{}, err
+	}
+	sum := sha256.Sum256(canonical)
+	return ReportSignature{
+		Algorithm: SignatureAlgorithm,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Digest:    hex.EncodeToString(sum[:]),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)),
+	}, nil
+}
+
+// VerifyReport checks a detached signature against the report and key.
+func VerifyReport(report SuiteReport, sig ReportSignature, pub ed25519.PublicKey) error {
+	if sig.Algorithm != SignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	if keyID := KeyID(pub); sig.KeyID != keyID {
+		return fmt.Errorf("signed with key %s, verifying with %s", sig.KeyID, keyID)
+	}
+	if err := checkSubmitterKey(report, pub); err != nil {
+		return err
+	}
+	canonical, err := CanonicalReport(report)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(canonical)
+	if digest := hex.EncodeToString(sum[:]); sig.Digest != digest {
+		return fmt.Errorf("report was modified after signing: sha256 %s, signed %s", digest, sig.Digest)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if !ed25519.Verify(pub, canonical, raw) {
+		return fmt.Errorf("signature does not match report")
+	}
+	return nil
+}
+
+// SignReportEnvelope wraps the report in an in-toto statement and signs it
+// as a DSSE envelope.
+func SignReportEnvelope(report SuiteReport, key ed25519.PrivateKey) (Envelope, error) {
+	if err := checkSubmitterKey(report, key.Public().(ed25519.PublicKey)); err != nil {
+		return Envelope{}, err
+	}
+	canonical, err := CanonicalReport(report)
+	if err != nil {
+		return Envelope{}, err
+	}
+	sum := sha256.Sum256(canonical)
+	payload, err := json.Marshal(Statement{
+		Type:          InTotoStatementType,
+		Subject:       []Subject{{Name: ReportSubjectName, Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])}}},
+		PredicateType: ReportPredicateType,
+		Predicate:     canonical,
+	})
+	if err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{
+		PayloadType: DSSEPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []EnvelopeSignature{{
+			KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(key, pae(DSSEPayloadType, payload))),
+		}},
+	}, nil
+}
+
+// VerifyReportEnvelope checks a DSSE envelope made by SignReportEnvelope
+// and returns the report it attests. The predicate must hash to the
+// statement's subject digest.
+func VerifyReportEnvelope(env Envelope, pub ed25519.PublicKey) (SuiteReport, error) {
+	var report SuiteReport
+	if env.PayloadType != DSSEPayloadType {
+		return report, fmt.Errorf("unsupported payload type %q", env.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return report, fmt.Errorf("malformed payload: %w", err)
+	}
+	keyID := KeyID(pub)
+	verified := false
+	for _, s := range env.Signatures {
+		if s.KeyID != "" && s.KeyID != keyID {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err == nil && ed25519.Verify(pub, pae(env.PayloadType, payload), raw) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return report, fmt.Errorf("no valid signature by key %s", keyID)
+	}
+
+	var st Statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return report, fmt.Errorf("malformed statement: %w", err)
+	}
+	if st.Type != InTotoStatementType || st.PredicateType != ReportPredicateType {
+		return report, fmt.Errorf("statement is not a conformance report attestation (%s, %s)", st.Type, st.PredicateType)
+	}
+	if report, err = ParseReportStrict(st.Predicate); err != nil {
+		return report, fmt.Errorf("malformed report predicate: %w", err)
+	}
+	digest, err := StatementDigest(st)
+	if err != nil {
+		return report, err
+	}
+	actual, err := ReportDigest(report)
+	if err != nil {
+		return report, err
+	}
+	if actual != digest {
+		return report, fmt.Errorf("report predicate does not match subject digest %s", digest)
+	}
+	return report, checkSubmitterKey(report, pub)
+}
+
+// SignedReport is a report with its detached signature, or a DSSE envelope,
+// which carries the report itself, together with the key that signed it.
+// It is the body of transparency log and portal report submissions.
+type SignedReport struct {
+	Report    json.RawMessage  `json:"report,omitempty"`
+	Signature *ReportSignature `json:"signature,omitempty"`
+	Envelope  *Envelope        `json:"envelope,omitempty"`
+	// PublicKey is the PEM-encoded Ed25519 key that signed the report.
+	PublicKey string `json:"public_key"`
+}
+
+// ErrUntrustedKey is returned by VerifySignedReport for reports signed by a
+// key outside the trusted set.
+var ErrUntrustedKey = errors.New("untrusted signing key")
+
+// VerifySignedReport checks that a signed report was signed by a trusted
+// key, indexed by KeyID, and that the signature verifies, and returns the
+// report and the key. A nil trusted set trusts every key. Errors other
+// than ErrUntrustedKey mean the submission is malformed or does not
+// verify.
+func VerifySignedReport(s SignedReport, trusted map[string]bool) (SuiteReport, ed25519.PublicKey, error) {
+	var report SuiteReport
+	pub, err := ParseVerifyKey([]byte(s.PublicKey))
+	if err != nil {
+		return report, nil, fmt.Errorf("public_key: %w", err)
+	}
+	if keyID := KeyID(pub); trusted != nil && !trusted[keyID] {
+		return report, nil, fmt.Errorf("%w: %s", ErrUntrustedKey, keyID)
+	}
+	switch {
+	case s.Envelope != nil:
+		if report, err = VerifyReportEnvelope(*s.Envelope, pub); err != nil {
+			return report, nil, err
+		}
+	case len(s.Report) > 0 && s.Signature != nil:
+		if report, err = ParseReportStrict(s.Report); err != nil {
+			return report, nil, fmt.Errorf("report: %w", err)
+		}
+		if err := VerifyReport(report, *s.Signature, pub); err != nil {
+			return report, nil, err
+		}
+	default:
+		return report, nil, fmt.Errorf("need an envelope, or a report and its signature")
+	}
+	return report, pub, nil
+}
+
+// StatementDigest returns the SHA-256 subject digest of the report in st.
+func StatementDigest(st Statement) (string, error) {
+	for _, s := range st.Subject {
+		if s.Name == ReportSubjectName && s.Digest["sha256"] != "" {
+			return s.Digest["sha256"], nil
+		}
+	}
+	return "", fmt.Errorf("statement has no sha256 subject named %q", ReportSubjectName)
+}
+
+// ReportDigest returns the hex SHA-256 of the canonical report, as found in
+// ReportSignature.Digest and the statement subject.
+func ReportDigest(report SuiteReport) (string, error) {
+	canonical, err := CanonicalReport(report)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkSubmitterKey rejects a report whose submitter names a different key.
+func checkSubmitterKey(report SuiteReport, pub ed25519.PublicKey) error {
+	if report.Submitter == nil || report.Submitter.KeyID == "" {
+		return nil
+	}
+	if keyID := KeyID(pub); report.Submitter.KeyID != keyID {
+		return fmt.Errorf("report submitter key_id is %s, but the key is %s", report.Submitter.KeyID, keyID)
+	}
+	return nil
+}
+
+// pae is the DSSE pre-authentication encoding.
+func pae(payloadType string, payload []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("DSSEv1 ")
+	b.WriteString(strconv.Itoa(len(payloadType)))
+	b.WriteByte(' ')
+	b.WriteString(payloadType)
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(len(payload)))
+	b.WriteByte(' ')
+	b.Write(payload)
+	return b.Bytes()
+}
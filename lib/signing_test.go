@@ -0,0 +1,230 @@
+package lib
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testSigningKey(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+	t.Helper()
+	privPEM, pubPEM, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	privPath, pubPath := filepath.Join(dir, "key.pem"), filepath.Join(dir, "key.pub.pem")
+	if err := os.WriteFile(privPath, privPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pubPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	priv, err := LoadSigningKey(privPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := LoadVerifyKey(pubPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromPriv, err := LoadVerifyKey(privPath); err != nil || !fromPriv.Equal(pub) {
+		t.Fatalf("public key from private key file: %v", err)
+	}
+	if _, err := LoadSigningKey(pubPath); err == nil {
+		t.Fatal("expected public key to be rejected as signing key")
+	}
+	return priv, pub
+}
+
+func signedTestReport() SuiteReport {
+	return SuiteReport{
+		TestSuiteVersion: "1.0",
+		Target:           "http://localhost:8080",
+		RunAt:            "2026-10-01T12:00:00Z",
+		Results:          ResultsSummary{Total: 2, Passed: 1, Failed: 1, ByLevel: map[int]LevelSummary{0: {Total: 2, Passed: 1, Failed: 1}}},
+		ConformantLevel:  -1,
+		Failures:         []TestResult{{TestID: "L0-ENV-002", Status: "fail", Failures: []Failure{{Field: "status", Expected: "201", Actual: "500"}}}},
+		Commit:           &CommitInfo{SHA: "abc123"},
+		Tests: []TestOutcome{
+			{TestID: "L0-ENV-001", Status: "pass"},
+			{TestID: "L0-ENV-002", Status: "fail"},
+		},
+	}
+}
+
+func TestCanonicalReportIgnoresFormatting(t *testing.T) {
+	report := signedTestReport()
+	indented, _ := json.MarshalIndent(report, "", "    ")
+	parsed, err := ParseReportStrict(indented)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := CanonicalReport(report)
+	b, _ := CanonicalReport(parsed)
+	if string(a) != string(b) {
+		t.Errorf("canonical forms differ:\n%s\n%s", a, b)
+	}
+	if strings.Contains(string(a), "\n") || !strings.HasPrefix(string(a), `{"commit":`) {
+		t.Errorf("expected compact JSON with sorted keys, got %s", a)
+	}
+
+	if _, err := ParseReportStrict([]byte(`{"target":"x","signed_off":true}`)); err == nil {
+		t.Error("expected unknown field to be rejected")
+	}
+}
+
+func TestSignAndVerifyReport(t *testing.T) {
+	priv, pub := testSigningKey(t)
+	report := signedTestReport()
+
+	sig, err := SignReport(report, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.KeyID != KeyID(pub) || sig.Algorithm != SignatureAlgorithm {
+		t.Errorf("unexpected signature metadata: %+v", sig)
+	}
+	if err := VerifyReport(report, sig, pub); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+
+	edits := map[string]func(r *SuiteReport){
+		"summary":     func(r *SuiteReport) { r.Results.Passed = 2 },
+		"level":       func(r *SuiteReport) { r.ConformantLevel = 0 },
+		"by level":    func(r *SuiteReport) { r.Results.ByLevel[0] = LevelSummary{Total: 2, Passed: 2} },
+		"test status": func(r *SuiteReport) { r.Tests[1].Status = "pass" },
+		"failures":    func(r *SuiteReport) { r.Failures = nil },
+		"commit":      func(r *SuiteReport) { r.Commit.SHA = "def456" },
+	}
+	for name, edit := range edits {
+		edited := signedTestReport()
+		edit(&edited)
+		if err := VerifyReport(edited, sig, pub); err == nil || !strings.Contains(err.Error(), "modified") {
+			t.Errorf("%s: expected modification to be detected, got %v", name, err)
+		}
+	}
+
+	forged := sig
+	forged.Signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+	if err := VerifyReport(report, forged, pub); err == nil {
+		t.Error("expected forged signature to be rejected")
+	}
+
+	_, otherPub := testSigningKey(t)
+	if err := VerifyReport(report, sig, otherPub); err == nil {
+		t.Error("expected signature to be rejected for another key")
+	}
+}
+
+func TestSignReportSubmitterKey(t *testing.T) {
+	priv, pub := testSigningKey(t)
+	report := signedTestReport()
+	report.Submitter = &SubmitterInfo{KeyID: "ed25519:0000000000000000"}
+	if _, err := SignReport(report, priv); err == nil {
+		t.Error("expected mismatched submitter key_id to be rejected")
+	}
+	report.Submitter.KeyID = KeyID(pub)
+	if _, err := SignReport(report, priv); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSignAndVerifyEnvelope(t *testing.T) {
+	priv, pub := testSigningKey(t)
+	report := signedTestReport()
+
+	env, err := SignReportEnvelope(report, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := VerifyReportEnvelope(env, pub)
+	if err != nil {
+		t.Fatalf("valid envelope rejected: %v", err)
+	}
+	want, _ := ReportDigest(report)
+	if digest, _ := ReportDigest(got); digest != want {
+		t.Errorf("envelope report digest %s, want %s", digest, want)
+	}
+
+	// Re-encode the statement with an edited predicate but keep the old
+	// signature.
+	payload, _ := base64.StdEncoding.DecodeString(env.Payload)
+	var st Statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Type != InTotoStatementType || st.PredicateType != ReportPredicateType {
+		t.Errorf("unexpected statement header: %s %s", st.Type, st.PredicateType)
+	}
+	edited := signedTestReport()
+	edited.Results.Passed = 2
+	st.Predicate, _ = CanonicalReport(edited)
+	tampered := env
+	payload, _ = json.Marshal(st)
+	tampered.Payload = base64.StdEncoding.EncodeToString(payload)
+	if _, err := VerifyReportEnvelope(tampered, pub); err == nil {
+		t.Error("expected tampered envelope to be rejected")
+	}
+
+	_, otherPub := testSigningKey(t)
+	if _, err := VerifyReportEnvelope(env, otherPub); err == nil {
+		t.Error("expected envelope to be rejected for another key")
+	}
+}
+
+func TestVerifySignedReport(t *testing.T) {
+	priv, pub := testSigningKey(t)
+	pubPEM, _ := MarshalVerifyKey(pub)
+	report := signedTestReport()
+	sig, err := SignReport(report, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := SignReportEnvelope(report, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := json.Marshal(report)
+	want, _ := ReportDigest(report)
+
+	for name, s := range map[string]SignedReport{
+		"detached": {Report: raw, Signature: &sig, PublicKey: string(pubPEM)},
+		"envelope": {Envelope: &env, PublicKey: string(pubPEM)},
+	} {
+		got, key, err := VerifySignedReport(s, map[string]bool{KeyID(pub): true})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if digest, _ := ReportDigest(got); digest != want || !key.Equal(pub) {
+			t.Errorf("%s: got report %s signed by %s", name, digest, KeyID(key))
+		}
+	}
+
+	edited := []byte(strings.Replace(string(raw), `"passed":1`, `"passed":2`, 1))
+	for name, tc := range map[string]struct {
+		s    SignedReport
+		want string
+	}{
+		"tampered":  {SignedReport{Report: edited, Signature: &sig, PublicKey: string(pubPEM)}, "modified after signing"},
+		"unsigned":  {SignedReport{Report: raw, PublicKey: string(pubPEM)}, "need an envelope"},
+		"no key":    {SignedReport{Envelope: &env}, "public_key"},
+		"malformed": {SignedReport{Report: []byte(`{"signed_off":true}`), Signature: &sig, PublicKey: string(pubPEM)}, "report:"},
+	} {
+		if _, _, err := VerifySignedReport(tc.s, nil); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", name, tc.want, err)
+		}
+	}
+
+	// The key is checked before the signature.
+	s := SignedReport{Report: edited, Signature: &sig, PublicKey: string(pubPEM)}
+	if _, _, err := VerifySignedReport(s, map[string]bool{"ed25519:other": true}); !errors.Is(err, ErrUntrustedKey) {
+		t.Errorf("expected ErrUntrustedKey, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -90,18 +91,79 @@ func AssertGreaterThan(threshold time.Duration, actual time.Duration) error {
 	return nil
 }
 
+// DefaultPollIntervalMs is the delay between attempts of a polled step
+// when its poll block does not set interval_ms.
+const DefaultPollIntervalMs = 250
+
 // WaitForCondition polls a check function until it passes or timeout is reached.
 func WaitForCondition(timeout time.Duration, interval time.Duration, check func() error) error {
+	_, err := pollUntil(timeout, interval, 1, 0, check)
+	return err
+}
+
+// pollUntil calls check until it returns nil or the next attempt would
+// start after timeout, sleeping interval between attempts and multiplying
+// the interval by backoff (capped at maxInterval, if set). It always makes
+// at least one attempt and returns how many were made.
+func pollUntil(timeout, interval time.Duration, backoff float64, maxInterval time.Duration, check func() error) (int, error) {
 	deadline := time.Now().Add(timeout)
-	var lastErr error
+	attempts := 0
 
-	for time.Now().Before(deadline) {
-		lastErr = check()
+	for {
+		attempts++
+		lastErr := check()
 		if lastErr == nil {
-			return nil
+			return attempts, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return attempts, fmt.Errorf("condition not met within %v: %w", timeout, lastErr)
 		}
 		time.Sleep(interval)
+
+		if backoff > 1 {
+			interval = time.Duration(float64(interval) * backoff)
+			if maxInterval > 0 && interval > maxInterval {
+				interval = maxInterval
+			}
+		}
 	}
+}
 
-	return fmt.Errorf("condition not met within %v: %w", timeout, lastErr)
+// PollStep runs attempt until it reports no failures or the step's poll
+// budget is exhausted, and records the attempt count and elapsed time on
+// the returned result. When polling gives up, the failures of the last
+// attempt are returned after a summary failure.
+func PollStep(step Step, cfg TimingConfig, attempt func() (*StepResult, []Failure)) (*StepResult, []Failure) {
+	p := step.Poll
+	timeout := time.Duration(cfg.MaxWaitMs) * time.Millisecond
+	if p.TimeoutMs > 0 && (timeout <= 0 || time.Duration(p.TimeoutMs)*time.Millisecond < timeout) {
+		timeout = time.Duration(p.TimeoutMs) * time.Millisecond
+	}
+	interval := time.Duration(DefaultPollIntervalMs) * time.Millisecond
+	if p.IntervalMs > 0 {
+		interval = time.Duration(p.IntervalMs) * time.Millisecond
+	}
+
+	var sr *StepResult
+	var failures []Failure
+	start := time.Now()
+	attempts, err := pollUntil(timeout, interval, p.Backoff, time.Duration(p.MaxIntervalMs)*time.Millisecond, func() error {
+		sr, failures = attempt()
+		if len(failures) > 0 {
+			return errors.New(failures[0].Message)
+		}
+		return nil
+	})
+	elapsed := time.Since(start)
+
+	sr.PollAttempts = attempts
+	sr.PollElapsedMs = elapsed.Milliseconds()
+	if err != nil {
+		failures = append([]Failure{{
+			StepID:  step.ID,
+			Field:   "poll",
+			Message: fmt.Sprintf("Assertions did not pass after %d attempt(s) in %dms", attempts, elapsed.Milliseconds()),
+		}}, failures...)
+	}
+	return sr, failures
 }
@@ -0,0 +1,69 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForCondition(t *testing.T) {
+	calls := 0
+	err := WaitForCondition(time.Second, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on third call, got calls=%d err=%v", calls, err)
+	}
+
+	err = WaitForCondition(10*time.Millisecond, 2*time.Millisecond, func() error {
+		return errors.New("never")
+	})
+	if err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestPollStepSucceeds(t *testing.T) {
+	step := Step{ID: "s", Poll: &PollConfig{TimeoutMs: 1000, IntervalMs: 1}}
+	attempts := 0
+	sr, failures := PollStep(step, DefaultTimingConfig(), func() (*StepResult, []Failure) {
+		attempts++
+		if attempts < 3 {
+			return &StepResult{StepID: "s"}, []Failure{{StepID: "s", Message: "state is active"}}
+		}
+		return &StepResult{StepID: "s", StatusCode: 200}, nil
+	})
+
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %+v", failures)
+	}
+	if sr.StatusCode != 200 || sr.PollAttempts != 3 {
+		t.Errorf("expected last result after 3 attempts, got %+v", sr)
+	}
+}
+
+func TestPollStepGivesUp(t *testing.T) {
+	// MaxWaitMs caps the step's own timeout.
+	cfg := DefaultTimingConfig()
+	cfg.MaxWaitMs = 20
+	step := Step{ID: "s", Poll: &PollConfig{TimeoutMs: 60000, IntervalMs: 2, Backoff: 2, MaxIntervalMs: 5}}
+
+	start := time.Now()
+	sr, failures := PollStep(step, cfg, func() (*StepResult, []Failure) {
+		return &StepResult{StepID: "s"}, []Failure{{StepID: "s", Message: "state is active"}}
+	})
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected MaxWaitMs to cap polling, took %v", elapsed)
+	}
+	if len(failures) != 2 || failures[0].Field != "poll" || failures[1].Message != "state is active" {
+		t.Errorf("expected summary failure followed by last attempt's failure, got %+v", failures)
+	}
+	if sr.PollAttempts < 2 {
+		t.Errorf("expected several attempts, got %d", sr.PollAttempts)
+	}
+}
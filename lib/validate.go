@@ -0,0 +1,541 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Field sets are derived from the struct tags so that strict validation
+// stays in step with what the loader actually decodes.
+var (
+	testCaseFields  = jsonFieldNames(TestCase{})
+	setupFields     = jsonFieldNames(Setup{})
+	stepFields      = jsonFieldNames(Step{})
+	assertionFields = jsonFieldNames(Assertions{})
+	timingFields    = jsonFieldNames(TimingAssertion{})
+	pollFields      = jsonFieldNames(PollConfig{})
+	headerMatchOps  = map[string]bool{"$match": true, "$eq": true}
+	exclusiveObjOps = map[string]bool{"$match": true, "$in": true, "$size": true, "$or": true, "$empty": true}
+	matcherKeywords = map[string]bool{
+		"any":                 true,
+		"absent":              true,
+		"exists":              true,
+		"string:nonempty":     true,
+		"string:non_empty":    true,
+		"string:uuid":         true,
+		"string:uuidv7":       true,
+		"string:datetime":     true,
+		"number:positive":     true,
+		"number:non_negative": true,
+		"array:nonempty":      true,
+		"array:empty":         true,
+	}
+	matcherIntPrefixes  = []string{"array:min_length:", "array:min:", "array:length:"}
+	matcherTextPrefixes = []string{"contains:", "not_contains:", "string:contains:"}
+	matcherFamilies     = []string{"string:", "number:", "array:"}
+)
+
+// ValidateTestFile checks a suite file for keys the loader would silently
+// drop and for matchers that MatchAssertion would not interpret as written
+// (e.g. a misspelled "string:uuid7" that would be compared literally).
+// Every problem is reported with the file path and a JSON pointer.
+func ValidateTestFile(path string, data []byte) []Problem {
+	v := &validator{file: path}
+
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		v.add("", "invalid JSON object: %v", err)
+		return v.problems
+	}
+
+	v.checkKeys("", root, testCaseFields)
+	if raw, ok := root["setup"]; ok {
+		v.checkSetup("/setup", raw)
+	}
+	if raw, ok := root["steps"]; ok {
+		v.checkSteps("/steps", raw)
+	}
+	if raw, ok := root["teardown"]; ok {
+		v.checkSetup("/teardown", raw)
+	}
+	return v.problems
+}
+
+// ValidateMatcher reports problems with a single body matcher value.
+// The returned pointers are relative to the matcher itself.
+func ValidateMatcher(matcher json.RawMessage) []Problem {
+	v := &validator{}
+	v.checkMatcher("", matcher)
+	return v.problems
+}
+
+type validator struct {
+	file     string
+	problems []Problem
+}
+
+func (v *validator) add(ptr, format string, args ...any) {
+	v.problems = append(v.problems, Problem{
+		File:    v.file,
+		Pointer: ptr,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkKeys reports keys of obj that are not in allowed.
+func (v *validator) checkKeys(ptr string, obj map[string]json.RawMessage, allowed map[string]bool) {
+	for _, key := range sortedKeys(obj) {
+		if !allowed[key] {
+			v.add(pointerJoin(ptr, key), "unknown key %q", key)
+		}
+	}
+}
+
+func (v *validator) object(ptr string, raw json.RawMessage) (map[string]json.RawMessage, bool) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+		v.add(ptr, "expected object")
+		return nil, false
+	}
+	return obj, true
+}
+
+func (v *validator) checkSetup(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	v.checkKeys(ptr, obj, setupFields)
+	if steps, ok := obj["steps"]; ok {
+		v.checkSteps(ptr+"/steps", steps)
+	}
+}
+
+func (v *validator) checkSteps(ptr string, raw json.RawMessage) {
+	var steps []json.RawMessage
+	if err := json.Unmarshal(raw, &steps); err != nil {
+		v.add(ptr, "expected array of steps")
+		return
+	}
+	for i, step := range steps {
+		v.checkStep(fmt.Sprintf("%s/%d", ptr, i), step)
+	}
+}
+
+func (v *validator) checkStep(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	v.checkKeys(ptr, obj, stepFields)
+	if a, ok := obj["assertions"]; ok {
+		v.checkAssertions(ptr+"/assertions", a)
+	}
+	var action string
+	_ = json.Unmarshal(obj["action"], &action)
+	if (Step{Action: action}).IsAssert() {
+		v.checkAssertStep(ptr, obj)
+	}
+	if p, ok := obj["poll"]; ok {
+		if strings.EqualFold(action, "WAIT") || (Step{Action: action}).IsAssert() {
+			v.add(ptr+"/poll", "%s steps cannot be polled", strings.ToUpper(action))
+		} else if pobj, ok := v.object(ptr+"/poll", p); ok {
+			v.checkKeys(ptr+"/poll", pobj, pollFields)
+			var cfg PollConfig
+			if err := json.Unmarshal(p, &cfg); err != nil {
+				v.add(ptr+"/poll", "invalid poll block: %v", err)
+			} else if cfg.Backoff != 0 && cfg.Backoff < 1 {
+				v.add(ptr+"/poll/backoff", "backoff must be at least 1")
+			}
+		}
+	}
+	if e, ok := obj["expect"]; ok {
+		v.checkExpect(ptr+"/expect", e)
+	}
+	if c, ok := obj["capture"]; ok {
+		var capture map[string]string
+		if err := json.Unmarshal(c, &capture); err != nil {
+			v.add(ptr+"/capture", "expected object of variable name to JSONPath")
+		}
+		for _, name := range sortedKeys(capture) {
+			if !varRefPattern.MatchString("${" + name + "}") {
+				v.add(pointerJoin(ptr+"/capture", name), "invalid variable name %q", name)
+			}
+		}
+	}
+}
+
+// checkAssertStep reports fields that an ASSERT step would ignore, since it
+// sends no request and has no response of its own.
+func (v *validator) checkAssertStep(ptr string, obj map[string]json.RawMessage) {
+	for _, key := range []string{"path", "headers", "body", "expect"} {
+		if _, ok := obj[key]; ok {
+			v.add(pointerJoin(ptr, key), "ASSERT steps do not support %q", key)
+		}
+	}
+	var a map[string]json.RawMessage
+	if json.Unmarshal(obj["assertions"], &a) != nil || a == nil {
+		v.add(ptr, "ASSERT step needs body or body_absent assertions")
+		return
+	}
+	for _, key := range sortedKeys(a) {
+		if key != "body" && key != "body_absent" && assertionFields[key] {
+			v.add(pointerJoin(ptr+"/assertions", key), "ASSERT steps only support body and body_absent assertions")
+		}
+	}
+}
+
+func (v *validator) checkAssertions(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	v.checkKeys(ptr, obj, assertionFields)
+	if s, ok := obj["status"]; ok {
+		v.checkStatus(ptr+"/status", s)
+	}
+	if h, ok := obj["headers"]; ok {
+		v.checkHeaders(ptr+"/headers", h)
+	}
+	if t, ok := obj["timing_ms"]; ok {
+		if tobj, ok := v.object(ptr+"/timing_ms", t); ok {
+			v.checkKeys(ptr+"/timing_ms", tobj, timingFields)
+		}
+	}
+	if b, ok := obj["body"]; ok {
+		v.checkBody(ptr+"/body", b)
+	}
+}
+
+// checkExpect mirrors the keys accepted by Step.normalizeExpect.
+func (v *validator) checkExpect(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	for _, key := range sortedKeys(obj) {
+		val := obj[key]
+		kptr := pointerJoin(ptr, key)
+		switch {
+		case key == "status":
+			v.checkStatus(kptr, val)
+		case key == "status_in":
+			var codes []int
+			if err := json.Unmarshal(val, &codes); err != nil {
+				v.add(kptr, "expected array of status codes")
+			}
+		case key == "headers":
+			v.checkHeaders(kptr, val)
+		case key == "body":
+			if fields, ok := v.object(kptr, val); ok {
+				for _, f := range sortedKeys(fields) {
+					v.checkMatcher(pointerJoin(kptr, f), fields[f])
+				}
+			}
+		case strings.HasPrefix(key, "body."), strings.HasPrefix(key, "$."):
+			v.checkMatcher(kptr, val)
+		default:
+			v.add(kptr, "unknown expect key %q", key)
+		}
+	}
+}
+
+// checkStatus mirrors the formats accepted by the runners'
+// evaluateStatusAssertion.
+func (v *validator) checkStatus(ptr string, raw json.RawMessage) {
+	var code int
+	if json.Unmarshal(raw, &code) == nil {
+		return
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if strings.HasPrefix(s, "one_of:") {
+			for _, c := range strings.Split(s[len("one_of:"):], ",") {
+				if _, err := strconv.Atoi(strings.TrimSpace(c)); err != nil {
+					v.add(ptr, "invalid status code %q in one_of matcher", strings.TrimSpace(c))
+				}
+			}
+			return
+		}
+		v.checkMatcher(ptr, raw)
+		return
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) == nil && obj != nil {
+		if len(obj) != 1 || obj["$in"] == nil {
+			v.add(ptr, "status object must be {\"$in\": [...]}")
+			return
+		}
+		var codes []int
+		if err := json.Unmarshal(obj["$in"], &codes); err != nil {
+			v.add(ptr+"/$in", "expected array of status codes")
+		}
+		return
+	}
+	v.add(ptr, "unsupported status assertion %s", string(raw))
+}
+
+func (v *validator) checkHeaders(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	for _, name := range sortedKeys(obj) {
+		hptr := pointerJoin(ptr, name)
+		var s string
+		if json.Unmarshal(obj[name], &s) == nil {
+			continue
+		}
+		var m map[string]string
+		if err := json.Unmarshal(obj[name], &m); err != nil || len(m) != 1 {
+			v.add(hptr, "header matcher must be a string, {\"$match\": ...} or {\"$eq\": ...}")
+			continue
+		}
+		for op, val := range m {
+			if !headerMatchOps[op] {
+				v.add(pointerJoin(hptr, op), "unsupported header operator %q", op)
+			} else if op == "$match" {
+				if _, err := regexp.Compile(val); err != nil {
+					v.add(pointerJoin(hptr, op), "invalid regex: %v", err)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkBody(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	for _, path := range sortedKeys(obj) {
+		pptr := pointerJoin(ptr, path)
+		switch {
+		case path == "$or":
+			var alts []json.RawMessage
+			if err := json.Unmarshal(obj[path], &alts); err != nil {
+				v.add(pptr, "$or must be an array of body assertion objects")
+				continue
+			}
+			for i, alt := range alts {
+				v.checkBody(fmt.Sprintf("%s/%d", pptr, i), alt)
+			}
+		case strings.HasPrefix(path, "$") && path != "$" && !strings.HasPrefix(path, "$.") && !strings.HasPrefix(path, "$["):
+			v.add(pptr, "unsupported top-level body operator %q", path)
+		default:
+			v.checkMatcher(pptr, obj[path])
+		}
+	}
+}
+
+// checkMatcher mirrors the dispatch in MatchAssertion.
+func (v *validator) checkMatcher(ptr string, raw json.RawMessage) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if msg := checkStringMatcher(s); msg != "" {
+			v.add(ptr, "%s", msg)
+		}
+		return
+	}
+	var arr []json.RawMessage
+	if json.Unmarshal(raw, &arr) == nil && arr != nil {
+		for i, el := range arr {
+			v.checkMatcher(fmt.Sprintf("%s/%d", ptr, i), el)
+		}
+		return
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) == nil && obj != nil {
+		v.checkObjectMatcher(ptr, obj)
+	}
+	// Numbers, booleans and null are always valid matchers.
+}
+
+// checkObjectMatcher mirrors the operator precedence in matchObjectAssertion.
+func (v *validator) checkObjectMatcher(ptr string, obj map[string]json.RawMessage) {
+	var ops, fields []string
+	for _, key := range sortedKeys(obj) {
+		if strings.HasPrefix(key, "$") {
+			ops = append(ops, key)
+		} else {
+			fields = append(fields, key)
+		}
+	}
+
+	if len(ops) == 0 {
+		if len(fields) == 1 && fields[0] == "range" {
+			v.checkRange(pointerJoin(ptr, "range"), obj["range"])
+			return
+		}
+		for _, f := range fields {
+			v.checkMatcher(pointerJoin(ptr, f), obj[f])
+		}
+		return
+	}
+
+	if len(fields) > 0 {
+		v.add(ptr, "matcher mixes operators %v with field keys %v", ops, fields)
+		return
+	}
+
+	for _, op := range ops {
+		if !exclusiveObjOps[op] && !operatorNames[op] && op != "$exists" {
+			v.add(pointerJoin(ptr, op), "unknown operator %q", op)
+			return
+		}
+	}
+	// $match, $in, $size, $or and $empty are evaluated on their own; any
+	// other operator next to them would be silently ignored.
+	for _, op := range ops {
+		if exclusiveObjOps[op] && len(ops) > 1 {
+			v.add(ptr, "operator %q cannot be combined with %v", op, ops)
+			return
+		}
+	}
+
+	for _, op := range ops {
+		optr := pointerJoin(ptr, op)
+		operand := obj[op]
+		switch op {
+		case "$exists", "$empty":
+			var b bool
+			if err := json.Unmarshal(operand, &b); err != nil {
+				v.add(optr, "%s expects a boolean", op)
+			}
+		case "$match":
+			var pattern string
+			if err := json.Unmarshal(operand, &pattern); err != nil {
+				v.add(optr, "$match expects a regex string")
+			} else if _, err := regexp.Compile(pattern); err != nil {
+				v.add(optr, "invalid regex: %v", err)
+			}
+		case "$in", "$or":
+			var alts []json.RawMessage
+			if err := json.Unmarshal(operand, &alts); err != nil {
+				v.add(optr, "%s expects an array", op)
+				continue
+			}
+			for i, alt := range alts {
+				v.checkMatcher(fmt.Sprintf("%s/%d", optr, i), alt)
+			}
+		case "$size":
+			var n int
+			if json.Unmarshal(operand, &n) == nil {
+				continue
+			}
+			var sizeObj map[string]json.RawMessage
+			if err := json.Unmarshal(operand, &sizeObj); err != nil || !isOperatorObject(sizeObj) {
+				v.add(optr, "$size expects an integer or comparison operators")
+				continue
+			}
+			v.checkObjectMatcher(optr, sizeObj)
+		case "$type":
+			var name string
+			if err := json.Unmarshal(operand, &name); err != nil || !jsonTypeNames[name] {
+				v.add(optr, "invalid $type value %s", string(operand))
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			var n float64
+			var s string
+			if json.Unmarshal(operand, &n) != nil && json.Unmarshal(operand, &s) != nil {
+				v.add(optr, "%s expects a number or string", op)
+			}
+		}
+	}
+}
+
+func (v *validator) checkRange(ptr string, raw json.RawMessage) {
+	obj, ok := v.object(ptr, raw)
+	if !ok {
+		return
+	}
+	for _, key := range sortedKeys(obj) {
+		var n float64
+		switch {
+		case key != "min" && key != "max":
+			v.add(pointerJoin(ptr, key), "unknown range bound %q", key)
+		case json.Unmarshal(obj[key], &n) != nil:
+			v.add(pointerJoin(ptr, key), "range bound must be a number")
+		}
+	}
+}
+
+// checkStringMatcher returns a description of why s would not be
+// interpreted as intended by matchStringAssertion, or "" if it is fine.
+// Keep in sync with matchStringAssertion.
+func checkStringMatcher(s string) string {
+	// Templates resolve to literal values at run time.
+	if strings.Contains(s, "{{") || strings.Contains(s, "${") {
+		return ""
+	}
+	if matcherKeywords[s] {
+		return ""
+	}
+	for _, prefix := range matcherIntPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			if _, err := strconv.Atoi(s[len(prefix):]); err != nil {
+				return fmt.Sprintf("matcher %q: %q is not an integer", s, s[len(prefix):])
+			}
+			return ""
+		}
+	}
+	for _, prefix := range matcherTextPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return ""
+		}
+	}
+	if rangePattern.MatchString(s) || lengthPattern.MatchString(s) || approxPattern.MatchString(s) {
+		return ""
+	}
+	if strings.HasPrefix(s, "string:pattern(") && strings.HasSuffix(s, ")") {
+		if _, err := regexp.Compile(s[len("string:pattern(") : len(s)-1]); err != nil {
+			return fmt.Sprintf("matcher %q: invalid regex: %v", s, err)
+		}
+		return ""
+	}
+	if strings.HasPrefix(s, "~") {
+		return fmt.Sprintf("malformed approximate matcher %q", s)
+	}
+	for _, family := range matcherFamilies {
+		if strings.HasPrefix(s, family) {
+			return fmt.Sprintf("unknown matcher %q (would be compared as a literal string)", s)
+		}
+	}
+	return ""
+}
+
+// jsonFieldNames returns the set of JSON keys a struct decodes.
+func jsonFieldNames(v any) map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// pointerJoin appends a reference token to an RFC 6901 JSON pointer.
+func pointerJoin(ptr, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return ptr + "/" + token
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,197 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTestFileValid(t *testing.T) {
+	data := []byte(`{
+		"test_id": "L0-TEST-001",
+		"name": "valid",
+		"level": 0,
+		"category": "envelope",
+		"steps": [
+			{
+				"id": "enqueue",
+				"action": "POST",
+				"path": "/ojs/v1/jobs",
+				"assertions": {
+					"status": 201,
+					"headers": {"Content-Type": {"$match": "json"}},
+					"body": {
+						"$.job.id": "string:uuidv7",
+						"$.job.attempt": {"$gte": 0, "$lt": 5},
+						"$.job.tags": {"$size": {"$gte": 1}},
+						"$.job.meta": {"range": {"min": 1, "max": 2}},
+						"$.job.args": ["string:nonempty", 42]
+					},
+					"timing_ms": {"less_than": 500}
+				},
+				"capture": {"job_id": "$.job.id"}
+			},
+			{
+				"id": "fetch",
+				"action": "GET",
+				"path": "/ojs/v1/jobs/${job_id}",
+				"expect": {"status": 200, "body.state": "available"}
+			}
+		]
+	}`)
+
+	if problems := ValidateTestFile("valid.json", data); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestValidateTestFileUnknownKeys(t *testing.T) {
+	data := []byte(`{
+		"test_id": "L0-TEST-002",
+		"level": 0,
+		"preconditions": {},
+		"steps": [
+			{
+				"id": "s1",
+				"action": "GET",
+				"path": "/x",
+				"asserts": {},
+				"assertions": {"status": 200, "bodyy": {}},
+				"expect": {"stauts": 200}
+			}
+		]
+	}`)
+
+	problems := ValidateTestFile("f.json", data)
+	want := []string{
+		"/preconditions",
+		"/steps/0/asserts",
+		"/steps/0/assertions/bodyy",
+		"/steps/0/expect/stauts",
+	}
+	assertPointers(t, problems, want)
+	for _, p := range problems {
+		if p.File != "f.json" {
+			t.Errorf("expected file f.json, got %q", p.File)
+		}
+	}
+}
+
+func TestValidateTestFileAssertStep(t *testing.T) {
+	data := []byte(`{
+		"test_id": "L0-TEST-003",
+		"level": 0,
+		"steps": [
+			{"id": "ok", "action": "ASSERT", "assertions": {"body": {"$.steps.a.response.status": 200}}},
+			{"id": "bad", "action": "ASSERT", "path": "/x", "assertions": {"status": 200, "body": {}}},
+			{"id": "empty", "action": "ASSERT"}
+		]
+	}`)
+
+	assertPointers(t, ValidateTestFile("f.json", data), []string{
+		"/steps/1/path",
+		"/steps/1/assertions/status",
+		"/steps/2",
+	})
+}
+
+func TestValidateTestFilePoll(t *testing.T) {
+	data := []byte(`{
+		"test_id": "L0-TEST-004",
+		"level": 0,
+		"steps": [
+			{"id": "ok", "action": "GET", "path": "/x", "poll": {"timeout_ms": 5000, "interval_ms": 100, "backoff": 1.5}},
+			{"id": "bad", "action": "GET", "path": "/x", "poll": {"interval": 100, "backoff": 0.5}},
+			{"id": "wait", "action": "WAIT", "duration_ms": 10, "poll": {}}
+		]
+	}`)
+
+	assertPointers(t, ValidateTestFile("f.json", data), []string{
+		"/steps/1/poll/interval",
+		"/steps/1/poll/backoff",
+		"/steps/2/poll",
+	})
+}
+
+func TestValidateMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher string
+		want    []string
+	}{
+		{"literal string", `"available"`, nil},
+		{"known keyword", `"string:uuid"`, nil},
+		{"number", `3`, nil},
+		{"template", `"{{steps.a.response.body.id}}"`, nil},
+		{"misspelled keyword", `"string:uuid7"`, []string{""}},
+		{"non-integer length", `"array:min_length:two"`, []string{""}},
+		{"bad pattern", `"string:pattern(([)"`, []string{""}},
+		{"malformed approx", `"~abc"`, []string{""}},
+		{"unknown operator", `{"$regex": "x"}`, []string{"/$regex"}},
+		{"mixed ops and fields", `{"$exists": true, "id": 1}`, []string{""}},
+		{"exclusive op combined", `{"$in": [1, 2], "$ne": 3}`, []string{""}},
+		{"bad $type", `{"$type": "int"}`, []string{"/$type"}},
+		{"nested in $or", `{"$or": ["any", "number:big"]}`, []string{"/$or/1"}},
+		{"nested field", `{"job": {"id": "string:uid"}}`, []string{"/job/id"}},
+		{"escaped pointer", `{"a/b": "array:nope"}`, []string{"/a~1b"}},
+		{"bad range bound", `{"range": {"min": 1, "maximum": 2}}`, []string{"/range/maximum"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertPointers(t, ValidateMatcher(json.RawMessage(tt.matcher)), tt.want)
+		})
+	}
+}
+
+func TestLoadTestsStrict(t *testing.T) {
+	dir := t.TempDir()
+	writeSuiteFile(t, dir, "a.json", `{"test_id": "B", "level": 0, "steps": [{"id": "s", "action": "GET", "path": "/", "assertions": {"status": 200}}]}`)
+	writeSuiteFile(t, dir, "b.json", `{"test_id": "A", "level": 0, "typo": 1, "steps": [{"id": "s", "action": "GET", "path": "/", "assertions": {"body": {"$.x": "string:uuid7"}}}]}`)
+
+	tests, err := LoadTests(dir, LoadOptions{})
+	if err != nil {
+		t.Fatalf("lenient load failed: %v", err)
+	}
+	if len(tests) != 2 || tests[0].TestID != "A" || tests[1].TestID != "B" {
+		t.Errorf("expected tests sorted by ID, got %+v", tests)
+	}
+
+	_, err = LoadTests(dir, LoadOptions{Strict: true})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", verr.Problems)
+	}
+	if !strings.Contains(err.Error(), "b.json#/typo") {
+		t.Errorf("expected error to reference b.json#/typo, got %q", err.Error())
+	}
+}
+
+func assertPointers(t *testing.T, problems []Problem, want []string) {
+	t.Helper()
+	got := make(map[string]bool)
+	for _, p := range problems {
+		got[p.Pointer] = true
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problem(s) at %v, got %v", len(want), want, problems)
+	}
+	for _, ptr := range want {
+		if !got[ptr] {
+			t.Errorf("expected problem at %q, got %v", ptr, problems)
+		}
+	}
+}
+
+func writeSuiteFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,202 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// varRefPattern matches ${name} variable references.
+var varRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Vars holds named values captured from step responses via a step's
+// "capture" block. It is safe for concurrent use, since steps linked by
+// parallel_with may capture at the same time.
+type Vars struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewVars returns an empty variable set.
+func NewVars() *Vars {
+	return &Vars{values: make(map[string]string)}
+}
+
+// Set stores a variable value.
+func (v *Vars) Set(name, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[name] = value
+}
+
+// Get returns a variable value and whether it was captured.
+func (v *Vars) Get(name string) (string, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	val, ok := v.values[name]
+	return val, ok
+}
+
+// Interpolate replaces ${name} references with captured values.
+// References to variables that were never captured are left unchanged,
+// mirroring how unresolved {{steps...}} templates are handled.
+func (v *Vars) Interpolate(input string) string {
+	if v == nil || !strings.Contains(input, "${") {
+		return input
+	}
+	return varRefPattern.ReplaceAllStringFunc(input, func(match string) string {
+		name := varRefPattern.FindStringSubmatch(match)[1]
+		if val, ok := v.Get(name); ok {
+			return val
+		}
+		return match
+	})
+}
+
+// InterpolateRaw applies Interpolate to a raw JSON value.
+func (v *Vars) InterpolateRaw(raw json.RawMessage) json.RawMessage {
+	s := string(raw)
+	resolved := v.Interpolate(s)
+	if resolved == s {
+		return raw
+	}
+	return json.RawMessage(resolved)
+}
+
+// Capture evaluates a step's capture block against the parsed response
+// body and stores the results. A path that does not resolve is reported
+// as a failure so that later steps don't silently run against a literal
+// ${name}.
+func (v *Vars) Capture(step Step, parsed map[string]any) []Failure {
+	var failures []Failure
+	for name, path := range step.Capture {
+		val, err := ResolveJSONPath(path, parsed)
+		if err != nil || val == nil {
+			msg := fmt.Sprintf("Capture %q: path %q did not resolve", name, path)
+			if err != nil {
+				msg = fmt.Sprintf("Capture %q: path %q: %v", name, path, err)
+			}
+			failures = append(failures, Failure{
+				StepID:  step.ID,
+				Field:   "capture:" + name,
+				Message: msg,
+			})
+			continue
+		}
+		v.Set(name, formatVarValue(val))
+	}
+	return failures
+}
+
+// formatVarValue renders a captured value for substitution, using the
+// same conversion rules as {{steps...}} template references.
+func formatVarValue(val any) string {
+	switch x := val.(type) {
+	case string:
+		return x
+	case float64:
+		if x == float64(int64(x)) {
+			return fmt.Sprintf("%d", int64(x))
+		}
+		return fmt.Sprintf("%v", x)
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
+
+// NormalizeExpect folds every "expect" block in the test (setup, steps and
+// teardown) into the step's Assertions, so runners only have to evaluate
+// one assertion shape. Supported expect keys are:
+//
+//	"status", "status_in", "headers"   same meaning as in assertions
+//	"body": {...}                      each key k becomes "$.k"
+//	"body.<path>"                      becomes "$.<path>"
+//	"$.<path>"                         used as-is
+func (tc *TestCase) NormalizeExpect() error {
+	normalize := func(steps []Step) error {
+		for i := range steps {
+			if err := steps[i].normalizeExpect(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tc.Setup != nil {
+		if err := normalize(tc.Setup.Steps); err != nil {
+			return err
+		}
+	}
+	if err := normalize(tc.Steps); err != nil {
+		return err
+	}
+	if tc.Teardown != nil {
+		if err := normalize(tc.Teardown.Steps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Step) normalizeExpect() error {
+	if len(s.Expect) == 0 {
+		return nil
+	}
+	if s.Assertions == nil {
+		s.Assertions = &Assertions{}
+	}
+	a := s.Assertions
+
+	setBody := func(path string, matcher json.RawMessage) error {
+		if a.Body == nil {
+			a.Body = make(map[string]json.RawMessage)
+		}
+		if _, dup := a.Body[path]; dup {
+			return fmt.Errorf("step %q: expect %q conflicts with an existing body assertion", s.ID, path)
+		}
+		a.Body[path] = matcher
+		return nil
+	}
+
+	for key, val := range s.Expect {
+		switch {
+		case key == "status":
+			if len(a.Status) > 0 {
+				return fmt.Errorf("step %q: expect.status conflicts with assertions.status", s.ID)
+			}
+			a.Status = val
+		case key == "status_in":
+			if err := json.Unmarshal(val, &a.StatusIn); err != nil {
+				return fmt.Errorf("step %q: expect.status_in: %w", s.ID, err)
+			}
+		case key == "headers":
+			if len(a.Headers) > 0 {
+				return fmt.Errorf("step %q: expect.headers conflicts with assertions.headers", s.ID)
+			}
+			a.Headers = val
+		case key == "body":
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(val, &fields); err != nil {
+				return fmt.Errorf("step %q: expect.body must be an object: %w", s.ID, err)
+			}
+			for field, matcher := range fields {
+				if err := setBody("$."+field, matcher); err != nil {
+					return err
+				}
+			}
+		case strings.HasPrefix(key, "body."):
+			if err := setBody("$."+key[len("body."):], val); err != nil {
+				return err
+			}
+		case strings.HasPrefix(key, "$."):
+			if err := setBody(key, val); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("step %q: unsupported expect key %q", s.ID, key)
+		}
+	}
+	return nil
+}
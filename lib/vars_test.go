@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVarsInterpolate(t *testing.T) {
+	v := NewVars()
+	v.Set("job_id", "abc-123")
+
+	got := v.Interpolate("/ojs/v1/jobs/${job_id}/checkpoint")
+	if got != "/ojs/v1/jobs/abc-123/checkpoint" {
+		t.Errorf("unexpected interpolation: %q", got)
+	}
+
+	// Unknown variables are left unchanged
+	got = v.Interpolate("/ojs/v1/jobs/${other}")
+	if got != "/ojs/v1/jobs/${other}" {
+		t.Errorf("expected unknown var to be preserved, got %q", got)
+	}
+}
+
+func TestVarsInterpolateNil(t *testing.T) {
+	var v *Vars
+	if got := v.Interpolate("${job_id}"); got != "${job_id}" {
+		t.Errorf("nil Vars should not interpolate, got %q", got)
+	}
+}
+
+func TestVarsCapture(t *testing.T) {
+	v := NewVars()
+	step := Step{ID: "step-1", Capture: map[string]string{
+		"job_id":  "$.job.id",
+		"attempt": "$.job.attempt",
+	}}
+	parsed := map[string]any{
+		"job": map[string]any{"id": "abc-123", "attempt": 2.0},
+	}
+
+	if failures := v.Capture(step, parsed); len(failures) != 0 {
+		t.Fatalf("unexpected failures: %+v", failures)
+	}
+	if got, _ := v.Get("job_id"); got != "abc-123" {
+		t.Errorf("expected job_id abc-123, got %q", got)
+	}
+	if got, _ := v.Get("attempt"); got != "2" {
+		t.Errorf("expected attempt 2, got %q", got)
+	}
+}
+
+func TestVarsCaptureMissingPath(t *testing.T) {
+	v := NewVars()
+	step := Step{ID: "step-1", Capture: map[string]string{"job_id": "$.id"}}
+
+	failures := v.Capture(step, map[string]any{"job": map[string]any{"id": "x"}})
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 failure, got %d", len(failures))
+	}
+	if failures[0].Field != "capture:job_id" {
+		t.Errorf("unexpected failure field %q", failures[0].Field)
+	}
+	if _, ok := v.Get("job_id"); ok {
+		t.Error("expected job_id to remain uncaptured")
+	}
+}
+
+func TestNormalizeExpect(t *testing.T) {
+	var tc TestCase
+	data := `{
+		"test_id": "EXT-DUR-001",
+		"steps": [{
+			"id": "step-1",
+			"action": "GET",
+			"path": "/ojs/v1/jobs/${job_id}/checkpoint",
+			"expect": {
+				"status": 200,
+				"body.state.cursor": "page-500",
+				"body": {"sequence": 1}
+			}
+		}]
+	}`
+	if err := json.Unmarshal([]byte(data), &tc); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.NormalizeExpect(); err != nil {
+		t.Fatalf("NormalizeExpect: %v", err)
+	}
+
+	a := tc.Steps[0].Assertions
+	if a == nil {
+		t.Fatal("expected assertions to be populated")
+	}
+	if string(a.Status) != "200" {
+		t.Errorf("expected status 200, got %s", a.Status)
+	}
+	if string(a.Body["$.state.cursor"]) != `"page-500"` {
+		t.Errorf("expected $.state.cursor matcher, got %s", a.Body["$.state.cursor"])
+	}
+	if string(a.Body["$.sequence"]) != "1" {
+		t.Errorf("expected $.sequence matcher, got %s", a.Body["$.sequence"])
+	}
+}
+
+func TestNormalizeExpectErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		step Step
+	}{
+		{"unknown key", Step{ID: "s", Expect: map[string]json.RawMessage{"stauts": raw("200")}}},
+		{"body not object", Step{ID: "s", Expect: map[string]json.RawMessage{"body": raw(`"x"`)}}},
+		{"status conflict", Step{
+			ID:         "s",
+			Expect:     map[string]json.RawMessage{"status": raw("200")},
+			Assertions: &Assertions{Status: raw("201")},
+		}},
+		{"body conflict", Step{
+			ID:         "s",
+			Expect:     map[string]json.RawMessage{"body.id": raw(`"a"`)},
+			Assertions: &Assertions{Body: map[string]json.RawMessage{"$.id": raw(`"b"`)}},
+		}},
+	}
+	for _, tt := range tests {
+		tc := TestCase{Steps: []Step{tt.step}}
+		if err := tc.NormalizeExpect(); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
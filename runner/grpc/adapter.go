@@ -11,26 +11,75 @@ package main
 // concerned only with the route-resolution and status-code translation
 // that bridges the two worlds.
 
-import "google.golang.org/grpc/codes"
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
 
 // --- HTTP path → gRPC method routing ---
 
 // RouteMapping describes a single HTTP-to-gRPC route entry.
 type RouteMapping struct {
-	// HTTPAction is the HTTP verb (GET, POST, DELETE).
+	// HTTPAction is the HTTP verb (GET, POST, PUT, PATCH, DELETE).
 	HTTPAction string
-	// PathPrefix is matched against the test step's path. Exact matches
-	// are tried first; prefix matches handle paths containing dynamic IDs.
+	// PathPrefix is matched against the test step's path, without its
+	// query string. Exact matches are tried first, then patterns, then
+	// prefix matches for paths containing dynamic IDs. A path with {name}
+	// segments is a pattern: each {name} matches one path segment, which is
+	// passed to the RPC as the request field name.
 	PathPrefix string
-	// RPCMethod is the gRPC method name dispatched by CallRPC.
+	// RPCMethod is the gRPC method dispatched by CallRPC: a method of the
+	// OJS services, such as "GetJob", or a fully qualified method such as
+	// "acme.billing.v1.BillingService/CreateInvoice".
 	RPCMethod string
 	// Exact means the path must match exactly (no prefix matching).
 	Exact bool
+	// Status, if set, is the HTTP status reported when the RPC succeeds,
+	// such as 201 for RPCs that create a resource.
+	Status int
+}
+
+// isPattern reports whether the route's path has {name} segments.
+func (r RouteMapping) isPattern() bool {
+	return strings.Contains(r.PathPrefix, "{")
+}
+
+// matchPattern matches path against a pattern route, returning the values
+// of its {name} segments.
+func (r RouteMapping) matchPattern(path string) (map[string]string, bool) {
+	want := strings.Split(strings.Trim(r.PathPrefix, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range want {
+		if name, ok := strings.CutPrefix(seg, "{"); ok {
+			if got[i] == "" {
+				return nil, false
+			}
+			params[strings.TrimSuffix(name, "}")] = got[i]
+			continue
+		}
+		if seg != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
 }
 
 // routeTable defines the ordered set of HTTP → gRPC mappings used by
-// resolveMethod. Exact matches are listed before prefix matches so that
-// "/ojs/v1/jobs" doesn't shadow "/ojs/v1/jobs/batch".
+// MatchRoute. Exact matches are tried before patterns and prefix matches,
+// so "/ojs/v1/jobs" doesn't shadow "/ojs/v1/jobs/batch". Among patterns,
+// the first match wins, so literal segments are listed before {name}
+// segments in the same position.
+//
+// Core RPCs are dispatched by typed calls in client.go. The extension RPCs
+// are resolved by name from the OJS service descriptors (see dynamic.go);
+// a backend whose RPCs are named differently can map them with -routes.
 var routeTable = []RouteMapping{
 	// --- System ---
 	{HTTPAction: "GET", PathPrefix: "/ojs/manifest", RPCMethod: "Manifest", Exact: true},
@@ -38,7 +87,11 @@ var routeTable = []RouteMapping{
 
 	// --- Jobs ---
 	{HTTPAction: "POST", PathPrefix: "/ojs/v1/jobs/batch", RPCMethod: "EnqueueBatch", Exact: true},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/jobs/fetch", RPCMethod: "Fetch", Exact: true},
 	{HTTPAction: "POST", PathPrefix: "/ojs/v1/jobs", RPCMethod: "Enqueue", Exact: true},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/jobs/{job_id}/ack", RPCMethod: "Ack"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/jobs/{job_id}/nack", RPCMethod: "Nack"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/jobs/{job_id}/cancel", RPCMethod: "CancelJob"},
 	{HTTPAction: "GET", PathPrefix: "/ojs/v1/jobs/", RPCMethod: "GetJob"},
 	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/jobs/", RPCMethod: "CancelJob"},
 
@@ -47,11 +100,15 @@ var routeTable = []RouteMapping{
 	{HTTPAction: "POST", PathPrefix: "/ojs/v1/workers/ack", RPCMethod: "Ack", Exact: true},
 	{HTTPAction: "POST", PathPrefix: "/ojs/v1/workers/nack", RPCMethod: "Nack", Exact: true},
 	{HTTPAction: "POST", PathPrefix: "/ojs/v1/workers/heartbeat", RPCMethod: "Heartbeat", Exact: true},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/workers/beat", RPCMethod: "Heartbeat", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/workers/{worker_id}/health", RPCMethod: "GetWorkerHealth"},
 
 	// --- Queues ---
 	{HTTPAction: "GET", PathPrefix: "/ojs/v1/queues", RPCMethod: "ListQueues", Exact: true},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/queues/{queue}/pause", RPCMethod: "PauseQueue"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/queues/{queue}/resume", RPCMethod: "ResumeQueue"},
+	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/queues/{queue}", RPCMethod: "UpdateQueueConfig"},
 	{HTTPAction: "GET", PathPrefix: "/ojs/v1/queues/", RPCMethod: "QueueStats"},
-	{HTTPAction: "POST", PathPrefix: "/ojs/v1/queues/", RPCMethod: "PauseOrResumeQueue"},
 
 	// --- Dead letter ---
 	{HTTPAction: "GET", PathPrefix: "/ojs/v1/dead-letter", RPCMethod: "ListDeadLetter", Exact: true},
@@ -69,27 +126,120 @@ var routeTable = []RouteMapping{
 	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/workflows/", RPCMethod: "CancelWorkflow"},
 
 	// --- Durable Execution (Checkpoints) ---
-	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/jobs/", RPCMethod: "SaveCheckpoint"},
-	{HTTPAction: "GET", PathPrefix: "/ojs/v1/jobs/", RPCMethod: "GetCheckpointOrJob"},
-	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/jobs/", RPCMethod: "DeleteCheckpointOrCancel"},
+	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/jobs/{job_id}/checkpoint", RPCMethod: "SaveCheckpoint"},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/jobs/{job_id}/checkpoint", RPCMethod: "GetCheckpoint"},
+	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/jobs/{job_id}/checkpoint", RPCMethod: "DeleteCheckpoint"},
+
+	// --- Progress ---
+	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/jobs/{job_id}/progress", RPCMethod: "UpdateProgress"},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/jobs/{job_id}/progress", RPCMethod: "GetProgress"},
+
+	// --- Attestation ---
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/jobs/{job_id}/receipt", RPCMethod: "GetReceipt"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/attest/verify", RPCMethod: "VerifyReceipt", Exact: true},
+
+	// --- Agents ---
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/agents/{agent_id}/fork", RPCMethod: "ForkAgent"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/agents/{agent_id}/merge", RPCMethod: "MergeAgent"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/agents/{agent_id}/pause", RPCMethod: "PauseAgent"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/agents/{agent_id}/resume", RPCMethod: "ResumeAgent"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/agents/{agent_id}/tool-retry", RPCMethod: "RetryAgentTool"},
+
+	// --- Webhooks ---
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/webhooks/subscriptions", RPCMethod: "CreateWebhookSubscription", Exact: true, Status: 201},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/webhooks/subscriptions", RPCMethod: "ListWebhookSubscriptions", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/webhooks/subscriptions/{subscription_id}", RPCMethod: "GetWebhookSubscription"},
+	{HTTPAction: "PATCH", PathPrefix: "/ojs/v1/webhooks/subscriptions/{subscription_id}", RPCMethod: "UpdateWebhookSubscription"},
+	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/webhooks/subscriptions/{subscription_id}", RPCMethod: "DeleteWebhookSubscription"},
+
+	// --- Events ---
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/events", RPCMethod: "ListEvents", Exact: true},
+
+	// --- Rate limits ---
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/rate-limits/{key}", RPCMethod: "GetRateLimit"},
+
+	// --- Schemas ---
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/schemas/{type}/versions", RPCMethod: "ListSchemaVersions"},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/schemas/{type}", RPCMethod: "GetSchema"},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/schemas/{type}/{version}", RPCMethod: "GetSchema"},
+	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/admin/schemas/{type}/{version}", RPCMethod: "RegisterSchema"},
+	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/admin/schemas/{type}/{version}", RPCMethod: "DeleteSchema"},
+
+	// --- Admin ---
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/stats", RPCMethod: "GetStats", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/scheduling/stats", RPCMethod: "GetSchedulingStats", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/workers", RPCMethod: "ListWorkers", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/queues", RPCMethod: "ListQueues", Exact: true},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/admin/queues/{queue}/pause", RPCMethod: "PauseQueue"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/admin/queues/{queue}/resume", RPCMethod: "ResumeQueue"},
+	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/admin/queues/{queue}/config", RPCMethod: "UpdateQueueConfig"},
+	{HTTPAction: "PUT", PathPrefix: "/ojs/v1/admin/pools/{pool}", RPCMethod: "UpdateWorkerPool"},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/jobs/{job_id}", RPCMethod: "GetJob"},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/admin/jobs/bulk/retry", RPCMethod: "BulkRetryJobs", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/dead-letter", RPCMethod: "ListDeadLetter", Exact: true},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/dead-letter/stats", RPCMethod: "DeadLetterStats", Exact: true},
+	{HTTPAction: "POST", PathPrefix: "/ojs/v1/admin/dead-letter/{job_id}/retry", RPCMethod: "RetryDeadLetter"},
+	{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/admin/dead-letter/{job_id}", RPCMethod: "DeleteDeadLetter", Status: 204},
+	{HTTPAction: "GET", PathPrefix: "/ojs/v1/admin/tenants/{tenant}/stats", RPCMethod: "GetTenantStats"},
 }
 
-// ResolveRoute finds the gRPC method name for an HTTP action + path pair.
-// Returns "" if no route matches.
-func ResolveRoute(action, path string) string {
+// AddRoutes puts routes ahead of the built-in table, so that they win over
+// built-in routes of the same kind (exact, pattern or prefix).
+func AddRoutes(routes []RouteMapping) {
+	routeTable = append(slices.Clip(routes), routeTable...)
+}
+
+// MatchRoute finds the route for an HTTP action + path pair. Params holds
+// the path's {name} segments and its query parameters. It returns false if
+// no route matches.
+func MatchRoute(action, path string) (route RouteMapping, params map[string]string, ok bool) {
+	path, query, _ := strings.Cut(path, "?")
+	route, params, ok = matchPath(action, path)
+	if !ok {
+		return RouteMapping{}, nil, false
+	}
+	if params == nil {
+		params = make(map[string]string)
+	}
+	if values, err := url.ParseQuery(query); err == nil {
+		for k, v := range values {
+			if _, isPath := params[k]; !isPath {
+				params[k] = strings.Join(v, ",")
+			}
+		}
+	}
+	return route, params, true
+}
+
+func matchPath(action, path string) (RouteMapping, map[string]string, bool) {
 	// Exact matches first.
 	for _, r := range routeTable {
 		if r.Exact && r.HTTPAction == action && r.PathPrefix == path {
-			return r.RPCMethod
+			return r, nil, true
 		}
 	}
-	// Prefix matches second.
+	// Patterns second.
 	for _, r := range routeTable {
-		if !r.Exact && r.HTTPAction == action && len(path) >= len(r.PathPrefix) && path[:len(r.PathPrefix)] == r.PathPrefix {
-			return r.RPCMethod
+		if r.isPattern() && r.HTTPAction == action {
+			if params, ok := r.matchPattern(path); ok {
+				return r, params, true
+			}
 		}
 	}
-	return ""
+	// Prefix matches last.
+	for _, r := range routeTable {
+		if !r.Exact && !r.isPattern() && r.HTTPAction == action && strings.HasPrefix(path, r.PathPrefix) {
+			return r, nil, true
+		}
+	}
+	return RouteMapping{}, nil, false
+}
+
+// ResolveRoute finds the gRPC method name for an HTTP action + path pair.
+// Returns "" if no route matches.
+func ResolveRoute(action, path string) string {
+	route, _, _ := MatchRoute(action, path)
+	return route.RPCMethod
 }
 
 // --- gRPC status code → HTTP status code translation ---
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		action, path string
+		rpc          string
+		params       map[string]string
+	}{
+		// Exact routes win over prefixes that also match.
+		{"POST", "/ojs/v1/jobs/batch", "EnqueueBatch", map[string]string{}},
+		{"POST", "/ojs/v1/jobs", "Enqueue", map[string]string{}},
+		{"GET", "/ojs/v1/jobs/job-1", "GetJob", map[string]string{}},
+		{"DELETE", "/ojs/v1/jobs/job-1", "CancelJob", map[string]string{}},
+
+		// Patterns win over prefixes, and name their segments.
+		{"POST", "/ojs/v1/jobs/job-1/ack", "Ack", map[string]string{"job_id": "job-1"}},
+		{"PUT", "/ojs/v1/jobs/job-1/checkpoint", "SaveCheckpoint", map[string]string{"job_id": "job-1"}},
+		{"GET", "/ojs/v1/jobs/job-1/checkpoint", "GetCheckpoint", map[string]string{"job_id": "job-1"}},
+		{"DELETE", "/ojs/v1/jobs/job-1/checkpoint", "DeleteCheckpoint", map[string]string{"job_id": "job-1"}},
+		{"GET", "/ojs/v1/jobs/job-1/progress", "GetProgress", map[string]string{"job_id": "job-1"}},
+		{"GET", "/ojs/v1/admin/tenants/acme/stats", "GetTenantStats", map[string]string{"tenant": "acme"}},
+
+		// {type}/versions is listed before {type}/{version}, so it is not
+		// read as a version called "versions".
+		{"GET", "/ojs/v1/admin/schemas/email/versions", "ListSchemaVersions", map[string]string{"type": "email"}},
+		{"GET", "/ojs/v1/admin/schemas/email/v2", "GetSchema", map[string]string{"type": "email", "version": "v2"}},
+		{"GET", "/ojs/v1/admin/schemas/email", "GetSchema", map[string]string{"type": "email"}},
+		{"PUT", "/ojs/v1/admin/schemas/email/v2", "RegisterSchema", map[string]string{"type": "email", "version": "v2"}},
+
+		// Query parameters are added, but do not replace path parameters.
+		{"GET", "/ojs/v1/events?type=job.completed&type=job.failed", "ListEvents", map[string]string{"type": "job.completed,job.failed"}},
+		{"GET", "/ojs/v1/jobs/job-1/checkpoint?job_id=job-2&limit=5", "GetCheckpoint", map[string]string{"job_id": "job-1", "limit": "5"}},
+	}
+	for _, tt := range tests {
+		route, params, ok := MatchRoute(tt.action, tt.path)
+		if !ok || route.RPCMethod != tt.rpc || !maps.Equal(params, tt.params) {
+			t.Errorf("%s %s: got %s %v (ok %v), want %s %v", tt.action, tt.path, route.RPCMethod, params, ok, tt.rpc, tt.params)
+		}
+	}
+
+	for _, tt := range []struct{ action, path string }{
+		{"PATCH", "/ojs/v1/jobs/job-1"},
+		{"GET", "/ojs/v2/jobs"},
+		{"POST", "/ojs/v1/jobs//ack"}, // a {name} segment cannot be empty
+	} {
+		if route, _, ok := MatchRoute(tt.action, tt.path); ok {
+			t.Errorf("%s %s: expected no route, got %s", tt.action, tt.path, route.RPCMethod)
+		}
+	}
+}
+
+func TestAddRoutes(t *testing.T) {
+	builtin := routeTable
+	t.Cleanup(func() { routeTable = builtin })
+
+	AddRoutes([]RouteMapping{
+		{HTTPAction: "PUT", PathPrefix: "/ojs/v1/jobs/{id}/checkpoint", RPCMethod: "acme.durable.v1.Checkpoints/Save"},
+		{HTTPAction: "GET", PathPrefix: "/ojs/v1/billing/invoices", RPCMethod: "acme.billing.v1.BillingService/ListInvoices", Exact: true},
+		{HTTPAction: "GET", PathPrefix: "/ojs/v1/jobs/", RPCMethod: "acme.jobs.v1.Jobs/Get"},
+		{HTTPAction: "POST", PathPrefix: "/ojs/v1/", RPCMethod: "acme.fallback.v1.Fallback/Post"},
+	})
+
+	tests := []struct {
+		action, path string
+		rpc          string
+		params       map[string]string
+	}{
+		// A user route wins over a built-in route of the same kind.
+		{"PUT", "/ojs/v1/jobs/job-1/checkpoint", "acme.durable.v1.Checkpoints/Save", map[string]string{"id": "job-1"}},
+		{"GET", "/ojs/v1/jobs/job-1", "acme.jobs.v1.Jobs/Get", map[string]string{}},
+		{"GET", "/ojs/v1/billing/invoices", "acme.billing.v1.BillingService/ListInvoices", map[string]string{}},
+		// Built-in exact routes and patterns still win over user prefixes.
+		{"POST", "/ojs/v1/jobs", "Enqueue", map[string]string{}},
+		{"POST", "/ojs/v1/jobs/job-1/ack", "Ack", map[string]string{"job_id": "job-1"}},
+		{"POST", "/ojs/v1/billing/invoices", "acme.fallback.v1.Fallback/Post", map[string]string{}},
+		// Other built-in routes are untouched.
+		{"GET", "/ojs/v1/jobs/job-1/checkpoint", "GetCheckpoint", map[string]string{"job_id": "job-1"}},
+	}
+	for _, tt := range tests {
+		route, params, ok := MatchRoute(tt.action, tt.path)
+		if !ok || route.RPCMethod != tt.rpc || !maps.Equal(params, tt.params) {
+			t.Errorf("%s %s: got %s %v (ok %v), want %s %v", tt.action, tt.path, route.RPCMethod, params, ok, tt.rpc, tt.params)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	ojsv1 "github.com/openjobspec/ojs-proto/gen/go/ojs/v1"
@@ -14,6 +15,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	grpcInsecure "google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -23,8 +25,15 @@ import (
 type OJSClient struct {
 	conn   *grpc.ClientConn
 	client ojsv1.OJSServiceClient
+
+	methodsMu sync.Mutex
+	methods   map[string]protoreflect.MethodDescriptor // resolved by invoke, by RPC name
 }
 
+// ojsPackage is the proto package of the OJS services, searched for RPCs
+// routed by method name alone.
+var ojsPackage = (&ojsv1.Job{}).ProtoReflect().Descriptor().ParentFile().Package()
+
 // ConnectOptions configures the gRPC dial behaviour.
 type ConnectOptions struct {
 	TLS      bool // Use TLS transport credentials.
@@ -71,17 +80,37 @@ type RPCResult struct {
 	HTTPStatusOverride int
 }
 
-// CallRPC dispatches a test step to the appropriate gRPC RPC.
-func (c *OJSClient) CallRPC(ctx context.Context, method string, path string, body map[string]any) (*RPCResult, error) {
+// CallRPC dispatches a test step to the appropriate gRPC RPC. Params are
+// the route's path and query parameters (see MatchRoute). Core RPCs have
+// typed calls; any other method is called through its descriptor (see
+// invoke).
+func (c *OJSClient) CallRPC(ctx context.Context, method string, path string, params map[string]string, body map[string]any) (*RPCResult, error) {
+	// Path parameters name the resource for routes such as
+	// /ojs/v1/jobs/{job_id}/ack, whose body does not.
+	pathID := func(name, prefix string) string {
+		if v := params[name]; v != "" {
+			return v
+		}
+		return extractIDFromPath(path, prefix)
+	}
+	if id := params["job_id"]; id != "" {
+		if body == nil {
+			body = make(map[string]any)
+		}
+		if _, ok := body["job_id"]; !ok {
+			body["job_id"] = id
+		}
+	}
+
 	switch method {
 	case "Enqueue":
 		return c.enqueue(ctx, body)
 	case "EnqueueBatch":
 		return c.enqueueBatch(ctx, body)
 	case "GetJob":
-		return c.getJob(ctx, extractIDFromPath(path, "/ojs/v1/jobs/"))
+		return c.getJob(ctx, pathID("job_id", "/ojs/v1/jobs/"))
 	case "CancelJob":
-		return c.cancelJob(ctx, extractIDFromPath(path, "/ojs/v1/jobs/"), body)
+		return c.cancelJob(ctx, pathID("job_id", "/ojs/v1/jobs/"), body)
 	case "Fetch":
 		return c.fetch(ctx, body)
 	case "Ack":
@@ -93,17 +122,17 @@ func (c *OJSClient) CallRPC(ctx context.Context, method string, path string, bod
 	case "ListQueues":
 		return c.listQueues(ctx)
 	case "QueueStats":
-		return c.queueStats(ctx, path)
+		return c.queueStats(ctx, pathID("queue", "/ojs/v1/queues/"))
 	case "PauseQueue":
-		return c.pauseQueue(ctx, path)
+		return c.pauseQueue(ctx, pathID("queue", "/ojs/v1/queues/"))
 	case "ResumeQueue":
-		return c.resumeQueue(ctx, path)
+		return c.resumeQueue(ctx, pathID("queue", "/ojs/v1/queues/"))
 	case "ListDeadLetter":
 		return c.listDeadLetter(ctx, body)
 	case "RetryDeadLetter":
-		return c.retryDeadLetter(ctx, path)
+		return c.retryDeadLetter(ctx, pathID("job_id", "/ojs/v1/dead-letter/"))
 	case "DeleteDeadLetter":
-		return c.deleteDeadLetter(ctx, path)
+		return c.deleteDeadLetter(ctx, pathID("job_id", "/ojs/v1/dead-letter/"))
 	case "RegisterCron":
 		return c.registerCron(ctx, body)
 	case "UnregisterCron":
@@ -121,7 +150,7 @@ func (c *OJSClient) CallRPC(ctx context.Context, method string, path string, bod
 	case "Manifest":
 		return c.manifest(ctx)
 	default:
-		return nil, fmt.Errorf("unsupported gRPC method: %s", method)
+		return c.invoke(ctx, method, params, body)
 	}
 }
 
@@ -345,9 +374,7 @@ func (c *OJSClient) listQueues(ctx context.Context) (*RPCResult, error) {
 	return &RPCResult{ResponseJSON: respJSON, GRPCCode: codes.OK}, nil
 }
 
-func (c *OJSClient) queueStats(ctx context.Context, path string) (*RPCResult, error) {
-	queue := extractIDFromPath(path, "/ojs/v1/queues/")
-	queue = strings.TrimSuffix(queue, "/stats")
+func (c *OJSClient) queueStats(ctx context.Context, queue string) (*RPCResult, error) {
 	resp, err := c.client.QueueStats(ctx, &ojsv1.QueueStatsRequest{Queue: queue})
 	if err != nil {
 		return grpcError(err), nil
@@ -367,9 +394,7 @@ func (c *OJSClient) queueStats(ctx context.Context, path string) (*RPCResult, er
 	return &RPCResult{ResponseJSON: respJSON, GRPCCode: codes.OK}, nil
 }
 
-func (c *OJSClient) pauseQueue(ctx context.Context, path string) (*RPCResult, error) {
-	queue := extractIDFromPath(path, "/ojs/v1/queues/")
-	queue = strings.TrimSuffix(queue, "/pause")
+func (c *OJSClient) pauseQueue(ctx context.Context, queue string) (*RPCResult, error) {
 	_, err := c.client.PauseQueue(ctx, &ojsv1.PauseQueueRequest{Queue: queue})
 	if err != nil {
 		return grpcError(err), nil
@@ -378,9 +403,7 @@ func (c *OJSClient) pauseQueue(ctx context.Context, path string) (*RPCResult, er
 	return &RPCResult{ResponseJSON: respJSON, GRPCCode: codes.OK}, nil
 }
 
-func (c *OJSClient) resumeQueue(ctx context.Context, path string) (*RPCResult, error) {
-	queue := extractIDFromPath(path, "/ojs/v1/queues/")
-	queue = strings.TrimSuffix(queue, "/resume")
+func (c *OJSClient) resumeQueue(ctx context.Context, queue string) (*RPCResult, error) {
 	_, err := c.client.ResumeQueue(ctx, &ojsv1.ResumeQueueRequest{Queue: queue})
 	if err != nil {
 		return grpcError(err), nil
@@ -411,9 +434,7 @@ func (c *OJSClient) listDeadLetter(ctx context.Context, body map[string]any) (*R
 	return &RPCResult{ResponseJSON: respJSON, GRPCCode: codes.OK}, nil
 }
 
-func (c *OJSClient) retryDeadLetter(ctx context.Context, path string) (*RPCResult, error) {
-	jobID := extractIDFromPath(path, "/ojs/v1/dead-letter/")
-	jobID = strings.TrimSuffix(jobID, "/retry")
+func (c *OJSClient) retryDeadLetter(ctx context.Context, jobID string) (*RPCResult, error) {
 	resp, err := c.client.RetryDeadLetter(ctx, &ojsv1.RetryDeadLetterRequest{JobId: jobID})
 	if err != nil {
 		return grpcError(err), nil
@@ -422,8 +443,7 @@ func (c *OJSClient) retryDeadLetter(ctx context.Context, path string) (*RPCResul
 	return &RPCResult{ResponseJSON: respJSON, GRPCCode: codes.OK}, nil
 }
 
-func (c *OJSClient) deleteDeadLetter(ctx context.Context, path string) (*RPCResult, error) {
-	jobID := extractIDFromPath(path, "/ojs/v1/dead-letter/")
+func (c *OJSClient) deleteDeadLetter(ctx context.Context, jobID string) (*RPCResult, error) {
 	_, err := c.client.DeleteDeadLetter(ctx, &ojsv1.DeleteDeadLetterRequest{JobId: jobID})
 	if err != nil {
 		return grpcError(err), nil
@@ -0,0 +1,436 @@
+package main
+
+// Dynamic dispatch: calls RPCs that have no typed wrapper in client.go.
+//
+// The extension services (agents, attestation, webhooks, schemas, admin,
+// progress, events, rate limits, tenants) and any custom RPCs named in a
+// -routes file are called through their descriptors. A method is looked up
+// in the OJS protos linked into the runner first, then through the
+// server's reflection service, so a backend can be tested against RPCs the
+// runner was not built with. Requests are built from the step's JSON body
+// and the route's path and query parameters; responses are converted back
+// to the JSON shape the HTTP assertions expect.
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// invoke calls the named RPC with a request built from params and body.
+func (c *OJSClient) invoke(ctx context.Context, method string, params map[string]string, body map[string]any) (*RPCResult, error) {
+	md, err := c.resolveRPC(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("%s is a streaming RPC; routes can only call unary RPCs", md.FullName())
+	}
+
+	req := dynamicpb.NewMessage(md.Input())
+	if len(body) > 0 {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, req); err != nil {
+			return nil, fmt.Errorf("building %s request: %w", md.Name(), err)
+		}
+	}
+	if err := setParams(req, params); err != nil {
+		return nil, fmt.Errorf("building %s request: %w", md.Name(), err)
+	}
+
+	resp := dynamicpb.NewMessage(md.Output())
+	fullMethod := "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+	if err := c.conn.Invoke(ctx, fullMethod, req, resp); err != nil {
+		return grpcError(err), nil
+	}
+	respJSON, _ := json.Marshal(messageToMap(resp))
+	return &RPCResult{ResponseJSON: respJSON, GRPCCode: codes.OK}, nil
+}
+
+// --- Method resolution ---
+
+// resolveRPC finds the descriptor of a method named either by its fully
+// qualified service and method ("pkg.Service/Method") or by its name alone,
+// which is looked up across the OJS services.
+func (c *OJSClient) resolveRPC(ctx context.Context, method string) (protoreflect.MethodDescriptor, error) {
+	c.methodsMu.Lock()
+	defer c.methodsMu.Unlock()
+	if md, ok := c.methods[method]; ok {
+		return md, nil
+	}
+
+	var md protoreflect.MethodDescriptor
+	var err error
+	if service, name, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/"); ok {
+		md, err = c.qualifiedMethod(ctx, service, name)
+	} else {
+		md, err = c.ojsMethod(ctx, method)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.methods == nil {
+		c.methods = make(map[string]protoreflect.MethodDescriptor)
+	}
+	c.methods[method] = md
+	return md, nil
+}
+
+func (c *OJSClient) qualifiedMethod(ctx context.Context, service, name string) (protoreflect.MethodDescriptor, error) {
+	var sd protoreflect.ServiceDescriptor
+	if d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service)); err == nil {
+		sd, _ = d.(protoreflect.ServiceDescriptor)
+	}
+	if sd == nil {
+		var err error
+		if sd, err = c.reflectService(ctx, service); err != nil {
+			return nil, err
+		}
+	}
+	md := sd.Methods().ByName(protoreflect.Name(name))
+	if md == nil {
+		return nil, fmt.Errorf("service %s has no method %s", service, name)
+	}
+	return md, nil
+}
+
+// ojsMethod looks a method name up in the services of the OJS proto
+// package: those linked into the runner, or else those the server lists.
+func (c *OJSClient) ojsMethod(ctx context.Context, name string) (protoreflect.MethodDescriptor, error) {
+	var services []protoreflect.ServiceDescriptor
+	protoregistry.GlobalFiles.RangeFilesByPackage(ojsPackage, func(fd protoreflect.FileDescriptor) bool {
+		for i := 0; i < fd.Services().Len(); i++ {
+			services = append(services, fd.Services().Get(i))
+		}
+		return true
+	})
+	md, err := methodIn(services, name)
+	if md != nil || err != nil {
+		return md, err
+	}
+
+	names, err := c.reflectServiceNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no OJS service has a method %s, and the server's services cannot be listed: %w", name, err)
+	}
+	services = services[:0]
+	for _, svc := range names {
+		if !strings.HasPrefix(svc, string(ojsPackage)+".") {
+			continue
+		}
+		sd, err := c.reflectService(ctx, svc)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, sd)
+	}
+	md, err = methodIn(services, name)
+	if md == nil && err == nil {
+		err = fmt.Errorf("no OJS service has a method %s; map the route to a service/method with -routes", name)
+	}
+	return md, err
+}
+
+// methodIn finds the only method called name in services.
+func methodIn(services []protoreflect.ServiceDescriptor, name string) (protoreflect.MethodDescriptor, error) {
+	var found protoreflect.MethodDescriptor
+	for _, sd := range services {
+		md := sd.Methods().ByName(protoreflect.Name(name))
+		if md == nil {
+			continue
+		}
+		if found != nil && found.Parent().FullName() != sd.FullName() {
+			return nil, fmt.Errorf("method %s is defined by both %s and %s; name it as service/method", name, found.Parent().FullName(), sd.FullName())
+		}
+		found = md
+	}
+	return found, nil
+}
+
+// --- Server reflection ---
+
+func (c *OJSClient) reflect(ctx context.Context, req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(c.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, fmt.Errorf("server reflection: %s", e.GetErrorMessage())
+	}
+	return resp, nil
+}
+
+func (c *OJSClient) reflectServiceNames(ctx context.Context) ([]string, error) {
+	resp, err := c.reflect(ctx, &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		names = append(names, s.GetName())
+	}
+	return names, nil
+}
+
+// reflectService fetches the file defining service, and the files it
+// imports, from the server.
+func (c *OJSClient) reflectService(ctx context.Context, service string) (protoreflect.ServiceDescriptor, error) {
+	resp, err := c.reflect(ctx, &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", service, err)
+	}
+	pending := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fdp := new(descriptorpb.FileDescriptorProto)
+		if err := proto.Unmarshal(raw, fdp); err != nil {
+			return nil, fmt.Errorf("looking up %s: %w", service, err)
+		}
+		pending[fdp.GetName()] = fdp
+	}
+
+	files := new(protoregistry.Files)
+	var register func(path string) error
+	register = func(path string) error {
+		if _, err := files.FindFileByPath(path); err == nil {
+			return nil
+		}
+		fdp, ok := pending[path]
+		if !ok {
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+			if err != nil {
+				return fmt.Errorf("server did not send %s", path)
+			}
+			return files.RegisterFile(fd)
+		}
+		for _, dep := range fdp.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			return err
+		}
+		return files.RegisterFile(fd)
+	}
+	for path := range pending {
+		if err := register(path); err != nil {
+			return nil, fmt.Errorf("looking up %s: %w", service, err)
+		}
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	return sd, nil
+}
+
+// --- Requests ---
+
+// setParams sets request fields from path and query parameters. A
+// parameter names a field by its proto or JSON name, with dashes read as
+// underscores; parameters naming no field are ignored, as are fields the
+// body already set. Lists take comma-separated values.
+func setParams(m *dynamicpb.Message, params map[string]string) error {
+	fields := m.Descriptor().Fields()
+	for name, value := range params {
+		fd := fields.ByName(protoreflect.Name(strings.ReplaceAll(name, "-", "_")))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil || m.Has(fd) || fd.IsMap() {
+			continue
+		}
+		if fd.IsList() {
+			list := m.Mutable(fd).List()
+			for _, v := range strings.Split(value, ",") {
+				pv, err := scalarValue(fd, v)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				list.Append(pv)
+			}
+			continue
+		}
+		pv, err := scalarValue(fd, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		m.Set(fd, pv)
+	}
+	return nil
+}
+
+// scalarValue parses s as a value of a scalar or enum field.
+func scalarValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(s)), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			if v := values.Get(i); string(v.Name()) == s || enumName(v) == s {
+				return protoreflect.ValueOfEnum(v.Number()), nil
+			}
+		}
+		return protoreflect.Value{}, fmt.Errorf("%q is not a %s", s, fd.Enum().Name())
+	default:
+		return protoreflect.Value{}, fmt.Errorf("cannot be set from a path or query parameter")
+	}
+}
+
+// --- Responses ---
+
+// messageToMap converts a response message to the JSON shape of the HTTP
+// binding, as protoJobToMap does for jobs: fields by their proto names,
+// enums as lowercase names without their type prefix, timestamps as RFC
+// 3339 strings and durations as seconds.
+func messageToMap(m protoreflect.Message) map[string]any {
+	out := make(map[string]any)
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		out[string(fd.Name())] = fieldValue(fd, v)
+		return true
+	})
+	return out
+}
+
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		items := make([]any, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			items = append(items, singularValue(fd, list.Get(i)))
+		}
+		return items
+	case fd.IsMap():
+		entries := make(map[string]any)
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			entries[k.String()] = singularValue(fd.MapValue(), mv)
+			return true
+		})
+		return entries
+	default:
+		return singularValue(fd, v)
+	}
+}
+
+func singularValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return enumName(ev)
+		}
+		return int32(v.Enum())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageValue(v.Message())
+	default:
+		return v.Interface()
+	}
+}
+
+func messageValue(m protoreflect.Message) any {
+	switch name := m.Descriptor().FullName(); {
+	case name == "google.protobuf.Timestamp":
+		fields := m.Descriptor().Fields()
+		t := time.Unix(m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int())
+		return t.UTC().Format(time.RFC3339Nano)
+	case name == "google.protobuf.Duration":
+		fields := m.Descriptor().Fields()
+		d := time.Duration(m.Get(fields.ByName("seconds")).Int())*time.Second + time.Duration(m.Get(fields.ByName("nanos")).Int())
+		return d.Seconds()
+	case strings.HasPrefix(string(name), "google.protobuf."):
+		// Struct, Value, wrappers and Any: their JSON mapping is the HTTP shape.
+		raw, err := protojson.Marshal(m.Interface())
+		if err != nil {
+			return nil
+		}
+		var v any
+		_ = json.Unmarshal(raw, &v)
+		return v
+	default:
+		return messageToMap(m)
+	}
+}
+
+// enumName is an enum value's name in lowercase, without the prefix that
+// names its type: JOB_STATE_ACTIVE of JobState is "active".
+func enumName(v protoreflect.EnumValueDescriptor) string {
+	prefix := upperSnake(string(v.Parent().Name())) + "_"
+	return strings.ToLower(strings.TrimPrefix(string(v.Name()), prefix))
+}
+
+// upperSnake converts a CamelCase name to UPPER_SNAKE_CASE.
+func upperSnake(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) &&
+			(unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
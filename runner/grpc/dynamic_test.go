@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// checkpointService describes a test CheckpointService whose requests and
+// responses carry a job ID and a JSON-encoded state.
+func checkpointService(t *testing.T) protoreflect.ServiceDescriptor {
+	t.Helper()
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	opt := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	message := func(name string, fields ...string) *descriptorpb.DescriptorProto {
+		m := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+		for i, f := range fields {
+			m.Field = append(m.Field, &descriptorpb.FieldDescriptorProto{
+				Name: proto.String(f), Number: proto.Int32(int32(i + 1)), Type: str, Label: opt,
+			})
+		}
+		return m
+	}
+	rpc := func(name string) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".ojstest.v1.CheckpointRequest"),
+			OutputType: proto.String(".ojstest.v1.Checkpoint"),
+		}
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("ojstest/v1/checkpoint.proto"),
+		Package: proto.String("ojstest.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("CheckpointRequest", "job_id", "state"),
+			message("Checkpoint", "job_id", "state"),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("CheckpointService"),
+			Method: []*descriptorpb.MethodDescriptorProto{rpc("SaveCheckpoint"), rpc("GetCheckpoint"), rpc("DeleteCheckpoint")},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd.Services().Get(0)
+}
+
+// checkpointServer keeps one state per job, and records the requests it
+// is sent.
+type checkpointServer struct {
+	mu       sync.Mutex
+	states   map[string]string
+	requests []string
+}
+
+func (s *checkpointServer) handle(md protoreflect.MethodDescriptor, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	get := func(m *dynamicpb.Message, name protoreflect.Name) string {
+		return m.Get(m.Descriptor().Fields().ByName(name)).String()
+	}
+	jobID, state := get(req, "job_id"), get(req, "state")
+	s.requests = append(s.requests, string(md.Name())+" "+jobID+" "+state)
+
+	switch md.Name() {
+	case "SaveCheckpoint":
+		s.states[jobID] = state
+	case "GetCheckpoint":
+		saved, ok := s.states[jobID]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "no checkpoint for job %s", jobID)
+		}
+		state = saved
+	case "DeleteCheckpoint":
+		delete(s.states, jobID)
+		state = ""
+	}
+	resp := dynamicpb.NewMessage(md.Output())
+	resp.Set(md.Output().Fields().ByName("job_id"), protoreflect.ValueOfString(jobID))
+	resp.Set(md.Output().Fields().ByName("state"), protoreflect.ValueOfString(state))
+	return resp, nil
+}
+
+// serviceDesc registers s for sd without generated code.
+func (s *checkpointServer) serviceDesc(sd protoreflect.ServiceDescriptor) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{ServiceName: string(sd.FullName()), HandlerType: (*any)(nil)}
+	for i := 0; i < sd.Methods().Len(); i++ {
+		md := sd.Methods().Get(i)
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: string(md.Name()),
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := dynamicpb.NewMessage(md.Input())
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return s.handle(md, req)
+			},
+		})
+	}
+	return desc
+}
+
+// dialCheckpoints starts srv on an in-memory listener and returns a client
+// whose checkpoint RPCs resolve to sd, as the server's reflection service
+// would resolve them.
+func dialCheckpoints(t *testing.T, srv *checkpointServer, sd protoreflect.ServiceDescriptor) *OJSClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	s.RegisterService(srv.serviceDesc(sd), srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	methods := make(map[string]protoreflect.MethodDescriptor)
+	for i := 0; i < sd.Methods().Len(); i++ {
+		md := sd.Methods().Get(i)
+		methods[string(md.Name())] = md
+	}
+	return &OJSClient{conn: conn, methods: methods}
+}
+
+func TestCheckpointRoutes(t *testing.T) {
+	srv := &checkpointServer{states: make(map[string]string)}
+	c := dialCheckpoints(t, srv, checkpointService(t))
+
+	steps := []struct {
+		action, path string
+		body         map[string]any
+		code         codes.Code
+		want         map[string]any
+	}{
+		{"GET", "/ojs/v1/jobs/job-1/checkpoint", nil, codes.NotFound, nil},
+		{"PUT", "/ojs/v1/jobs/job-1/checkpoint", map[string]any{"state": `{"step":3}`}, codes.OK,
+			map[string]any{"job_id": "job-1", "state": `{"step":3}`}},
+		{"GET", "/ojs/v1/jobs/job-1/checkpoint", nil, codes.OK,
+			map[string]any{"job_id": "job-1", "state": `{"step":3}`}},
+		{"DELETE", "/ojs/v1/jobs/job-1/checkpoint", nil, codes.OK, map[string]any{"job_id": "job-1"}},
+		{"GET", "/ojs/v1/jobs/job-1/checkpoint", nil, codes.NotFound, nil},
+	}
+	for _, step := range steps {
+		route, params, ok := MatchRoute(step.action, step.path)
+		if !ok {
+			t.Fatalf("%s %s: no route", step.action, step.path)
+		}
+		res, err := c.CallRPC(context.Background(), route.RPCMethod, step.path, params, step.body)
+		if err != nil {
+			t.Fatalf("%s %s: %v", step.action, step.path, err)
+		}
+		if res.GRPCCode != step.code {
+			t.Errorf("%s %s: got code %v (%s), want %v", step.action, step.path, res.GRPCCode, res.GRPCMessage, step.code)
+			continue
+		}
+		if step.want == nil {
+			continue
+		}
+		var got map[string]any
+		if err := json.Unmarshal(res.ResponseJSON, &got); err != nil {
+			t.Fatalf("%s %s: %v", step.action, step.path, err)
+		}
+		for k, v := range step.want {
+			if got[k] != v {
+				t.Errorf("%s %s: %s is %v, want %v (response %s)", step.action, step.path, k, got[k], v, res.ResponseJSON)
+			}
+		}
+	}
+
+	// The job ID comes from the path, the state from the body.
+	want := []string{
+		"GetCheckpoint job-1 ",
+		`SaveCheckpoint job-1 {"step":3}`,
+		"GetCheckpoint job-1 ",
+		"DeleteCheckpoint job-1 ",
+		"GetCheckpoint job-1 ",
+	}
+	if len(srv.requests) != len(want) {
+		t.Fatalf("server got %q, want %q", srv.requests, want)
+	}
+	for i := range want {
+		if srv.requests[i] != want[i] {
+			t.Errorf("request %d: got %q, want %q", i+1, srv.requests[i], want[i])
+		}
+	}
+}
+
+// TestRoutesResolve checks that every built-in route names an RPC of the
+// OJS protos linked into the runner. The client cannot reach a server, so
+// nothing resolves through reflection.
+func TestRoutesResolve(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough:///unreachable",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return nil, errors.New("no server") }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &OJSClient{conn: conn}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resolved := make(map[string]bool)
+	for _, r := range routeTable {
+		if resolved[r.RPCMethod] {
+			continue
+		}
+		resolved[r.RPCMethod] = true
+		md, err := c.resolveRPC(ctx, r.RPCMethod)
+		if err != nil {
+			t.Errorf("%s %s: %v", r.HTTPAction, r.PathPrefix, err)
+			continue
+		}
+		if pkg := md.ParentFile().Package(); pkg != ojsPackage {
+			t.Errorf("%s %s: %s is in package %s, not %s", r.HTTPAction, r.PathPrefix, r.RPCMethod, pkg, ojsPackage)
+		}
+	}
+}
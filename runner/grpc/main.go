@@ -12,18 +12,18 @@
 //	ojs-conformance-grpc-runner -url localhost:9090 -suites ./suites -category retry
 //	ojs-conformance-grpc-runner -url localhost:9090 -suites ./suites -test L1-RET-001
 //	ojs-conformance-grpc-runner -url localhost:9090 -suites ./suites -output json
+//	ojs-conformance-grpc-runner -url localhost:9090 -suites ./suites -output table,junit=junit.xml,sarif=ojs.sarif
+//	ojs-conformance-grpc-runner -url localhost:9090 -suites ./suites -routes routes.yaml
 package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/openjobspec/ojs-conformance/lib"
-	"github.com/redis/go-redis/v9"
 )
 
 const suiteVersion = "1.0"
@@ -40,9 +40,26 @@ func main() {
 		tolerancePct float64
 		timeoutSec   int
 		redisURL     string
+		resetURL     string
+		resetPG      string
+		resetTables  string
+		resetExec    string
+		resetRetries int
 		useTLS       bool
 		insecureConn bool
 		reportFile   string
+		strict       bool
+		baselineFile string
+		parallel     int
+		routesFile   string
+
+		submitterOrg     string
+		submitterContact string
+		submitterKeyID   string
+		anonymous        bool
+		redact           bool
+		redactHeaders    string
+		redactPatterns   []string
 	)
 
 	flag.StringVar(&grpcAddr, "url", "", "gRPC server address (host:port)")
@@ -50,16 +67,58 @@ func main() {
 	flag.IntVar(&level, "level", -1, "Filter by conformance level (0-4), -1 for all")
 	flag.StringVar(&category, "category", "", "Filter by category (e.g., envelope, retry)")
 	flag.StringVar(&testID, "test", "", "Run a single test by ID (e.g., L0-ENV-001)")
-	flag.StringVar(&outputFormat, "output", "table", "Output format: table or json")
+	flag.StringVar(&outputFormat, "output", "table", "Comma-separated output formats (table, json, junit, tap, sarif); append =path to write one to a file")
 	flag.BoolVar(&verbose, "verbose", false, "Show detailed step results")
 	flag.Float64Var(&tolerancePct, "tolerance", 50, "Timing tolerance percentage")
 	flag.IntVar(&timeoutSec, "timeout", 30, "Per-RPC timeout in seconds")
 	flag.StringVar(&redisURL, "redis", "", "Redis URL for FLUSHDB between tests (e.g., redis://localhost:6379)")
+	flag.StringVar(&resetURL, "reset-url", "", "HTTP URL to POST for state reset between tests (e.g., http://localhost:8090/ojs/v1/admin/reset)")
+	flag.StringVar(&resetPG, "reset-postgres", "", "PostgreSQL URL whose -reset-tables are truncated between tests (e.g., postgres://localhost:5432/ojs)")
+	flag.StringVar(&resetTables, "reset-tables", "", "Comma-separated tables to TRUNCATE between tests (used with -reset-postgres)")
+	flag.StringVar(&resetExec, "reset-exec", "", "Shell command run between tests to reset server state; must exit 0")
+	flag.IntVar(&resetRetries, "reset-retries", lib.DefaultResetRetries, "Retries for a failed -reset-url or -reset-exec reset")
 	flag.BoolVar(&useTLS, "tls", false, "Use TLS for gRPC connection")
 	flag.BoolVar(&insecureConn, "insecure", false, "Skip TLS certificate verification (use with -tls)")
 	flag.StringVar(&reportFile, "report-file", "", "Write conformance report JSON to this file path (in addition to stdout output)")
+	flag.IntVar(&parallel, "parallel", 1, "Number of tests to run concurrently; tests that cannot be isolated still run alone")
+	flag.StringVar(&routesFile, "routes", "", "YAML or JSON file of extra HTTP route to gRPC method mappings, tried before the built-in routes")
+	flag.BoolVar(&strict, "strict", false, "Reject unknown keys and matchers in suite files instead of ignoring them")
+	flag.StringVar(&baselineFile, "baseline", "", "Known-failures JSON file; listed failures are reported as xfail and only regressions fail the run")
+	flag.StringVar(&submitterOrg, "submitter-org", "", "Organization submitting the report")
+	flag.StringVar(&submitterContact, "submitter-contact", "", "Submitter contact email or URL")
+	flag.StringVar(&submitterKeyID, "submitter-key-id", "", "ID of the key the report will be signed with (see ojs-conformance keygen)")
+	flag.BoolVar(&anonymous, "anonymous", false, "Record only the submitter key ID, and redact as with -redact")
+	flag.BoolVar(&redact, "redact", false, "Strip hostnames, CI URLs, response bodies and sensitive header values from reports")
+	flag.StringVar(&redactHeaders, "redact-headers", "", "Comma-separated header name regexps whose values are redacted, in addition to -redact's defaults")
+	flag.Func("redact-pattern", "Regexp replaced wherever it matches in reports; repeatable", func(s string) error {
+		redactPatterns = append(redactPatterns, s)
+		return nil
+	})
 	flag.Parse()
 
+	outputs, err := lib.ParseOutputs(outputFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -output: %v\n", err)
+		os.Exit(2)
+	}
+
+	if routesFile != "" {
+		routes, err := LoadRoutes(routesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading routes: %v\n", err)
+			os.Exit(2)
+		}
+		AddRoutes(routes)
+	}
+
+	var baseline *lib.Baseline
+	if baselineFile != "" {
+		if baseline, err = lib.LoadBaseline(baselineFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading baseline: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
 	// Resolve gRPC address: flag > env var > default
 	if grpcAddr == "" {
 		grpcAddr = os.Getenv("OJS_TEST_URL")
@@ -68,6 +127,19 @@ func main() {
 		grpcAddr = "localhost:9090"
 	}
 
+	redactCfg := lib.RedactConfig{}
+	if redact || anonymous {
+		redactCfg = lib.DefaultRedactConfig()
+	}
+	redactCfg.Headers = append(redactCfg.Headers, splitList(redactHeaders)...)
+	redactCfg.Patterns = redactPatterns
+	redactCfg.Targets = []string{grpcAddr}
+	redactor, err := lib.NewRedactor(redactCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -redact: %v\n", err)
+		os.Exit(2)
+	}
+
 	// Connect to gRPC server
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -80,7 +152,7 @@ func main() {
 	defer client.Close()
 
 	// Load test cases
-	tests, err := loadTests(suitesDir)
+	tests, err := lib.LoadTests(suitesDir, lib.LoadOptions{Strict: strict})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading tests: %v\n", err)
 		os.Exit(2)
@@ -101,39 +173,51 @@ func main() {
 
 	rpcTimeout := time.Duration(timeoutSec) * time.Second
 
-	// Set up optional Redis client for test isolation
-	var redisClient *redis.Client
-	if redisURL != "" {
-		opts, err := redis.ParseURL(redisURL)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing Redis URL: %v\n", err)
-			os.Exit(2)
-		}
-		redisClient = redis.NewClient(opts)
-		if err := redisClient.Ping(context.Background()).Err(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error connecting to Redis: %v\n", err)
-			os.Exit(2)
-		}
-		defer redisClient.Close()
+	// Set up optional state reset between tests
+	resetter, err := lib.NewResetter(context.Background(), lib.ResetConfig{
+		RedisURL:       redisURL,
+		PostgresURL:    resetPG,
+		PostgresTables: splitList(resetTables),
+		HTTPURL:        resetURL,
+		Exec:           resetExec,
+		Retries:        resetRetries,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up state reset: %v\n", err)
+		os.Exit(2)
 	}
 
 	// Run tests
+	scheduler := lib.Scheduler{Parallel: parallel}
+	if resetter != nil {
+		defer resetter.Close()
+		scheduler.Reset = resetter
+	}
+
 	suiteStart := time.Now()
-	var results []lib.TestResult
+	results := scheduler.Run(tests, func(tc lib.TestCase) lib.TestResult {
+		return runTest(tc, client, rpcTimeout, timingCfg, verbose)
+	})
 
-	for _, tc := range tests {
-		// Flush Redis between tests for isolation
-		if redisClient != nil {
-			redisClient.FlushDB(context.Background())
+	suiteDuration := time.Since(suiteStart)
+
+	// Reclassify known failures
+	if baseline != nil {
+		for _, kf := range baseline.Expired(time.Now()) {
+			fmt.Fprintf(os.Stderr, "Warning: baseline entry for %s expired on %s and is ignored\n", kf.TestID, kf.Expires)
 		}
-		result := runTest(tc, client, rpcTimeout, timingCfg, verbose)
-		results = append(results, result)
+		results = baseline.Apply(results, time.Now())
 	}
 
-	suiteDuration := time.Since(suiteStart)
-
 	// Build report
+	if redactor != nil {
+		redactor.Results(results)
+	}
 	report := buildReport(results, "grpc://"+grpcAddr, level, suiteDuration)
+	report.Submitter = lib.NewSubmitter(submitterOrg, submitterContact, submitterKeyID, anonymous)
+	if redactor != nil {
+		redactor.Report(&report)
+	}
 
 	// Write report file if requested
 	if reportFile != "" {
@@ -145,24 +229,24 @@ func main() {
 	}
 
 	// Output results
-	switch outputFormat {
-	case "json":
-		outputJSON(report)
-	default:
-		outputTable(report, results, verbose)
+	for _, o := range outputs {
+		if o.Format == lib.FormatTable {
+			outputTable(report, results, verbose)
+			continue
+		}
+		if err := lib.WriteOutput(o, report, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s output: %v\n", o.Format, err)
+			os.Exit(2)
+		}
+		if o.Path != "" {
+			fmt.Fprintf(os.Stderr, "%s report written to %s\n", o.Format, o.Path)
+		}
 	}
 
-	// Exit code
-	if report.Conformant {
+	// Exit code: with a baseline, only regressions fail the run
+	if report.Conformant || (baseline != nil && report.Results.Regressions() == 0) {
 		os.Exit(0)
 	} else {
 		os.Exit(1)
 	}
 }
-
-// outputJSON writes the report as JSON to stdout.
-func outputJSON(report lib.SuiteReport) {
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	enc.Encode(report)
-}
@@ -0,0 +1,103 @@
+package main
+
+// Route files: extra HTTP → gRPC mappings supplied with -routes.
+//
+// A backend with RPCs the built-in table does not know, or that names the
+// extension RPCs differently, can map them without forking the runner:
+//
+//	routes:
+//	  - action: POST
+//	    path: /ojs/v1/billing/invoices
+//	    rpc: acme.billing.v1.BillingService/CreateInvoice
+//	    status: 201
+//	  - action: GET
+//	    path: /ojs/v1/billing/invoices/{invoice_id}
+//	    rpc: acme.billing.v1.BillingService/GetInvoice
+//
+// The same document may be written as JSON. Unknown keys are rejected in
+// either form.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// routeFile is the document read by LoadRoutes.
+type routeFile struct {
+	Routes []routeEntry `json:"routes" yaml:"routes"`
+}
+
+// routeEntry is one route in a route file. Path is matched exactly unless
+// it has {name} segments or Prefix is set.
+type routeEntry struct {
+	Action string `json:"action" yaml:"action"`
+	Path   string `json:"path" yaml:"path"`
+	RPC    string `json:"rpc" yaml:"rpc"`
+	Prefix bool   `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// LoadRoutes reads a route file, in JSON or YAML.
+func LoadRoutes(path string) ([]RouteMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f routeFile
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&f); errors.Is(err, io.EOF) {
+			err = errors.New("empty route file")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	routes := make([]RouteMapping, 0, len(f.Routes))
+	for i, e := range f.Routes {
+		r := RouteMapping{
+			HTTPAction: strings.ToUpper(e.Action),
+			PathPrefix: e.Path,
+			RPCMethod:  e.RPC,
+			Status:     e.Status,
+		}
+		r.Exact = !e.Prefix && !r.isPattern()
+		if err := checkRoute(r, e.Prefix); err != nil {
+			return nil, fmt.Errorf("%s: route %d: %w", path, i+1, err)
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+func checkRoute(r RouteMapping, prefix bool) error {
+	switch r.HTTPAction {
+	case "GET", "POST", "PUT", "PATCH", "DELETE":
+	default:
+		return fmt.Errorf("action %q is not GET, POST, PUT, PATCH or DELETE", r.HTTPAction)
+	}
+	switch {
+	case !strings.HasPrefix(r.PathPrefix, "/"):
+		return fmt.Errorf("path %q does not start with /", r.PathPrefix)
+	case prefix && r.isPattern():
+		return fmt.Errorf("path %q: a prefix cannot have {name} segments", r.PathPrefix)
+	case r.RPCMethod == "":
+		return fmt.Errorf("%s %s has no rpc", r.HTTPAction, r.PathPrefix)
+	case r.Status != 0 && (r.Status < 100 || r.Status > 599):
+		return fmt.Errorf("status %d is not an HTTP status", r.Status)
+	}
+	return nil
+}
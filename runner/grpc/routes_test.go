@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRoutes(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRoutes(t *testing.T) {
+	want := []RouteMapping{
+		{HTTPAction: "POST", PathPrefix: "/ojs/v1/billing/invoices", RPCMethod: "acme.billing.v1.BillingService/CreateInvoice", Exact: true, Status: 201},
+		{HTTPAction: "GET", PathPrefix: "/ojs/v1/billing/invoices/{invoice_id}", RPCMethod: "acme.billing.v1.BillingService/GetInvoice"},
+		{HTTPAction: "DELETE", PathPrefix: "/ojs/v1/billing/", RPCMethod: "DeleteInvoice"},
+	}
+	files := map[string]string{
+		"json": `{
+		  "routes": [
+		    {"action": "post", "path": "/ojs/v1/billing/invoices", "rpc": "acme.billing.v1.BillingService/CreateInvoice", "status": 201},
+		    {"action": "GET", "path": "/ojs/v1/billing/invoices/{invoice_id}", "rpc": "acme.billing.v1.BillingService/GetInvoice"},
+		    {"action": "DELETE", "path": "/ojs/v1/billing/", "rpc": "DeleteInvoice", "prefix": true}
+		  ]
+		}`,
+		"yaml": `# Acme billing RPCs.
+routes:
+  - action: post
+    path: /ojs/v1/billing/invoices
+    rpc: acme.billing.v1.BillingService/CreateInvoice
+    status: 201
+  - {action: GET, path: "/ojs/v1/billing/invoices/{invoice_id}", rpc: acme.billing.v1.BillingService/GetInvoice}
+  - action: DELETE
+    path: /ojs/v1/billing/
+    rpc: DeleteInvoice
+    prefix: true
+`,
+	}
+	for name, data := range files {
+		routes, err := LoadRoutes(writeRoutes(t, data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(routes) != len(want) {
+			t.Fatalf("%s: got %d routes, want %d: %+v", name, len(routes), len(want), routes)
+		}
+		for i := range want {
+			if routes[i] != want[i] {
+				t.Errorf("%s: route %d: got %+v, want %+v", name, i+1, routes[i], want[i])
+			}
+		}
+	}
+}
+
+func TestLoadRoutesRejected(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"empty", "", "empty route file"},
+		{"unknown key", `{"routes": [{"action": "GET", "path": "/x", "rpc": "X", "method": "GET"}]}`, `unknown field "method"`},
+		{"unknown yaml key", "routes:\n  - action: GET\n    path: /x\n    rpc: X\n    method: GET\n", "field method not found"},
+		{"yaml syntax", "routes:\n  - action: GET\n   path: /x\n", "yaml:"},
+		{"yaml action", "routes:\n  - action: HEAD\n    path: /x\n    rpc: X\n", `route 1: action "HEAD"`},
+		{"action", `{"routes": [{"action": "HEAD", "path": "/x", "rpc": "X"}]}`, `route 1: action "HEAD"`},
+		{"path", `{"routes": [{"action": "GET", "path": "x", "rpc": "X"}]}`, "does not start with /"},
+		{"prefix pattern", `{"routes": [{"action": "GET", "path": "/x/{id}", "rpc": "X", "prefix": true}]}`, "a prefix cannot have {name} segments"},
+		{"rpc", `{"routes": [{"action": "GET", "path": "/x", "rpc": "X"}, {"action": "GET", "path": "/y"}]}`, "route 2: GET /y has no rpc"},
+		{"status", `{"routes": [{"action": "GET", "path": "/x", "rpc": "X", "status": 42}]}`, "status 42"},
+	}
+	for _, tt := range tests {
+		_, err := LoadRoutes(writeRoutes(t, tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	if _, err := LoadRoutes(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected a missing file to be an error")
+	}
+}
@@ -1,13 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,43 +14,7 @@ import (
 	"google.golang.org/grpc/codes"
 )
 
-var templateRefPattern = regexp.MustCompile(`\{\{steps\.([^.]+)\.response\.body\.([^}]+)\}\}`)
-
-// loadTests recursively loads all JSON test files from a directory.
-func loadTests(dir string) ([]lib.TestCase, error) {
-	var tests []lib.TestCase
-
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() || !strings.HasSuffix(path, ".json") {
-			return nil
-		}
-
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("reading %s: %w", path, err)
-		}
-
-		var tc lib.TestCase
-		if err := json.Unmarshal(data, &tc); err != nil {
-			return fmt.Errorf("parsing %s: %w", path, err)
-		}
-		tc.FilePath = path
-		if _, err := tc.ParseLevel(); err != nil {
-			return fmt.Errorf("parsing %s: level: %w", path, err)
-		}
-		tests = append(tests, tc)
-		return nil
-	})
-
-	sort.Slice(tests, func(i, j int) bool {
-		return tests[i].TestID < tests[j].TestID
-	})
-
-	return tests, err
-}
+var templateRefPattern = regexp.MustCompile(`\{\{steps\.([^.]+)\.response\.body(?:\.([^}]+))?\}\}`)
 
 // filterTests applies level, category, and test ID filters.
 func filterTests(tests []lib.TestCase, level int, category, testID string) []lib.TestCase {
@@ -72,6 +34,17 @@ func filterTests(tests []lib.TestCase, level int, category, testID string) []lib
 	return filtered
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
 // runTest executes a single test case and returns the result.
 func runTest(tc lib.TestCase, client *OJSClient, rpcTimeout time.Duration, timingCfg lib.TimingConfig, verbose bool) lib.TestResult {
 	start := time.Now()
@@ -85,11 +58,12 @@ func runTest(tc lib.TestCase, client *OJSClient, rpcTimeout time.Duration, timin
 	}
 
 	stepResults := make(map[string]*lib.StepResult)
+	vars := lib.NewVars()
 
 	// Run setup steps
 	if tc.Setup != nil {
 		for _, step := range tc.Setup.Steps {
-			sr, failures := executeStep(step, client, rpcTimeout, stepResults, timingCfg)
+			sr, failures := executeStep(step, client, rpcTimeout, stepResults, vars, timingCfg)
 			stepResults[step.ID] = sr
 			if len(failures) > 0 {
 				result.Status = "error"
@@ -104,14 +78,14 @@ func runTest(tc lib.TestCase, client *OJSClient, rpcTimeout time.Duration, timin
 	}
 
 	// Run test steps (with parallel execution support)
-	allResults, allFailures := executeStepsWithParallel(tc.Steps, client, rpcTimeout, stepResults, timingCfg)
+	allResults, allFailures := executeStepsWithParallel(tc.Steps, client, rpcTimeout, stepResults, vars, timingCfg)
 	result.StepResults = append(result.StepResults, allResults...)
 	result.Failures = append(result.Failures, allFailures...)
 
 	// Run teardown steps
 	if tc.Teardown != nil {
 		for _, step := range tc.Teardown.Steps {
-			sr, _ := executeStep(step, client, rpcTimeout, stepResults, timingCfg)
+			sr, _ := executeStep(step, client, rpcTimeout, stepResults, vars, timingCfg)
 			stepResults[step.ID] = sr
 		}
 	}
@@ -127,7 +101,7 @@ func runTest(tc lib.TestCase, client *OJSClient, rpcTimeout time.Duration, timin
 
 // executeStepsWithParallel runs steps sequentially, except steps linked by
 // parallel_with which are executed concurrently via goroutines.
-func executeStepsWithParallel(steps []lib.Step, client *OJSClient, rpcTimeout time.Duration, stepResults map[string]*lib.StepResult, timingCfg lib.TimingConfig) ([]lib.StepResult, []lib.Failure) {
+func executeStepsWithParallel(steps []lib.Step, client *OJSClient, rpcTimeout time.Duration, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) ([]lib.StepResult, []lib.Failure) {
 	var allResults []lib.StepResult
 	var allFailures []lib.Failure
 
@@ -161,7 +135,7 @@ func executeStepsWithParallel(steps []lib.Step, client *OJSClient, rpcTimeout ti
 					wg.Add(1)
 					go func(idx int, s lib.Step) {
 						defer wg.Done()
-						sr, failures := executeStep(s, client, rpcTimeout, stepResults, timingCfg)
+						sr, failures := executeStep(s, client, rpcTimeout, stepResults, vars, timingCfg)
 						mu.Lock()
 						stepResults[s.ID] = sr
 						mu.Unlock()
@@ -179,7 +153,7 @@ func executeStepsWithParallel(steps []lib.Step, client *OJSClient, rpcTimeout ti
 			}
 		}
 
-		sr, failures := executeStep(step, client, rpcTimeout, stepResults, timingCfg)
+		sr, failures := executeStep(step, client, rpcTimeout, stepResults, vars, timingCfg)
 		stepResults[step.ID] = sr
 		allResults = append(allResults, *sr)
 		allFailures = append(allFailures, failures...)
@@ -207,7 +181,7 @@ func collectParallelGroup(steps []lib.Step, triggerID string, groups map[string]
 }
 
 // executeStep runs a single gRPC step and evaluates its assertions.
-func executeStep(step lib.Step, client *OJSClient, rpcTimeout time.Duration, stepResults map[string]*lib.StepResult, timingCfg lib.TimingConfig) (*lib.StepResult, []lib.Failure) {
+func executeStep(step lib.Step, client *OJSClient, rpcTimeout time.Duration, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) (*lib.StepResult, []lib.Failure) {
 	// Apply delay if specified
 	if step.DelayMs > 0 {
 		time.Sleep(time.Duration(step.DelayMs) * time.Millisecond)
@@ -225,59 +199,52 @@ func executeStep(step lib.Step, client *OJSClient, rpcTimeout time.Duration, ste
 		return &lib.StepResult{StepID: step.ID}, nil
 	}
 
-	// Resolve template references in path
-	path := resolveTemplates(step.Path, stepResults)
+	// Handle ASSERT action (no RPC; body assertions resolve against
+	// the results of earlier steps via $.steps.<id>.response paths)
+	if step.IsAssert() {
+		if failures := lib.CheckAssertStep(step); len(failures) > 0 {
+			return &lib.StepResult{StepID: step.ID}, failures
+		}
+		scope := &lib.StepResult{StepID: step.ID, Parsed: lib.AssertScope(stepResults)}
+		return &lib.StepResult{StepID: step.ID}, evaluateAssertions(step, scope, stepResults, vars, timingCfg)
+	}
+
+	if step.Poll != nil {
+		return lib.PollStep(step, timingCfg, func() (*lib.StepResult, []lib.Failure) {
+			return sendStep(step, client, rpcTimeout, stepResults, vars, timingCfg)
+		})
+	}
+	return sendStep(step, client, rpcTimeout, stepResults, vars, timingCfg)
+}
 
-	// Resolve template references in body and parse to map
+// sendStep issues a step's RPC, then captures variables and evaluates its
+// assertions against the response.
+func sendStep(step lib.Step, client *OJSClient, rpcTimeout time.Duration, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) (*lib.StepResult, []lib.Failure) {
+	// Resolve template and ${var} references in path
+	path := vars.Interpolate(resolveTemplates(step.Path, stepResults))
+
+	// Resolve template and ${var} references in body and parse to map
 	var body map[string]any
 	if step.Body != nil {
-		bodyStr := resolveTemplates(string(step.Body), stepResults)
+		bodyStr := vars.Interpolate(string(resolveJSONTemplates(step.Body, stepResults)))
 		_ = json.Unmarshal([]byte(bodyStr), &body)
 	}
 
 	// Resolve HTTP action+path to gRPC method
-	method := ResolveRoute(step.Action, path)
-	if method == "" {
+	route, params, ok := MatchRoute(step.Action, path)
+	if !ok {
 		return &lib.StepResult{StepID: step.ID}, []lib.Failure{{
 			StepID:  step.ID,
 			Message: fmt.Sprintf("Cannot resolve gRPC method for %s %s", step.Action, path),
 		}}
 	}
 
-	// Handle PauseOrResumeQueue disambiguation
-	if method == "PauseOrResumeQueue" {
-		if strings.HasSuffix(path, "/pause") {
-			method = "PauseQueue"
-		} else if strings.HasSuffix(path, "/resume") {
-			method = "ResumeQueue"
-		}
-	}
-
-	// Handle checkpoint vs job disambiguation for /ojs/v1/jobs/{id}/checkpoint
-	if method == "SaveCheckpoint" && !strings.HasSuffix(path, "/checkpoint") {
-		method = "" // Not a checkpoint path; no PUT on jobs
-	}
-	if method == "GetCheckpointOrJob" {
-		if strings.HasSuffix(path, "/checkpoint") {
-			method = "GetCheckpoint"
-		} else {
-			method = "GetJob"
-		}
-	}
-	if method == "DeleteCheckpointOrCancel" {
-		if strings.HasSuffix(path, "/checkpoint") {
-			method = "DeleteCheckpoint"
-		} else {
-			method = "CancelJob"
-		}
-	}
-
 	// Execute RPC
 	ctx, cancel := context.WithTimeout(context.Background(), rpcTimeout)
 	defer cancel()
 
 	reqStart := time.Now()
-	rpcResult, err := client.CallRPC(ctx, method, path, body)
+	rpcResult, err := client.CallRPC(ctx, route.RPCMethod, path, params, body)
 	reqDuration := time.Since(reqStart)
 
 	if err != nil {
@@ -297,6 +264,9 @@ func executeStep(step lib.Step, client *OJSClient, rpcTimeout time.Duration, ste
 	// Some RPCs override the default gRPC-to-HTTP mapping (e.g., Enqueue returns
 	// 201 Created on success instead of 200 OK).
 	httpStatus := GRPCCodeToHTTPStatus(rpcResult.GRPCCode)
+	if route.Status > 0 {
+		rpcResult.HTTPStatusOverride = route.Status
+	}
 	if rpcResult.HTTPStatusOverride > 0 && rpcResult.GRPCCode == codes.OK {
 		httpStatus = rpcResult.HTTPStatusOverride
 	}
@@ -309,17 +279,17 @@ func executeStep(step lib.Step, client *OJSClient, rpcTimeout time.Duration, ste
 		Parsed:     parsed,
 	}
 
-	// Evaluate assertions
-	var failures []lib.Failure
+	// Capture named variables, then evaluate assertions
+	failures := vars.Capture(step, parsed)
 	if step.Assertions != nil {
-		failures = evaluateAssertions(step, sr, stepResults, timingCfg)
+		failures = append(failures, evaluateAssertions(step, sr, stepResults, vars, timingCfg)...)
 	}
 
 	return sr, failures
 }
 
 // evaluateAssertions checks all assertions for a step result.
-func evaluateAssertions(step lib.Step, sr *lib.StepResult, stepResults map[string]*lib.StepResult, timingCfg lib.TimingConfig) []lib.Failure {
+func evaluateAssertions(step lib.Step, sr *lib.StepResult, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) []lib.Failure {
 	var failures []lib.Failure
 	a := step.Assertions
 
@@ -368,7 +338,7 @@ func evaluateAssertions(step lib.Step, sr *lib.StepResult, stepResults map[strin
 					if json.Unmarshal(alt, &altBody) == nil {
 						altFailed := false
 						for p, m := range altBody {
-							resolvedMatcher := resolveMatcherTemplates(m, stepResults)
+							resolvedMatcher := resolveMatcherTemplates(m, stepResults, vars)
 							val, err := lib.ResolveJSONPath(p, sr.Parsed)
 							if err != nil || lib.MatchAssertion(resolvedMatcher, val) != nil {
 								altFailed = true
@@ -398,8 +368,8 @@ func evaluateAssertions(step lib.Step, sr *lib.StepResult, stepResults map[strin
 					continue
 				}
 
-				resolvedPath := resolveTemplates(path, stepResults)
-				resolvedMatcher := resolveMatcherTemplates(matcher, stepResults)
+				resolvedPath := vars.Interpolate(resolveTemplates(path, stepResults))
+				resolvedMatcher := resolveMatcherTemplates(matcher, stepResults, vars)
 
 				val, err := lib.ResolveJSONPath(resolvedPath, sr.Parsed)
 				if err != nil {
@@ -496,6 +466,7 @@ func evaluateAssertions(step lib.Step, sr *lib.StepResult, stepResults map[strin
 }
 
 // resolveTemplates replaces {{steps.step-id.response.body.field}} references.
+// {{steps.step-id.response.body}} refers to the whole body.
 func resolveTemplates(input string, stepResults map[string]*lib.StepResult) string {
 	return templateRefPattern.ReplaceAllStringFunc(input, func(match string) string {
 		parts := templateRefPattern.FindStringSubmatch(match)
@@ -530,17 +501,61 @@ func resolveTemplates(input string, stepResults map[string]*lib.StepResult) stri
 	})
 }
 
-// resolveMatcherTemplates resolves template references within a JSON assertion matcher value.
-func resolveMatcherTemplates(matcher json.RawMessage, stepResults map[string]*lib.StepResult) json.RawMessage {
-	s := string(matcher)
-	if !strings.Contains(s, "{{steps.") {
-		return matcher
+// resolveMatcherTemplates resolves template and ${var} references within a JSON assertion matcher value,
+// as resolveJSONTemplates does, so {"$eq": "{{steps.step-2.response.body}}"}
+// compares whole bodies.
+func resolveMatcherTemplates(matcher json.RawMessage, stepResults map[string]*lib.StepResult, vars *lib.Vars) json.RawMessage {
+	return resolveJSONTemplates(vars.InterpolateRaw(matcher), stepResults)
+}
+
+// resolveJSONTemplates resolves template references within the strings of
+// a JSON document and re-encodes it, so substituted values are escaped and
+// the result stays valid JSON. A string that is nothing but a template
+// referring to an object or array is replaced by that value rather than
+// its text. A document that is not JSON is resolved as text.
+func resolveJSONTemplates(raw json.RawMessage, stepResults map[string]*lib.StepResult) json.RawMessage {
+	if !strings.Contains(string(raw), "{{steps.") {
+		return raw
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return json.RawMessage(resolveTemplates(string(raw), stepResults))
+	}
+	b, err := json.Marshal(resolveValueTemplates(v, stepResults))
+	if err != nil {
+		return raw
 	}
-	resolved := resolveTemplates(s, stepResults)
-	if resolved != s {
-		return json.RawMessage(resolved)
+	return b
+}
+
+// resolveValueTemplates resolves template references within the strings of
+// a decoded JSON value.
+func resolveValueTemplates(v any, stepResults map[string]*lib.StepResult) any {
+	switch x := v.(type) {
+	case string:
+		if parts := templateRefPattern.FindStringSubmatch(x); parts != nil && parts[0] == x {
+			if sr, ok := stepResults[parts[1]]; ok && sr.Parsed != nil {
+				if val, err := lib.ResolveJSONPath(parts[2], sr.Parsed); err == nil {
+					switch val.(type) {
+					case map[string]any, []any:
+						return val
+					}
+				}
+			}
+		}
+		return resolveTemplates(x, stepResults)
+	case map[string]any:
+		for k, e := range x {
+			x[k] = resolveValueTemplates(e, stepResults)
+		}
+	case []any:
+		for i, e := range x {
+			x[i] = resolveValueTemplates(e, stepResults)
+		}
 	}
-	return matcher
+	return v
 }
 
 func buildReport(results []lib.TestResult, target string, requestedLevel int, duration time.Duration) lib.SuiteReport {
@@ -555,6 +570,7 @@ func buildReport(results []lib.TestResult, target string, requestedLevel int, du
 		Commit:              lib.CaptureCommit(),
 		Environment:         lib.CaptureEnvironment(),
 		Backend:             &lib.BackendInfo{URL: target},
+		Tests:               lib.Outcomes(results),
 		Results:             summary,
 		Conformant:          false,
 		ConformantLevel:     -1,
@@ -569,6 +585,15 @@ func buildReport(results []lib.TestResult, target string, requestedLevel int, du
 		case "pass":
 			summary.Passed++
 			levelSummary.Passed++
+		case lib.StatusXPass:
+			summary.Passed++
+			summary.XPassed++
+			levelSummary.Passed++
+			levelSummary.XPassed++
+		case lib.StatusXFail:
+			summary.XFailed++
+			levelSummary.XFailed++
+			report.Failures = append(report.Failures, tr)
 		case "skip":
 			summary.Skipped++
 			levelSummary.Skipped++
@@ -629,6 +654,10 @@ func outputTable(report lib.SuiteReport, results []lib.TestResult, verbose bool)
 			status = "SKIP"
 		case "error":
 			status = "ERR"
+		case lib.StatusXFail:
+			status = "XFAIL"
+		case lib.StatusXPass:
+			status = "XPASS"
 		}
 
 		name := r.Name
@@ -638,7 +667,16 @@ func outputTable(report lib.SuiteReport, results []lib.TestResult, verbose bool)
 
 		fmt.Printf("  %-14s %-40s %-8s %dms\n", r.TestID, name, status, r.DurationMs)
 
-		if r.Status == "fail" || r.Status == "error" {
+		// Show polling statistics in verbose mode
+		if verbose {
+			for _, sr := range r.StepResults {
+				if sr.PollAttempts > 0 {
+					fmt.Printf("    ~  [%s] polled %d attempt(s) in %dms\n", sr.StepID, sr.PollAttempts, sr.PollElapsedMs)
+				}
+			}
+		}
+
+		if r.Status == "fail" || r.Status == "error" || (verbose && r.Status == lib.StatusXFail) {
 			for _, f := range r.Failures {
 				fmt.Printf("    -> [%s] %s\n", f.StepID, f.Message)
 				if verbose && f.Expected != "" {
@@ -674,6 +712,10 @@ func outputTable(report lib.SuiteReport, results []lib.TestResult, verbose bool)
 	fmt.Printf("  Total: %d | Passed: %d | Failed: %d | Skipped: %d | Errored: %d\n",
 		report.Results.Total, report.Results.Passed, report.Results.Failed,
 		report.Results.Skipped, report.Results.Errored)
+	if report.Results.XFailed > 0 || report.Results.XPassed > 0 {
+		fmt.Printf("  Baseline: %d known failure(s) | %d newly passing | %d regression(s)\n",
+			report.Results.XFailed, report.Results.XPassed, report.Results.Regressions())
+	}
 
 	if report.Conformant {
 		fmt.Printf("  Result: CONFORMANT (Level %d - %s)\n", report.ConformantLevel, lib.LevelName(report.ConformantLevel))
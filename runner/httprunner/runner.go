@@ -0,0 +1,736 @@
+// Package httprunner runs conformance test cases against an OJS server over
+// HTTP. It is the engine behind the ojs-conformance-runner command and the
+// certification portal.
+package httprunner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+const (
+	// SuiteVersion is reported as SuiteReport.TestSuiteVersion.
+	SuiteVersion = "1.0"
+	// MediaType is the Content-Type sent with request bodies.
+	MediaType = "application/openjobspec+json"
+)
+
+var templateRefPattern = regexp.MustCompile(`\{\{steps\.([^.]+)\.response\.body(?:\.([^}]+))?\}\}`)
+
+// DefaultTimingConfig is the timing tolerance used unless overridden.
+func DefaultTimingConfig() lib.TimingConfig {
+	return lib.TimingConfig{
+		TolerancePct:   50,
+		MinToleranceMs: 100,
+		MaxWaitMs:      30000,
+	}
+}
+
+// FilterTests applies level, category, and test ID filters.
+func FilterTests(tests []lib.TestCase, level int, category, testID string) []lib.TestCase {
+	var filtered []lib.TestCase
+	for _, tc := range tests {
+		if level >= 0 && tc.LevelInt != level {
+			continue
+		}
+		if category != "" && tc.Category != category {
+			continue
+		}
+		if testID != "" && tc.TestID != testID {
+			continue
+		}
+		filtered = append(filtered, tc)
+	}
+	return filtered
+}
+
+// RunTest executes a single test case and returns the result.
+func RunTest(tc lib.TestCase, baseURL string, client *http.Client, timingCfg lib.TimingConfig) lib.TestResult {
+	start := time.Now()
+	result := lib.TestResult{
+		TestID:   tc.TestID,
+		Name:     tc.Name,
+		Level:    tc.LevelInt,
+		Category: tc.Category,
+		SpecRef:  tc.SpecRef,
+		FilePath: tc.FilePath,
+	}
+
+	// Store step results and captured variables for template resolution
+	stepResults := make(map[string]*lib.StepResult)
+	vars := lib.NewVars()
+
+	// Run setup steps if any
+	if tc.Setup != nil {
+		for _, step := range tc.Setup.Steps {
+			sr, failures := executeStep(step, baseURL, client, stepResults, vars, timingCfg)
+			stepResults[step.ID] = sr
+			if len(failures) > 0 {
+				result.Status = "error"
+				result.Failures = append(result.Failures, lib.Failure{
+					StepID:  step.ID,
+					Message: fmt.Sprintf("Setup step failed: %s", failures[0].Message),
+				})
+				result.DurationMs = time.Since(start).Milliseconds()
+				return result
+			}
+		}
+	}
+
+	// Run test steps (with parallel execution support)
+	result.StepResults, result.Failures = executeStepsWithParallel(tc.Steps, baseURL, client, stepResults, vars, timingCfg)
+
+	// Run teardown steps if any
+	if tc.Teardown != nil {
+		for _, step := range tc.Teardown.Steps {
+			sr, _ := executeStep(step, baseURL, client, stepResults, vars, timingCfg)
+			stepResults[step.ID] = sr
+		}
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if len(result.Failures) > 0 {
+		result.Status = "fail"
+	} else {
+		result.Status = "pass"
+	}
+
+	return result
+}
+
+// executeStepsWithParallel runs steps sequentially, except steps linked by
+// parallel_with which are executed concurrently via goroutines.
+func executeStepsWithParallel(steps []lib.Step, baseURL string, client *http.Client, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) ([]lib.StepResult, []lib.Failure) {
+	var allResults []lib.StepResult
+	var allFailures []lib.Failure
+
+	// Build parallel groups: map step ID → set of step IDs to run together
+	parallelGroups := make(map[string]bool)
+	for _, step := range steps {
+		if step.ParallelWith != "" {
+			parallelGroups[step.ID] = true
+			parallelGroups[step.ParallelWith] = true
+		}
+	}
+
+	var mu sync.Mutex
+	executed := make(map[string]bool)
+
+	for i, step := range steps {
+		if executed[step.ID] {
+			continue
+		}
+
+		// If this step is part of a parallel group, collect all group members
+		if parallelGroups[step.ID] {
+			group := collectParallelGroup(steps[i:], step.ID, parallelGroups)
+			if len(group) > 1 {
+				// Execute group in parallel
+				type parallelResult struct {
+					stepID   string
+					result   *lib.StepResult
+					failures []lib.Failure
+				}
+				results := make([]parallelResult, len(group))
+				var wg sync.WaitGroup
+				for j, gs := range group {
+					wg.Add(1)
+					go func(idx int, s lib.Step) {
+						defer wg.Done()
+						sr, failures := executeStep(s, baseURL, client, stepResults, vars, timingCfg)
+						mu.Lock()
+						stepResults[s.ID] = sr
+						mu.Unlock()
+						results[idx] = parallelResult{stepID: s.ID, result: sr, failures: failures}
+					}(j, gs)
+				}
+				wg.Wait()
+
+				for _, pr := range results {
+					allResults = append(allResults, *pr.result)
+					allFailures = append(allFailures, pr.failures...)
+					executed[pr.stepID] = true
+				}
+				continue
+			}
+		}
+
+		// Sequential execution
+		sr, failures := executeStep(step, baseURL, client, stepResults, vars, timingCfg)
+		stepResults[step.ID] = sr
+		allResults = append(allResults, *sr)
+		allFailures = append(allFailures, failures...)
+		executed[step.ID] = true
+	}
+
+	return allResults, allFailures
+}
+
+// collectParallelGroup collects consecutive steps that are in the parallel group.
+func collectParallelGroup(steps []lib.Step, triggerID string, groupMembers map[string]bool) []lib.Step {
+	var group []lib.Step
+	for _, s := range steps {
+		if groupMembers[s.ID] && (s.ID == triggerID || s.ParallelWith == triggerID || s.ParallelWith != "") {
+			group = append(group, s)
+		}
+		if len(group) > 0 && !groupMembers[s.ID] {
+			break // End of parallel group
+		}
+	}
+	return group
+}
+
+// executeStep runs a single HTTP step and evaluates its assertions.
+func executeStep(step lib.Step, baseURL string, client *http.Client, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) (*lib.StepResult, []lib.Failure) {
+	// Apply delay if specified
+	if step.DelayMs > 0 {
+		time.Sleep(time.Duration(step.DelayMs) * time.Millisecond)
+	}
+
+	// Handle WAIT action (pure delay, no HTTP request)
+	if strings.EqualFold(step.Action, "WAIT") {
+		waitMs := step.DurationMs
+		if waitMs <= 0 {
+			waitMs = step.DelayMs
+		}
+		if waitMs > 0 {
+			time.Sleep(time.Duration(waitMs) * time.Millisecond)
+		}
+		return &lib.StepResult{StepID: step.ID}, nil
+	}
+
+	// Handle ASSERT action (no HTTP request; body assertions resolve against
+	// the results of earlier steps via $.steps.<id>.response paths)
+	if step.IsAssert() {
+		if failures := lib.CheckAssertStep(step); len(failures) > 0 {
+			return &lib.StepResult{StepID: step.ID}, failures
+		}
+		scope := &lib.StepResult{StepID: step.ID, Parsed: lib.AssertScope(stepResults)}
+		return &lib.StepResult{StepID: step.ID}, evaluateAssertions(step, scope, stepResults, vars, timingCfg)
+	}
+
+	if step.Poll != nil {
+		return lib.PollStep(step, timingCfg, func() (*lib.StepResult, []lib.Failure) {
+			return sendStep(step, baseURL, client, stepResults, vars, timingCfg)
+		})
+	}
+	return sendStep(step, baseURL, client, stepResults, vars, timingCfg)
+}
+
+// sendStep issues a step's HTTP request, then captures variables and
+// evaluates its assertions against the response.
+func sendStep(step lib.Step, baseURL string, client *http.Client, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) (*lib.StepResult, []lib.Failure) {
+	// Resolve template and ${var} references in path
+	path := vars.Interpolate(resolveTemplates(step.Path, stepResults))
+
+	// Resolve template and ${var} references in body
+	var body io.Reader
+	if step.Body != nil {
+		bodyStr := vars.Interpolate(string(resolveJSONTemplates(step.Body, stepResults)))
+		body = strings.NewReader(bodyStr)
+	}
+
+	// Build request
+	url := baseURL + path
+	req, err := http.NewRequest(step.Action, url, body)
+	if err != nil {
+		return &lib.StepResult{StepID: step.ID}, []lib.Failure{{
+			StepID:  step.ID,
+			Message: fmt.Sprintf("Failed to create request: %v", err),
+		}}
+	}
+
+	// Set headers
+	if step.Headers != nil {
+		for k, v := range step.Headers {
+			req.Header.Set(k, vars.Interpolate(v))
+		}
+	}
+	// Default content type
+	if req.Header.Get("Content-Type") == "" && body != nil {
+		req.Header.Set("Content-Type", MediaType)
+	}
+
+	// Execute request
+	reqStart := time.Now()
+	resp, err := client.Do(req)
+	reqDuration := time.Since(reqStart)
+
+	if err != nil {
+		return &lib.StepResult{StepID: step.ID}, []lib.Failure{{
+			StepID:  step.ID,
+			Message: fmt.Sprintf("HTTP request failed: %v", err),
+		}}
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &lib.StepResult{StepID: step.ID}, []lib.Failure{{
+			StepID:  step.ID,
+			Message: fmt.Sprintf("Failed to read response body: %v", err),
+		}}
+	}
+
+	// Parse response body
+	var parsed map[string]any
+	if len(respBody) > 0 {
+		_ = json.Unmarshal(respBody, &parsed)
+	}
+
+	sr := &lib.StepResult{
+		StepID:     step.ID,
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header,
+		Body:       json.RawMessage(respBody),
+		DurationMs: reqDuration.Milliseconds(),
+		Parsed:     parsed,
+	}
+
+	// Capture named variables, then evaluate assertions
+	failures := vars.Capture(step, parsed)
+	if step.Assertions != nil {
+		failures = append(failures, evaluateAssertions(step, sr, stepResults, vars, timingCfg)...)
+	}
+
+	return sr, failures
+}
+
+// evaluateAssertions checks all assertions for a step result.
+func evaluateAssertions(step lib.Step, sr *lib.StepResult, stepResults map[string]*lib.StepResult, vars *lib.Vars, timingCfg lib.TimingConfig) []lib.Failure {
+	var failures []lib.Failure
+	a := step.Assertions
+
+	// Status code assertion (supports int, string matchers, and object matchers)
+	if len(a.Status) > 0 {
+		if err := evaluateStatusAssertion(a.Status, sr.StatusCode); err != nil {
+			failures = append(failures, lib.Failure{
+				StepID:   step.ID,
+				Field:    "status",
+				Expected: string(a.Status),
+				Actual:   fmt.Sprintf("%d", sr.StatusCode),
+				Message:  err.Error(),
+			})
+		}
+	}
+
+	// Status code in list assertion
+	if len(a.StatusIn) > 0 {
+		found := false
+		for _, s := range a.StatusIn {
+			if sr.StatusCode == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			failures = append(failures, lib.Failure{
+				StepID:   step.ID,
+				Field:    "status",
+				Expected: fmt.Sprintf("one of %v", a.StatusIn),
+				Actual:   fmt.Sprintf("%d", sr.StatusCode),
+				Message:  fmt.Sprintf("Expected status in %v, got %d", a.StatusIn, sr.StatusCode),
+			})
+		}
+	}
+
+	// Body assertions using JSON path
+	if a.Body != nil {
+		// Handle special top-level operators like $or and $empty
+		if orRaw, ok := a.Body["$or"]; ok {
+			var alternatives []json.RawMessage
+			if json.Unmarshal(orRaw, &alternatives) == nil {
+				matched := false
+				for _, alt := range alternatives {
+					var altBody map[string]json.RawMessage
+					if json.Unmarshal(alt, &altBody) == nil {
+						altFailed := false
+						for p, m := range altBody {
+							resolvedMatcher := resolveMatcherTemplates(m, stepResults, vars)
+							val, err := lib.ResolveJSONPath(p, sr.Parsed)
+							if err != nil || lib.MatchAssertion(resolvedMatcher, val) != nil {
+								altFailed = true
+								break
+							}
+						}
+						if !altFailed {
+							matched = true
+							break
+						}
+					}
+				}
+				if !matched {
+					failures = append(failures, lib.Failure{
+						StepID:  step.ID,
+						Field:   "$or",
+						Message: "No $or alternative matched",
+					})
+				}
+			}
+		}
+
+		if sr.Parsed != nil {
+			for path, matcher := range a.Body {
+				// Skip special top-level operators (but NOT $.path expressions)
+				if strings.HasPrefix(path, "$") && !strings.HasPrefix(path, "$.") {
+					continue
+				}
+
+				// Resolve template references in assertion paths AND matchers
+				resolvedPath := vars.Interpolate(resolveTemplates(path, stepResults))
+				resolvedMatcher := resolveMatcherTemplates(matcher, stepResults, vars)
+
+				val, err := lib.ResolveJSONPath(resolvedPath, sr.Parsed)
+				if err != nil {
+					// Check if matcher is "absent" - field not found is ok
+					var matcherStr string
+					if json.Unmarshal(resolvedMatcher, &matcherStr) == nil && matcherStr == "absent" {
+						continue
+					}
+					failures = append(failures, lib.Failure{
+						StepID:  step.ID,
+						Field:   path,
+						Message: fmt.Sprintf("Failed to resolve path %q: %v", path, err),
+					})
+					continue
+				}
+
+				if err := lib.MatchAssertion(resolvedMatcher, val); err != nil {
+					actualStr := "null"
+					if val != nil {
+						b, _ := json.Marshal(val)
+						actualStr = string(b)
+					}
+					failures = append(failures, lib.Failure{
+						StepID:   step.ID,
+						Field:    path,
+						Expected: string(resolvedMatcher),
+						Actual:   actualStr,
+						Message:  fmt.Sprintf("Assertion failed at %q: %v", path, err),
+					})
+				}
+			}
+		}
+	}
+
+	// Body absent assertions
+	for _, path := range a.BodyAbsent {
+		val, _ := lib.ResolveJSONPath(path, sr.Parsed)
+		if val != nil {
+			failures = append(failures, lib.Failure{
+				StepID:  step.ID,
+				Field:   path,
+				Message: fmt.Sprintf("Expected field %q to be absent", path),
+			})
+		}
+	}
+
+	// Header assertions
+	if headerMatchers := a.ParsedHeaderMatchers(); len(headerMatchers) > 0 {
+		for key, hm := range headerMatchers {
+			actual := sr.Headers.Get(key)
+			matched := false
+			if hm.IsRegex {
+				if re, err := regexp.Compile(hm.Value); err == nil {
+					matched = re.MatchString(actual)
+				}
+			} else {
+				matched = actual == hm.Value
+			}
+			if !matched {
+				failures = append(failures, lib.Failure{
+					StepID:   step.ID,
+					Field:    fmt.Sprintf("header:%s", key),
+					Expected: hm.Value,
+					Actual:   actual,
+					Message:  fmt.Sprintf("Expected header %q=%q, got %q", key, hm.Value, actual),
+				})
+			}
+		}
+	}
+
+	// Timing assertions
+	if a.TimingMs != nil {
+		if a.TimingMs.LessThan != nil {
+			if sr.DurationMs >= int64(*a.TimingMs.LessThan) {
+				failures = append(failures, lib.Failure{
+					StepID:   step.ID,
+					Field:    "timing",
+					Expected: fmt.Sprintf("< %dms", *a.TimingMs.LessThan),
+					Actual:   fmt.Sprintf("%dms", sr.DurationMs),
+					Message:  fmt.Sprintf("Expected response in < %dms, took %dms", *a.TimingMs.LessThan, sr.DurationMs),
+				})
+			}
+		}
+		if a.TimingMs.GreaterThan != nil {
+			if sr.DurationMs <= int64(*a.TimingMs.GreaterThan) {
+				failures = append(failures, lib.Failure{
+					StepID:   step.ID,
+					Field:    "timing",
+					Expected: fmt.Sprintf("> %dms", *a.TimingMs.GreaterThan),
+					Actual:   fmt.Sprintf("%dms", sr.DurationMs),
+					Message:  fmt.Sprintf("Expected response in > %dms, took %dms", *a.TimingMs.GreaterThan, sr.DurationMs),
+				})
+			}
+		}
+		if a.TimingMs.Approximate != nil {
+			if err := timingCfg.AssertApproximateMs(float64(*a.TimingMs.Approximate), float64(sr.DurationMs)); err != nil {
+				failures = append(failures, lib.Failure{
+					StepID:  step.ID,
+					Field:   "timing",
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	// Body contains assertions (substring check on raw body)
+	for _, substr := range a.BodyContains {
+		if !strings.Contains(string(sr.Body), substr) {
+			failures = append(failures, lib.Failure{
+				StepID:   step.ID,
+				Field:    "body_contains",
+				Expected: fmt.Sprintf("body containing %q", substr),
+				Message:  fmt.Sprintf("Response body does not contain %q", substr),
+			})
+		}
+	}
+
+	return failures
+}
+
+// resolveTemplates replaces {{steps.step-id.response.body.field}} references.
+// {{steps.step-id.response.body}} refers to the whole body.
+func resolveTemplates(input string, stepResults map[string]*lib.StepResult) string {
+	return templateRefPattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := templateRefPattern.FindStringSubmatch(match)
+		if len(parts) != 3 {
+			return match
+		}
+		stepID := parts[1]
+		fieldPath := parts[2]
+
+		sr, ok := stepResults[stepID]
+		if !ok || sr.Parsed == nil {
+			return match
+		}
+
+		val, err := lib.ResolveJSONPath(fieldPath, sr.Parsed)
+		if err != nil || val == nil {
+			return match
+		}
+
+		switch v := val.(type) {
+		case string:
+			return v
+		case float64:
+			if v == float64(int64(v)) {
+				return fmt.Sprintf("%d", int64(v))
+			}
+			return fmt.Sprintf("%v", v)
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	})
+}
+
+// resolveMatcherTemplates resolves {{steps.step-id.response.body.field}} and
+// ${var} references within a JSON assertion matcher value, as
+// resolveJSONTemplates does, so {"$eq": "{{steps.step-2.response.body}}"}
+// compares whole bodies.
+func resolveMatcherTemplates(matcher json.RawMessage, stepResults map[string]*lib.StepResult, vars *lib.Vars) json.RawMessage {
+	return resolveJSONTemplates(vars.InterpolateRaw(matcher), stepResults)
+}
+
+// resolveJSONTemplates resolves template references within the strings of
+// a JSON document and re-encodes it, so substituted values are escaped and
+// the result stays valid JSON. A string that is nothing but a template
+// referring to an object or array is replaced by that value rather than
+// its text. A document that is not JSON is resolved as text.
+func resolveJSONTemplates(raw json.RawMessage, stepResults map[string]*lib.StepResult) json.RawMessage {
+	if !strings.Contains(string(raw), "{{steps.") {
+		return raw
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return json.RawMessage(resolveTemplates(string(raw), stepResults))
+	}
+	b, err := json.Marshal(resolveValueTemplates(v, stepResults))
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+// resolveValueTemplates resolves template references within the strings of
+// a decoded JSON value.
+func resolveValueTemplates(v any, stepResults map[string]*lib.StepResult) any {
+	switch x := v.(type) {
+	case string:
+		if parts := templateRefPattern.FindStringSubmatch(x); parts != nil && parts[0] == x {
+			if sr, ok := stepResults[parts[1]]; ok && sr.Parsed != nil {
+				if val, err := lib.ResolveJSONPath(parts[2], sr.Parsed); err == nil {
+					switch val.(type) {
+					case map[string]any, []any:
+						return val
+					}
+				}
+			}
+		}
+		return resolveTemplates(x, stepResults)
+	case map[string]any:
+		for k, e := range x {
+			x[k] = resolveValueTemplates(e, stepResults)
+		}
+	case []any:
+		for i, e := range x {
+			x[i] = resolveValueTemplates(e, stepResults)
+		}
+	}
+	return v
+}
+
+// BuildReport aggregates test results into a conformance report.
+func BuildReport(results []lib.TestResult, target string, requestedLevel int, duration time.Duration) lib.SuiteReport {
+	report := lib.SuiteReport{
+		TestSuiteVersion:    SuiteVersion,
+		ReportSchemaVersion: lib.SchemaVersionV11,
+		Target:              target,
+		RunAt:               time.Now().UTC().Format(time.RFC3339),
+		DurationMs:          duration.Milliseconds(),
+		RequestedLevel:      requestedLevel,
+		Commit:              lib.CaptureCommit(),
+		Environment:         lib.CaptureEnvironment(),
+		Backend:             &lib.BackendInfo{URL: target},
+		Tests:               lib.Outcomes(results),
+		Results: lib.ResultsSummary{
+			Total:   len(results),
+			ByLevel: make(map[int]lib.LevelSummary),
+		},
+	}
+
+	for _, r := range results {
+		report.Results.Total = len(results)
+		ls := report.Results.ByLevel[r.Level]
+		ls.Total++
+
+		switch r.Status {
+		case "pass":
+			report.Results.Passed++
+			ls.Passed++
+		case lib.StatusXPass:
+			report.Results.Passed++
+			report.Results.XPassed++
+			ls.Passed++
+			ls.XPassed++
+		case lib.StatusXFail:
+			report.Results.XFailed++
+			ls.XFailed++
+			report.Failures = append(report.Failures, r)
+		case "fail":
+			report.Results.Failed++
+			ls.Failed++
+			report.Failures = append(report.Failures, r)
+		case "skip":
+			report.Results.Skipped++
+			ls.Skipped++
+			report.Skipped = append(report.Skipped, r)
+		case "error":
+			report.Results.Errored++
+			ls.Errored++
+			report.Failures = append(report.Failures, r)
+		}
+
+		report.Results.ByLevel[r.Level] = ls
+	}
+
+	// Determine conformance
+	report.ConformantLevel = -1
+	for lvl := 0; lvl <= 4; lvl++ {
+		ls, exists := report.Results.ByLevel[lvl]
+		if !exists {
+			continue
+		}
+		ls.AllPass = ls.Failed == 0 && ls.Errored == 0 && ls.XFailed == 0
+		report.Results.ByLevel[lvl] = ls
+		if ls.AllPass && ls.Total > 0 {
+			report.ConformantLevel = lvl
+		} else {
+			break
+		}
+	}
+
+	report.Conformant = report.Results.Failed == 0 && report.Results.Errored == 0 && report.Results.XFailed == 0
+
+	return report
+}
+
+// evaluateStatusAssertion handles various status assertion formats:
+// - integer: exact match (e.g., 200)
+// - string: matcher like "number:range(400,422)"
+// - object: {"$in": [200, 409]}
+func evaluateStatusAssertion(raw json.RawMessage, actual int) error {
+	// Try as integer
+	var statusInt int
+	if err := json.Unmarshal(raw, &statusInt); err == nil {
+		if actual != statusInt {
+			return fmt.Errorf("Expected status %d, got %d", statusInt, actual)
+		}
+		return nil
+	}
+
+	// Try as string matcher
+	var statusStr string
+	if err := json.Unmarshal(raw, &statusStr); err == nil {
+		// Handle one_of:code1,code2,... matcher
+		if strings.HasPrefix(statusStr, "one_of:") {
+			codesStr := statusStr[len("one_of:"):]
+			codes := strings.Split(codesStr, ",")
+			for _, codeStr := range codes {
+				codeStr = strings.TrimSpace(codeStr)
+				code, err := strconv.Atoi(codeStr)
+				if err != nil {
+					return fmt.Errorf("invalid status code %q in one_of matcher", codeStr)
+				}
+				if actual == code {
+					return nil
+				}
+			}
+			return fmt.Errorf("expected status one of [%s], got %d", codesStr, actual)
+		}
+		return lib.MatchAssertion(raw, float64(actual))
+	}
+
+	// Try as object (e.g., {"$in": [200, 409]})
+	var statusObj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &statusObj); err == nil {
+		if inRaw, ok := statusObj["$in"]; ok {
+			var inList []int
+			if err := json.Unmarshal(inRaw, &inList); err == nil {
+				for _, s := range inList {
+					if actual == s {
+						return nil
+					}
+				}
+				return fmt.Errorf("Expected status in %v, got %d", inList, actual)
+			}
+		}
+	}
+
+	return fmt.Errorf("Unknown status assertion format: %s", string(raw))
+}
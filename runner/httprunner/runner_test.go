@@ -0,0 +1,49 @@
+package httprunner
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+func TestResolveJSONTemplates(t *testing.T) {
+	stepResults := map[string]*lib.StepResult{
+		"s1": {StepID: "s1", Parsed: map[string]any{
+			"job": map[string]any{"id": `a"b`, "tags": []any{"x"}, "attempt": float64(2)},
+		}},
+	}
+	tests := []struct {
+		name, in, want string
+	}{
+		{"whole body", `{"copy": "{{steps.s1.response.body}}"}`,
+			`{"copy": {"job": {"id": "a\"b", "tags": ["x"], "attempt": 2}}}`},
+		{"object in text", `{"note": "job {{steps.s1.response.body.job}}"}`,
+			`{"note": "job {\"attempt\":2,\"id\":\"a\\\"b\",\"tags\":[\"x\"]}"}`},
+		{"string with quotes", `{"id": "{{steps.s1.response.body.job.id}}"}`, `{"id": "a\"b"}`},
+		{"number", `{"attempt": "{{steps.s1.response.body.job.attempt}}"}`, `{"attempt": "2"}`},
+		{"unresolved", `["{{steps.s9.response.body}}", 12345678901234567890]`,
+			`["{{steps.s9.response.body}}", 12345678901234567890]`},
+		{"no templates", `{"b": 1, "a": 2}`, `{"b": 1, "a": 2}`},
+	}
+	for _, tt := range tests {
+		got := resolveJSONTemplates(json.RawMessage(tt.in), stepResults)
+		if !json.Valid(got) {
+			t.Errorf("%s: result is not JSON: %s", tt.name, got)
+			continue
+		}
+		var g, w any
+		json.Unmarshal(got, &g)
+		json.Unmarshal([]byte(tt.want), &w)
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// The large number survives re-encoding unchanged.
+	got := resolveJSONTemplates(json.RawMessage(`["{{steps.s1.response.body.job.id}}", 12345678901234567890]`), stepResults)
+	if string(got) != `["a\"b",12345678901234567890]` {
+		t.Errorf("got %s", got)
+	}
+}
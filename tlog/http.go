@@ -0,0 +1,201 @@
+package tlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxSubmissionBytes bounds POST /api/log/entries bodies, the same bound
+// the portal puts on report uploads.
+const maxSubmissionBytes = 10 << 20
+
+// Handler serves a Log over HTTP.
+type Handler struct {
+	log *Log
+
+	// Admit, if set, wraps HandleSubmit. The portal sets it to apply its
+	// API keys and rate limits to submissions.
+	Admit func(http.HandlerFunc) http.HandlerFunc
+}
+
+// NewHandler creates an HTTP handler for log.
+func NewHandler(log *Log) *Handler {
+	return &Handler{log: log}
+}
+
+// RegisterRoutes registers the log endpoints on a standard ServeMux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	submit := h.HandleSubmit
+	if h.Admit != nil {
+		submit = h.Admit(submit)
+	}
+	mux.HandleFunc("POST /api/log/entries", submit)
+	mux.HandleFunc("GET /api/log/entries", h.HandleListEntries)
+	mux.HandleFunc("GET /api/log/entries/{index}", h.HandleGetEntry)
+	mux.HandleFunc("GET /api/log/tree-head", h.HandleTreeHead)
+	mux.HandleFunc("GET /api/log/proof/inclusion", h.HandleInclusionProof)
+	mux.HandleFunc("GET /api/log/proof/consistency", h.HandleConsistencyProof)
+}
+
+// HandleSubmit appends a signed report.
+// POST /api/log/entries
+func (h *Handler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	var sub Submission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmissionBytes)).Decode(&sub); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	receipt, err := h.log.Submit(sub)
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	status := http.StatusCreated
+	if receipt.Duplicate {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, receipt)
+}
+
+// HandleListEntries returns a page of entries.
+// GET /api/log/entries?start={n}&limit={n}
+func (h *Handler) HandleListEntries(w http.ResponseWriter, r *http.Request) {
+	start, err := queryInt(r, "start", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(r, "limit", 100)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit = min(limit, 1000)
+	entries := h.log.Entries(start, limit)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"entries":   entries,
+		"count":     len(entries),
+		"tree_size": h.log.Size(),
+	})
+}
+
+// HandleGetEntry returns one entry.
+// GET /api/log/entries/{index}
+func (h *Handler) HandleGetEntry(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.ParseInt(r.PathValue("index"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "index must be an integer")
+		return
+	}
+	e, err := h.log.Entry(index)
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// HandleTreeHead returns the current tree head.
+// GET /api/log/tree-head
+func (h *Handler) HandleTreeHead(w http.ResponseWriter, r *http.Request) {
+	th, err := h.log.TreeHead()
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, th)
+}
+
+// HandleInclusionProof proves an entry is in a tree. The entry is given
+// by index or by report digest; tree_size defaults to the current size.
+// GET /api/log/proof/inclusion?index={n}|report_sha256={hex}[&tree_size={n}]
+func (h *Handler) HandleInclusionProof(w http.ResponseWriter, r *http.Request) {
+	size, err := queryInt(r, "tree_size", h.log.Size())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var index int64
+	if digest := r.URL.Query().Get("report_sha256"); digest != "" {
+		if index, err = h.log.Lookup(digest); err != nil {
+			writeLogError(w, err)
+			return
+		}
+	} else if r.URL.Query().Get("index") == "" {
+		writeError(w, http.StatusBadRequest, "index or report_sha256 is required")
+		return
+	} else if index, err = queryInt(r, "index", 0); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	proof, err := h.log.InclusionProof(index, size)
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, proof)
+}
+
+// HandleConsistencyProof proves one tree is a prefix of another; second
+// defaults to the current size.
+// GET /api/log/proof/consistency?first={n}[&second={n}]
+func (h *Handler) HandleConsistencyProof(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("first") == "" {
+		writeError(w, http.StatusBadRequest, "first is required")
+		return
+	}
+	first, err := queryInt(r, "first", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	second, err := queryInt(r, "second", h.log.Size())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	proof, err := h.log.ConsistencyProof(first, second)
+	if err != nil {
+		writeLogError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, proof)
+}
+
+func queryInt(r *http.Request, name string, def int64) (int64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return n, nil
+}
+
+func writeLogError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidSubmission):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrUntrustedKey):
+		writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
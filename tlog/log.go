@@ -0,0 +1,461 @@
+// Package tlog is a local, append-only transparency log for signed
+// conformance reports.
+//
+// Every accepted report becomes a leaf of an RFC 6962 Merkle tree. The log
+// hands out inclusion proofs, which show a report is in the log, and
+// consistency proofs, which show a later tree head extends an earlier one.
+// A client that keeps the tree heads it has seen can therefore detect
+// entries being removed or rewritten. Tree heads are signed when the log
+// has a key.
+package tlog
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+var (
+	// ErrInvalidSubmission is returned for submissions that are malformed
+	// or whose signature does not verify.
+	ErrInvalidSubmission = errors.New("invalid submission")
+	// ErrUntrustedKey is returned when the log only accepts reports from
+	// configured keys and the submission was signed by another.
+	ErrUntrustedKey = lib.ErrUntrustedKey
+	// ErrNotFound is returned for indices and digests not in the log.
+	ErrNotFound = errors.New("not found")
+)
+
+// Submission is a signed report offered to the log.
+type Submission = lib.SignedReport
+
+// Entry is a leaf of the log. Its compact JSON encoding is the leaf data.
+type Entry struct {
+	Index        int64  `json:"index"`
+	IntegratedAt string `json:"integrated_at"` // RFC 3339
+	ReportDigest string `json:"report_sha256"`
+	KeyID        string `json:"key_id"`
+	PublicKey    string `json:"public_key"`
+
+	// Copied from the report so the history can be read without parsing it.
+	Target          string           `json:"target"`
+	RunAt           string           `json:"run_at"`
+	Conformant      bool             `json:"conformant"`
+	ConformantLevel int              `json:"conformant_level"`
+	Commit          *lib.CommitInfo  `json:"commit,omitempty"`
+	Backend         *lib.BackendInfo `json:"backend,omitempty"`
+
+	// Report is the canonical report (lib.CanonicalReport), signed by
+	// Signature or Envelope.
+	Report    json.RawMessage      `json:"report"`
+	Signature *lib.ReportSignature `json:"signature,omitempty"`
+	Envelope  *lib.Envelope        `json:"envelope,omitempty"`
+}
+
+// Verify re-checks the entry's signature with its recorded public key, so
+// an auditor does not have to trust the log to have done so.
+func (e Entry) Verify() error {
+	pub, err := lib.ParseVerifyKey([]byte(e.PublicKey))
+	if err != nil {
+		return err
+	}
+	report, err := lib.ParseReportStrict(e.Report)
+	if err != nil {
+		return err
+	}
+	switch {
+	case e.Envelope != nil:
+		attested, err := lib.VerifyReportEnvelope(*e.Envelope, pub)
+		if err != nil {
+			return err
+		}
+		want, _ := lib.ReportDigest(attested)
+		if got, _ := lib.ReportDigest(report); got != want {
+			return fmt.Errorf("report does not match envelope")
+		}
+	case e.Signature != nil:
+		if err := lib.VerifyReport(report, *e.Signature, pub); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("entry has no signature")
+	}
+	if digest, _ := lib.ReportDigest(report); digest != e.ReportDigest {
+		return fmt.Errorf("report_sha256 %s does not match report %s", e.ReportDigest, digest)
+	}
+	return nil
+}
+
+// TreeHead commits to the log's contents at a size.
+type TreeHead struct {
+	TreeSize  int64  `json:"tree_size"`
+	RootHash  string `json:"root_hash"` // hex
+	Timestamp string `json:"timestamp"` // RFC 3339
+	KeyID     string `json:"key_id,omitempty"`
+	Signature string `json:"signature,omitempty"` // base64 Ed25519 over SignedData
+}
+
+// SignedData is what the tree head signature covers.
+func (th TreeHead) SignedData() []byte {
+	return []byte(fmt.Sprintf("ojs-tlog tree head v1\n%d\n%s\n%s\n", th.TreeSize, th.RootHash, th.Timestamp))
+}
+
+// Verify checks the tree head's signature.
+func (th TreeHead) Verify(pub ed25519.PublicKey) error {
+	if keyID := lib.KeyID(pub); th.KeyID != keyID {
+		return fmt.Errorf("tree head signed with key %q, verifying with %s", th.KeyID, keyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(th.Signature)
+	if err != nil || !ed25519.Verify(pub, th.SignedData(), sig) {
+		return fmt.Errorf("invalid tree head signature")
+	}
+	return nil
+}
+
+// InclusionProof shows that a leaf is in the tree of TreeSize.
+type InclusionProof struct {
+	Index     int64    `json:"index"`
+	TreeSize  int64    `json:"tree_size"`
+	LeafHash  string   `json:"leaf_hash"`
+	AuditPath []string `json:"audit_path"`
+}
+
+// Verify checks the proof against the root hash of a tree head of the
+// same size.
+func (p InclusionProof) Verify(rootHash string) error {
+	leaf, err := decodeHash(p.LeafHash)
+	if err != nil {
+		return err
+	}
+	root, err := decodeHash(rootHash)
+	if err != nil {
+		return err
+	}
+	path, err := decodeHashes(p.AuditPath)
+	if err != nil {
+		return err
+	}
+	return VerifyInclusion(p.Index, p.TreeSize, leaf, path, root)
+}
+
+// ConsistencyProof shows that the tree of size First is a prefix of the
+// tree of size Second.
+type ConsistencyProof struct {
+	First  int64    `json:"first"`
+	Second int64    `json:"second"`
+	Proof  []string `json:"proof"`
+}
+
+// Verify checks the proof against the root hashes of both tree heads.
+func (p ConsistencyProof) Verify(firstRoot, secondRoot string) error {
+	r1, err := decodeHash(firstRoot)
+	if err != nil {
+		return err
+	}
+	r2, err := decodeHash(secondRoot)
+	if err != nil {
+		return err
+	}
+	proof, err := decodeHashes(p.Proof)
+	if err != nil {
+		return err
+	}
+	return VerifyConsistency(p.First, p.Second, r1, r2, proof)
+}
+
+// Receipt is returned for a submission: the entry's place in the log and
+// proof that it is included in the current tree head.
+type Receipt struct {
+	Index     int64          `json:"index"`
+	Duplicate bool           `json:"duplicate,omitempty"` // the report was already logged
+	TreeHead  TreeHead       `json:"tree_head"`
+	Inclusion InclusionProof `json:"inclusion"`
+}
+
+// Config configures a Log.
+type Config struct {
+	// SigningKey signs tree heads. Optional.
+	SigningKey ed25519.PrivateKey
+	// TrustedKeys restricts submissions to reports signed by these keys.
+	// Empty accepts any key whose signature verifies.
+	TrustedKeys []ed25519.PublicKey
+	// Now returns the current time; defaults to time.Now.
+	Now func() time.Time
+}
+
+// Log is a transparency log. It is safe for concurrent use.
+type Log struct {
+	store   Store
+	cfg     Config
+	trusted map[string]bool
+
+	mu       sync.RWMutex
+	entries  []Entry
+	leaves   [][]byte // leaf hashes
+	byDigest map[string]int64
+}
+
+// Open loads the log from store. It checks that every stored entry is at
+// its recorded index and re-verifies its signature with Entry.Verify.
+func Open(store Store, cfg Config) (*Log, error) {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	l := &Log{store: store, cfg: cfg, byDigest: make(map[string]int64)}
+	if len(cfg.TrustedKeys) > 0 {
+		l.trusted = make(map[string]bool)
+		for _, k := range cfg.TrustedKeys {
+			l.trusted[lib.KeyID(k)] = true
+		}
+	}
+
+	data, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for i, leaf := range data {
+		var e Entry
+		if err := json.Unmarshal(leaf, &e); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		if e.Index != int64(i) {
+			return nil, fmt.Errorf("entry %d: recorded index %d", i, e.Index)
+		}
+		if err := e.Verify(); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		l.add(e, leaf)
+	}
+	return l, nil
+}
+
+func (l *Log) add(e Entry, leaf []byte) {
+	l.entries = append(l.entries, e)
+	l.leaves = append(l.leaves, LeafHash(leaf))
+	if _, ok := l.byDigest[e.ReportDigest]; !ok {
+		l.byDigest[e.ReportDigest] = e.Index
+	}
+}
+
+// Close closes the store.
+func (l *Log) Close() error {
+	return l.store.Close()
+}
+
+// Submit verifies a signed report and appends it to the log. A report that
+// is already logged is not added again; its existing entry is returned.
+func (l *Log) Submit(sub Submission) (Receipt, error) {
+	e, err := l.verify(sub)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	l.mu.Lock()
+	index, dup := l.byDigest[e.ReportDigest]
+	if !dup {
+		index = int64(len(l.entries))
+		e.Index = index
+		e.IntegratedAt = l.cfg.Now().UTC().Format(time.RFC3339)
+		leaf, err := json.Marshal(e)
+		if err == nil {
+			err = l.store.Append(leaf)
+		}
+		if err != nil {
+			l.mu.Unlock()
+			return Receipt{}, err
+		}
+		l.add(e, leaf)
+	}
+	size := int64(len(l.entries))
+	l.mu.Unlock()
+
+	th, err := l.treeHead(size)
+	if err != nil {
+		return Receipt{}, err
+	}
+	proof, err := l.InclusionProof(index, size)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return Receipt{Index: index, Duplicate: dup, TreeHead: th, Inclusion: proof}, nil
+}
+
+// verify checks the submission's signature and builds its entry.
+func (l *Log) verify(sub Submission) (Entry, error) {
+	report, pub, err := lib.VerifySignedReport(sub, l.trusted)
+	if errors.Is(err, ErrUntrustedKey) {
+		return Entry{}, err
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("%w: %v", ErrInvalidSubmission, err)
+	}
+
+	canonical, err := lib.CanonicalReport(report)
+	if err != nil {
+		return Entry{}, err
+	}
+	digest, err := lib.ReportDigest(report)
+	if err != nil {
+		return Entry{}, err
+	}
+	pubPEM, err := lib.MarshalVerifyKey(pub)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{
+		ReportDigest:    digest,
+		KeyID:           lib.KeyID(pub),
+		PublicKey:       string(pubPEM),
+		Target:          report.Target,
+		RunAt:           report.RunAt,
+		Conformant:      report.Conformant,
+		ConformantLevel: report.ConformantLevel,
+		Commit:          report.Commit,
+		Backend:         report.Backend,
+		Report:          canonical,
+		Signature:       sub.Signature,
+		Envelope:        sub.Envelope,
+	}, nil
+}
+
+// Size returns the number of entries.
+func (l *Log) Size() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return int64(len(l.entries))
+}
+
+// Entry returns the entry at index.
+func (l *Log) Entry(index int64) (Entry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if index < 0 || index >= int64(len(l.entries)) {
+		return Entry{}, fmt.Errorf("%w: index %d", ErrNotFound, index)
+	}
+	return l.entries[index], nil
+}
+
+// Entries returns up to limit entries starting at start.
+func (l *Log) Entries(start, limit int64) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	n := int64(len(l.entries))
+	if start < 0 || start >= n || limit <= 0 {
+		return []Entry{}
+	}
+	end := min(start+limit, n)
+	return append([]Entry(nil), l.entries[start:end]...)
+}
+
+// Lookup returns the index of the report with the given SHA-256 digest.
+func (l *Log) Lookup(reportDigest string) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	index, ok := l.byDigest[reportDigest]
+	if !ok {
+		return 0, fmt.Errorf("%w: report %s", ErrNotFound, reportDigest)
+	}
+	return index, nil
+}
+
+// TreeHead returns the current tree head.
+func (l *Log) TreeHead() (TreeHead, error) {
+	return l.treeHead(l.Size())
+}
+
+func (l *Log) treeHead(size int64) (TreeHead, error) {
+	leaves, err := l.prefix(size)
+	if err != nil {
+		return TreeHead{}, err
+	}
+	th := TreeHead{
+		TreeSize:  size,
+		RootHash:  hex.EncodeToString(RootHash(leaves)),
+		Timestamp: l.cfg.Now().UTC().Format(time.RFC3339),
+	}
+	if l.cfg.SigningKey != nil {
+		th.KeyID = lib.KeyID(l.cfg.SigningKey.Public().(ed25519.PublicKey))
+		th.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(l.cfg.SigningKey, th.SignedData()))
+	}
+	return th, nil
+}
+
+// InclusionProof proves the entry at index is in the tree of treeSize.
+func (l *Log) InclusionProof(index, treeSize int64) (InclusionProof, error) {
+	leaves, err := l.prefix(treeSize)
+	if err != nil {
+		return InclusionProof{}, err
+	}
+	if index < 0 || index >= treeSize {
+		return InclusionProof{}, fmt.Errorf("%w: index %d in tree of size %d", ErrNotFound, index, treeSize)
+	}
+	return InclusionProof{
+		Index:     index,
+		TreeSize:  treeSize,
+		LeafHash:  hex.EncodeToString(leaves[index]),
+		AuditPath: encodeHashes(inclusionPath(index, leaves)),
+	}, nil
+}
+
+// ConsistencyProof proves the tree of size first is a prefix of the tree
+// of size second.
+func (l *Log) ConsistencyProof(first, second int64) (ConsistencyProof, error) {
+	leaves, err := l.prefix(second)
+	if err != nil {
+		return ConsistencyProof{}, err
+	}
+	if first < 0 || first > second {
+		return ConsistencyProof{}, fmt.Errorf("%w: tree size %d not in 0..%d", ErrNotFound, first, second)
+	}
+	var path [][]byte
+	if first > 0 && first < second {
+		path = consistencyPath(first, leaves, true)
+	}
+	return ConsistencyProof{First: first, Second: second, Proof: encodeHashes(path)}, nil
+}
+
+// prefix returns the leaf hashes of the tree of size.
+func (l *Log) prefix(size int64) ([][]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if size < 0 || size > int64(len(l.leaves)) {
+		return nil, fmt.Errorf("%w: tree size %d, log has %d entries", ErrNotFound, size, len(l.leaves))
+	}
+	// Leaves are never modified, so the slice can be shared.
+	return l.leaves[:size:size], nil
+}
+
+func encodeHashes(hashes [][]byte) []string {
+	out := make([]string, len(hashes))
+	for i, h := range hashes {
+		out[i] = hex.EncodeToString(h)
+	}
+	return out
+}
+
+func decodeHash(s string) ([]byte, error) {
+	h, err := hex.DecodeString(s)
+	if err != nil || len(h) != HashSize {
+		return nil, fmt.Errorf("%w: malformed hash %q", ErrInvalidProof, s)
+	}
+	return h, nil
+}
+
+func decodeHashes(ss []string) ([][]byte, error) {
+	out := make([][]byte, len(ss))
+	for i, s := range ss {
+		h, err := decodeHash(s)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = h
+	}
+	return out, nil
+}
@@ -0,0 +1,358 @@
+package tlog
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openjobspec/ojs-conformance/lib"
+)
+
+type testSigner struct {
+	priv   ed25519.PrivateKey
+	pubPEM string
+}
+
+func newTestSigner(t *testing.T) testSigner {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubPEM, err := lib.MarshalVerifyKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{priv: priv, pubPEM: string(pubPEM)}
+}
+
+func testReport(sha string, level int) lib.SuiteReport {
+	return lib.SuiteReport{
+		TestSuiteVersion: "1.0",
+		Target:           "http://localhost:8080",
+		RunAt:            "2026-10-01T12:00:00Z",
+		Results:          lib.ResultsSummary{Total: 1, Passed: 1},
+		Conformant:       true,
+		ConformantLevel:  level,
+		Commit:           &lib.CommitInfo{SHA: sha, Repo: "github.com/acme/ojs-backend"},
+	}
+}
+
+func (s testSigner) detached(t *testing.T, r lib.SuiteReport) Submission {
+	t.Helper()
+	sig, err := lib.SignReport(r, s.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(r)
+	return Submission{Report: data, Signature: &sig, PublicKey: s.pubPEM}
+}
+
+func (s testSigner) envelope(t *testing.T, r lib.SuiteReport) Submission {
+	t.Helper()
+	env, err := lib.SignReportEnvelope(r, s.priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Submission{Envelope: &env, PublicKey: s.pubPEM}
+}
+
+func openTestLog(t *testing.T, path string, cfg Config) *Log {
+	t.Helper()
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(store, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+func TestLogSubmitAndProofs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	signer := newTestSigner(t)
+	_, logKey, _ := ed25519.GenerateKey(rand.Reader)
+	l := openTestLog(t, path, Config{SigningKey: logKey})
+
+	var heads []TreeHead
+	for i, sha := range []string{"a1", "b2", "c3", "d4", "e5"} {
+		sub := signer.detached(t, testReport(sha, i%5))
+		if i%2 == 1 {
+			sub = signer.envelope(t, testReport(sha, i%5))
+		}
+		rc, err := l.Submit(sub)
+		if err != nil {
+			t.Fatalf("submit %s: %v", sha, err)
+		}
+		if rc.Index != int64(i) || rc.Duplicate || rc.TreeHead.TreeSize != int64(i+1) {
+			t.Fatalf("unexpected receipt: %+v", rc)
+		}
+		if err := rc.TreeHead.Verify(logKey.Public().(ed25519.PublicKey)); err != nil {
+			t.Fatalf("tree head: %v", err)
+		}
+		if err := rc.Inclusion.Verify(rc.TreeHead.RootHash); err != nil {
+			t.Fatalf("inclusion: %v", err)
+		}
+		heads = append(heads, rc.TreeHead)
+	}
+
+	for i, old := range heads {
+		for _, newer := range heads[i:] {
+			p, err := l.ConsistencyProof(old.TreeSize, newer.TreeSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Verify(old.RootHash, newer.RootHash); err != nil {
+				t.Errorf("%d -> %d: %v", old.TreeSize, newer.TreeSize, err)
+			}
+		}
+		// Entries stay provable against later tree heads.
+		p, err := l.InclusionProof(int64(i), 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Verify(heads[4].RootHash); err != nil {
+			t.Errorf("entry %d in tree of 5: %v", i, err)
+		}
+	}
+
+	// Resubmitting returns the existing entry.
+	rc, err := l.Submit(signer.detached(t, testReport("c3", 2)))
+	if err != nil || !rc.Duplicate || rc.Index != 2 || l.Size() != 5 {
+		t.Errorf("expected duplicate of entry 2, got %+v, %v", rc, err)
+	}
+
+	e, err := l.Entry(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Commit == nil || e.Commit.SHA != "d4" || e.ConformantLevel != 3 || e.Envelope == nil {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if err := e.Verify(); err != nil {
+		t.Errorf("entry does not verify: %v", err)
+	}
+
+	// Reopening rebuilds the same tree.
+	l.Close()
+	reopened := openTestLog(t, path, Config{SigningKey: logKey})
+	th, err := reopened.TreeHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.TreeSize != 5 || th.RootHash != heads[4].RootHash {
+		t.Errorf("reopened log has tree head %+v, want root %s", th, heads[4].RootHash)
+	}
+}
+
+func TestLogRejectsInvalidSubmissions(t *testing.T) {
+	signer, other := newTestSigner(t), newTestSigner(t)
+	trustedPub, _ := lib.ParseVerifyKey([]byte(signer.pubPEM))
+	l := openTestLog(t, filepath.Join(t.TempDir(), "log.jsonl"), Config{TrustedKeys: []ed25519.PublicKey{trustedPub}})
+
+	edited := signer.detached(t, testReport("a1", 1))
+	edited.Report = []byte(strings.Replace(string(edited.Report), `"conformant_level":1`, `"conformant_level":4`, 1))
+
+	wrongKey := signer.detached(t, testReport("a1", 1))
+	wrongKey.PublicKey = other.pubPEM
+
+	for name, tt := range map[string]struct {
+		sub  Submission
+		want error
+	}{
+		"edited report":  {edited, ErrInvalidSubmission},
+		"no signature":   {Submission{Report: edited.Report, PublicKey: signer.pubPEM}, ErrInvalidSubmission},
+		"bad public key": {Submission{Envelope: signer.envelope(t, testReport("a1", 1)).Envelope, PublicKey: "nope"}, ErrInvalidSubmission},
+		"untrusted key":  {other.detached(t, testReport("a1", 1)), ErrUntrustedKey},
+		"mismatched key": {wrongKey, ErrUntrustedKey},
+	} {
+		if _, err := l.Submit(tt.sub); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", name, err, tt.want)
+		}
+	}
+	if l.Size() != 0 {
+		t.Errorf("expected empty log, got %d entries", l.Size())
+	}
+}
+
+func TestLogDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+	signer := newTestSigner(t)
+	l := openTestLog(t, path, Config{})
+	for _, sha := range []string{"a1", "b2"} {
+		if _, err := l.Submit(signer.detached(t, testReport(sha, 0))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, _ := l.TreeHead()
+	l.Close()
+
+	// Rewrite the level of the first entry in place.
+	data, _ := os.ReadFile(path)
+	data = []byte(strings.Replace(string(data), `"conformant_level":0`, `"conformant_level":4`, 1))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tampered := openTestLog(t, path, Config{})
+	after, _ := tampered.TreeHead()
+	if after.RootHash == before.RootHash {
+		t.Error("rewritten entry did not change the root hash")
+	}
+
+	// So is rewriting the signed report inside an entry.
+	forged := []byte(strings.Replace(string(data), `ojs-backend","sha":"b2"`, `ojs-backend","sha":"c3"`, 1))
+	if string(forged) == string(data) {
+		t.Fatal("test setup: report commit not found in the log")
+	}
+	if err := os.WriteFile(path, forged, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := OpenFileStore(path)
+	if _, err := Open(store, Config{}); err == nil || !strings.Contains(err.Error(), "entry 1") {
+		t.Errorf("expected the forged report to be rejected, got %v", err)
+	}
+	store.Close()
+
+	// A truncated final line is an error, not a silently shorter log.
+	if err := os.WriteFile(path, data[:len(data)-5], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, _ = OpenFileStore(path)
+	defer store.Close()
+	if _, err := Open(store, Config{}); err == nil {
+		t.Error("expected truncated log to be rejected")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	signer := newTestSigner(t)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	l := openTestLog(t, filepath.Join(t.TempDir(), "log.jsonl"), Config{Now: func() time.Time { return now }})
+	mux := http.NewServeMux()
+	NewHandler(l).RegisterRoutes(mux)
+
+	do := func(method, target string, body any) *httptest.ResponseRecorder {
+		var r *http.Request
+		if body != nil {
+			data, _ := json.Marshal(body)
+			r = httptest.NewRequest(method, target, strings.NewReader(string(data)))
+		} else {
+			r = httptest.NewRequest(method, target, nil)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := do("POST", "/api/log/entries", signer.envelope(t, testReport("a1", 2)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rc Receipt
+	json.NewDecoder(rec.Body).Decode(&rc)
+	if rc.TreeHead.Timestamp != "2026-10-01T12:00:00Z" {
+		t.Errorf("unexpected tree head: %+v", rc.TreeHead)
+	}
+	if rec := do("POST", "/api/log/entries", signer.envelope(t, testReport("a1", 2))); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for duplicate, got %d", rec.Code)
+	}
+	do("POST", "/api/log/entries", signer.detached(t, testReport("b2", 3)))
+
+	if rec := do("POST", "/api/log/entries", Submission{PublicKey: signer.pubPEM}); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+
+	rec = do("GET", "/api/log/entries/1", nil)
+	var e Entry
+	json.NewDecoder(rec.Body).Decode(&e)
+	if rec.Code != http.StatusOK || e.Commit.SHA != "b2" {
+		t.Errorf("unexpected entry response %d: %+v", rec.Code, e)
+	}
+	if rec := do("GET", "/api/log/entries/9", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+
+	rec = do("GET", "/api/log/tree-head", nil)
+	var th TreeHead
+	json.NewDecoder(rec.Body).Decode(&th)
+	if th.TreeSize != 2 {
+		t.Fatalf("unexpected tree head: %+v", th)
+	}
+
+	rec = do("GET", "/api/log/proof/inclusion?report_sha256="+e.ReportDigest, nil)
+	var ip InclusionProof
+	json.NewDecoder(rec.Body).Decode(&ip)
+	if err := ip.Verify(th.RootHash); err != nil || ip.Index != 1 {
+		t.Errorf("inclusion proof %+v: %v", ip, err)
+	}
+
+	rec = do("GET", "/api/log/proof/consistency?first=1&second=2", nil)
+	var cp ConsistencyProof
+	json.NewDecoder(rec.Body).Decode(&cp)
+	if err := cp.Verify(rc.TreeHead.RootHash, th.RootHash); err != nil {
+		t.Errorf("consistency proof %+v: %v", cp, err)
+	}
+	if rec := do("GET", "/api/log/proof/consistency?first=3", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for first beyond tree size, got %d", rec.Code)
+	}
+
+	rec = do("GET", "/api/log/entries?start=1&limit=5", nil)
+	var page struct {
+		Count    int   `json:"count"`
+		TreeSize int64 `json:"tree_size"`
+	}
+	json.NewDecoder(rec.Body).Decode(&page)
+	if page.Count != 1 || page.TreeSize != 2 {
+		t.Errorf("unexpected page: %+v", page)
+	}
+}
+
+func TestHandlerAdmit(t *testing.T) {
+	signer := newTestSigner(t)
+	l := openTestLog(t, filepath.Join(t.TempDir(), "log.jsonl"), Config{})
+	h := NewHandler(l)
+	h.Admit = func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer key-1" {
+				writeError(w, http.StatusUnauthorized, "API key required")
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	submit := func(auth string) int {
+		data, _ := json.Marshal(signer.detached(t, testReport("a1", 0)))
+		r := httptest.NewRequest("POST", "/api/log/entries", strings.NewReader(string(data)))
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	if code := submit(""); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a key, got %d", code)
+	}
+	if code := submit("Bearer key-1"); code != http.StatusCreated {
+		t.Errorf("expected 201 with a key, got %d", code)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/log/tree-head", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("reads should not be gated, got %d", rec.Code)
+	}
+}
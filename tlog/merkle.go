@@ -0,0 +1,174 @@
+package tlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// The tree is the RFC 6962 / RFC 9162 Merkle Tree Hash over SHA-256, so
+// proofs can be checked with any Certificate Transparency verifier.
+
+// HashSize is the size of every tree hash.
+const HashSize = sha256.Size
+
+// ErrInvalidProof is returned when a proof does not verify.
+var ErrInvalidProof = errors.New("invalid proof")
+
+// LeafHash is the hash of a leaf: SHA-256(0x00 || data).
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// nodeHash is the hash of an interior node: SHA-256(0x01 || left || right).
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// split returns the largest power of two smaller than n, for n > 1.
+func split(n int64) int64 {
+	return int64(1) << (bits.Len64(uint64(n-1)) - 1)
+}
+
+// RootHash is the Merkle Tree Hash of the leaf hashes. The empty tree
+// hashes to SHA-256 of the empty string.
+func RootHash(leaves [][]byte) []byte {
+	switch n := int64(len(leaves)); n {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	default:
+		k := split(n)
+		return nodeHash(RootHash(leaves[:k]), RootHash(leaves[k:]))
+	}
+}
+
+// inclusionPath is PATH(m, D[n]) of RFC 9162 section 2.1.3.1.
+func inclusionPath(m int64, leaves [][]byte) [][]byte {
+	n := int64(len(leaves))
+	if n <= 1 {
+		return nil
+	}
+	k := split(n)
+	if m < k {
+		return append(inclusionPath(m, leaves[:k]), RootHash(leaves[k:]))
+	}
+	return append(inclusionPath(m-k, leaves[k:]), RootHash(leaves[:k]))
+}
+
+// consistencyPath is SUBPROOF(m, D[n], b) of RFC 9162 section 2.1.4.1.
+func consistencyPath(m int64, leaves [][]byte, complete bool) [][]byte {
+	n := int64(len(leaves))
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{RootHash(leaves)}
+	}
+	k := split(n)
+	if m <= k {
+		return append(consistencyPath(m, leaves[:k], complete), RootHash(leaves[k:]))
+	}
+	return append(consistencyPath(m-k, leaves[k:], false), RootHash(leaves[:k]))
+}
+
+// VerifyInclusion checks that leafHash is at index in the tree of size
+// whose root is root (RFC 9162 section 2.1.3.2).
+func VerifyInclusion(index, size int64, leafHash []byte, proof [][]byte, root []byte) error {
+	if index < 0 || index >= size {
+		return fmt.Errorf("%w: index %d outside tree of size %d", ErrInvalidProof, index, size)
+	}
+	fn, sn := index, size-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return fmt.Errorf("%w: audit path too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("%w: audit path too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(r, root) {
+		return fmt.Errorf("%w: root hash mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// VerifyConsistency checks that the tree of size first with root
+// firstRoot is a prefix of the tree of size second with root secondRoot
+// (RFC 9162 section 2.1.4.2).
+func VerifyConsistency(first, second int64, firstRoot, secondRoot []byte, proof [][]byte) error {
+	switch {
+	case first < 0 || first > second:
+		return fmt.Errorf("%w: tree sizes %d and %d", ErrInvalidProof, first, second)
+	case first == second:
+		if len(proof) != 0 || !bytes.Equal(firstRoot, secondRoot) {
+			return fmt.Errorf("%w: trees of equal size differ", ErrInvalidProof)
+		}
+		return nil
+	case first == 0:
+		if len(proof) != 0 {
+			return fmt.Errorf("%w: proof from the empty tree must be empty", ErrInvalidProof)
+		}
+		return nil
+	case len(proof) == 0:
+		return fmt.Errorf("%w: empty proof", ErrInvalidProof)
+	}
+
+	if first&(first-1) == 0 {
+		// The old tree is a complete subtree; its root starts the path.
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return fmt.Errorf("%w: proof too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("%w: proof too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return fmt.Errorf("%w: root hash mismatch", ErrInvalidProof)
+	}
+	return nil
+}
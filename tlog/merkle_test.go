@@ -0,0 +1,113 @@
+package tlog
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// ctLeaves are the leaves of the Certificate Transparency reference tests.
+var ctLeaves = []string{
+	"", "00", "10", "2021", "3031", "40414243",
+	"5051525354555657", "606162636465666768696a6b6c6d6e6f",
+}
+
+func ctLeafHashes(n int) [][]byte {
+	hashes := make([][]byte, n)
+	for i := range hashes {
+		data, _ := hex.DecodeString(ctLeaves[i%len(ctLeaves)])
+		if i >= len(ctLeaves) {
+			data = append(data, byte(i)) // keep larger trees' leaves distinct
+		}
+		hashes[i] = LeafHash(data)
+	}
+	return hashes
+}
+
+func TestRootHashReferenceValues(t *testing.T) {
+	tests := []struct {
+		size int
+		root string
+	}{
+		{0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{1, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(RootHash(ctLeafHashes(tt.size))); got != tt.root {
+			t.Errorf("size %d: root %s, want %s", tt.size, got, tt.root)
+		}
+	}
+}
+
+func TestInclusionProofs(t *testing.T) {
+	for size := int64(1); size <= 20; size++ {
+		leaves := ctLeafHashes(int(size))
+		root := RootHash(leaves)
+		for i := int64(0); i < size; i++ {
+			proof := inclusionPath(i, leaves)
+			if err := VerifyInclusion(i, size, leaves[i], proof, root); err != nil {
+				t.Fatalf("size %d index %d: %v", size, i, err)
+			}
+			if err := VerifyInclusion(i, size, LeafHash([]byte("other")), proof, root); !errors.Is(err, ErrInvalidProof) {
+				t.Fatalf("size %d index %d: wrong leaf accepted", size, i)
+			}
+			if size > 1 {
+				if err := VerifyInclusion((i+1)%size, size, leaves[i], proof, root); err == nil {
+					t.Fatalf("size %d index %d: proof accepted for another index", size, i)
+				}
+			}
+		}
+	}
+}
+
+func TestConsistencyProofs(t *testing.T) {
+	for second := int64(1); second <= 20; second++ {
+		leaves := ctLeafHashes(int(second))
+		root2 := RootHash(leaves)
+		for first := int64(1); first < second; first++ {
+			root1 := RootHash(leaves[:first])
+			proof := consistencyPath(first, leaves, true)
+			if err := VerifyConsistency(first, second, root1, root2, proof); err != nil {
+				t.Fatalf("%d -> %d: %v", first, second, err)
+			}
+
+			// A rewritten entry in the old tree must be detected.
+			forged := append([][]byte(nil), leaves[:first]...)
+			forged[0] = LeafHash([]byte("rewritten"))
+			if err := VerifyConsistency(first, second, RootHash(forged), root2, proof); err == nil {
+				t.Fatalf("%d -> %d: forged old root accepted", first, second)
+			}
+			if len(proof) > 0 {
+				bad := append([][]byte(nil), proof...)
+				bad[len(bad)-1] = bytes.Repeat([]byte{0xff}, HashSize)
+				if err := VerifyConsistency(first, second, root1, root2, bad); err == nil {
+					t.Fatalf("%d -> %d: corrupted proof accepted", first, second)
+				}
+			}
+		}
+	}
+}
+
+func TestConsistencyEdgeCases(t *testing.T) {
+	leaves := ctLeafHashes(4)
+	root := RootHash(leaves)
+	for _, tt := range []struct {
+		first, second int64
+		r1, r2        []byte
+		proof         [][]byte
+		ok            bool
+	}{
+		{4, 4, root, root, nil, true},
+		{0, 4, RootHash(nil), root, nil, true},
+		{4, 4, root, RootHash(leaves[:3]), nil, false},
+		{5, 4, root, root, nil, false},
+		{2, 4, RootHash(leaves[:2]), root, nil, false},
+	} {
+		err := VerifyConsistency(tt.first, tt.second, tt.r1, tt.r2, tt.proof)
+		if (err == nil) != tt.ok {
+			t.Errorf("%d -> %d: got %v", tt.first, tt.second, err)
+		}
+	}
+}
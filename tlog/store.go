@@ -0,0 +1,84 @@
+package tlog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Store persists the log's leaves in order. Implementations only ever
+// append; the Merkle tree is rebuilt from the leaves when a log is opened.
+type Store interface {
+	// Load returns every leaf, oldest first.
+	Load() ([][]byte, error)
+	// Append durably adds a leaf. It must not return before the leaf
+	// would survive a crash.
+	Append(leaf []byte) error
+	Close() error
+}
+
+// FileStore keeps one leaf per line in an append-only file. Leaves must
+// not contain newlines; Log writes compact JSON.
+//
+// A FileStore assumes it is the file's only writer.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+// OpenFileStore opens or creates the log file at path.
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{path: path, f: f}, nil
+}
+
+// Load reads every leaf. A final line without a newline, left behind by a
+// crash during Append, is reported as an error rather than dropped, since
+// its leaf may already have been handed out.
+func (s *FileStore) Load() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var leaves [][]byte
+	r := bufio.NewReader(s.f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				return nil, fmt.Errorf("%s: truncated entry %d", s.path, len(leaves))
+			}
+			return leaves, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, bytes.TrimSuffix(line, []byte("\n")))
+	}
+}
+
+// Append writes the leaf and its newline, then syncs the file.
+func (s *FileStore) Append(leaf []byte) error {
+	if bytes.IndexByte(leaf, '\n') >= 0 {
+		return fmt.Errorf("leaf contains a newline")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(leaf[:len(leaf):len(leaf)], '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close closes the file.
+func (s *FileStore) Close() error {
+	return s.f.Close()
+}